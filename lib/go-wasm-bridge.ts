@@ -5,16 +5,448 @@
  * Handles concurrent execution, thread management, and system services.
  */
 
+export type GoThreadMode = 'running' | 'paused';
+
 export interface GoVMOrchestrator {
   initialize(emulatorPtr: any): boolean;
-  start(): boolean;
-  stop(): boolean;
-  createThread(startPC: number): number;
+  start(): GoResult;
+  stop(): GoResult;
+  stopGraceful(timeoutMs: number): Promise<boolean>;
+  reset(): boolean;
+  createThread(
+    startPC: GoAddress,
+    priority?: number,
+    mode?: GoThreadMode,
+    name?: string,
+    options?: GoCreateThreadOptions
+  ): GoResult<{ threadID: number }>;
+  startThread(threadID: number): GoResult;
+  getThread(threadID: number): GoThreadInfo | null;
+  suspendThread(threadID: number): GoResult;
+  resumeThread(threadID: number): GoResult;
+  joinThread(threadID: number): Promise<GoThreadExit>;
+  getRegisters(threadID: number): number[] | null;
+  setRegister(threadID: number, index: number, value: number): GoResult;
+  stepThread(threadID: number): GoResult<{ pc: GoAddress }>;
+  setThreadMode(threadID: number, mode: GoThreadMode): GoResult;
+  focusThread(threadID: number): GoResult<{ count: number }>;
+  unfocus(): GoResult<{ count: number }>;
+  setBreakpoint(address: GoAddress, condition?: GoBreakpointCondition | null): boolean;
+  clearBreakpoint(address: GoAddress): boolean;
+  listBreakpoints(): GoAddress[];
+  onBreakpoint(callback: ((threadID: number, address: GoAddress) => void) | null): boolean;
+  setRegisterWatch(threadID: number, regIndex: number): GoResult;
+  clearRegisterWatch(threadID: number, regIndex: number): GoResult;
+  onWatch(
+    callback: ((threadID: number, regIndex: number, oldValue: number, newValue: number) => void) | null
+  ): boolean;
+  snapshot(): GoVMSnapshot;
+  restore(snapshot: GoVMSnapshot): boolean;
+  diffSnapshots(a: GoVMSnapshot, b: GoVMSnapshot): GoSnapshotDiff | null;
+  setAutoSnapshot(everyNInstructions: number, keep?: number): boolean;
+  getAutoSnapshots(): GoAutoSnapshot[];
+  setMaxWorkers(workers: number): boolean;
+  setThroughputWindow(ms: number): boolean;
+  setBatchSize(n: number): boolean;
+  recordAllocation(bytes: number): boolean;
+  recordFree(bytes: number): boolean;
+  setMaxStackDepth(depth: number): boolean;
+  pushStack(threadID: number, value: number): GoResult;
+  popStack(threadID: number): GoResult<{ value: number }>;
+  onStackOverflow(callback: ((threadID: number, depth: number) => void) | null): boolean;
+  setThreadInstructionLimit(threadID: number, limit: number): GoResult;
+  onThreadTerminated(callback: ((exit: GoThreadExit) => void) | null): boolean;
+  onFault(callback: ((threadID: number, message: string, lastPC: GoAddress) => void) | null): boolean;
+  setTickMode(enabled: boolean): boolean;
+  tick(quanta?: number): boolean;
+  waitThread(threadID: number, onThreadID: number, timeoutMs?: number | null): GoResult;
+  wakeThread(threadID: number): GoResult;
+  detectDeadlock(): number[];
+  onDeadlock(callback: ((threadIDs: number[]) => void) | null): boolean;
+  setTLS(threadID: number, key: string, value: number | string | boolean | null): GoResult;
+  getTLS(threadID: number, key: string): number | string | boolean | null;
+  setInterruptHandler(vector: number, address: GoAddress | null): boolean;
+  sendInterrupt(threadID: number, vector: number): GoResult;
+  sendInterruptAll(vector: number): number;
+  returnFromInterrupt(threadID: number): GoResult;
+  setThreadAffinity(threadID: number, workerIndex: number): GoResult;
+  enableTrace(capacity: number): boolean;
+  disableTrace(): boolean;
+  getTrace(): GoTraceEntry[];
+  yieldThread(threadID: number): GoResult;
+  createMutex(): number;
+  lockMutex(threadID: number, mutexID: number): GoResult<{ acquired: boolean }>;
+  unlockMutex(threadID: number, mutexID: number): GoResult;
+  createCondVar(): number;
+  condWait(threadID: number, condID: number, mutexID: number): GoResult;
+  condSignal(condID: number): GoResult<{ woken: number }>;
+  condBroadcast(condID: number): GoResult<{ woken: number }>;
+  exportState(): string | null;
+  importState(json: string): GoResult;
+  setThreadName(threadID: number, name: string): GoResult;
+  mapRegion(base: GoAddress, size: GoAddress, perms: number): GoResult;
+  unmapRegion(base: GoAddress): GoResult;
+  checkAccess(address: GoAddress, accessType: GoAccessType): boolean;
+  validateAddress(address: GoAddress, accessType: GoAccessType): GoResult;
+  listRegions(): GoMemoryRegion[];
+  onStats(intervalMs: number, callback: (stats: GoVMStats) => void): boolean;
+  stopStatsHeartbeat(): boolean;
+  onThreadStateChange(
+    callback: ((threadID: number, oldStatus: string, newStatus: string) => void) | null
+  ): boolean;
+  setSpeedLimit(instructionsPerSecond: number, perThread?: boolean): boolean;
+  setDeterministic(seed: number | null): boolean;
+  onDisassemble(callback: ((pc: GoAddress) => string) | null): boolean;
+  getDisassembly(pc: GoAddress): string | null;
+  invalidateDisassembly(address: GoAddress, size: GoAddress): boolean;
+  setEventQueueSize(size: number): boolean;
+  pause(): GoResult;
+  resume(): GoResult;
+  isPaused(): boolean;
+  createThreadGroup(): number;
+  addThreadToGroup(groupID: number, threadID: number): GoResult;
+  suspendGroup(groupID: number): GoResult<{ count: number }>;
+  resumeGroup(groupID: number): GoResult<{ count: number }>;
+  terminateGroup(groupID: number): GoResult<{ count: number }>;
+  setGroupCpuQuota(groupID: number, percent: number | null): GoResult;
+  setAddressSpaceSize(bytes: GoAddress, policy?: 'wrap' | 'fault'): boolean;
+  injectFault(
+    threadID: number,
+    faultType: 'segfault' | 'illegal_instruction' | 'div_by_zero' | 'stack_overflow',
+    address?: GoAddress,
+    access?: 'read' | 'write' | 'execute'
+  ): GoResult;
+  compareAndSwap(
+    address: GoAddress,
+    expected: number,
+    newValue: number
+  ): GoResult<{ observed: number; swapped: boolean }>;
+  onInstructionMilestone(interval: number, callback: ((count: number) => void) | null): boolean;
+  dumpThreads(): string;
+  setStackGrowth(policy: 'double' | 'fixed', increment?: number): boolean;
+  onIdle(callback: (() => void) | null): boolean;
+  enableLatencyHistogram(): boolean;
+  disableLatencyHistogram(): boolean;
+  getLatencyHistogram(): Record<GoLatencyBucket, number> | null;
+  resetHistogram(): boolean;
+  requestSafepoint(): Promise<number>;
+  releaseSafepoint(token: number): boolean;
+  setThreadEmulator(threadID: number, emulatorPtr: any): GoResult;
+  setBridgeTimeout(ms: number): boolean;
+  setParallelism(n: number): number;
+  enableOpcodeProfiling(): boolean;
+  disableOpcodeProfiling(): boolean;
+  /** Keys are decimal opcodes */
+  getOpcodeProfile(): Record<string, number> | null;
+  exportThread(threadID: number): Promise<string>;
+  importThread(state: string): GoResult<{ threadID: number }>;
+  setLogLevel(level: GoLogLevel): boolean;
+  onLog(callback: ((level: GoLogLevel, message: string) => void) | null): boolean;
+  reapThread(threadID: number): GoResult;
+  setReapTimeout(ms: number): boolean;
+  setYieldStrategy(strategy: 'sleep0' | 'gosched' | 'none' | `interval(${number})`): boolean;
+  readMemory(address: GoAddress, length: number): GoResult<{ bytes: Uint8Array }>;
+  writeMemory(address: GoAddress, bytes: Uint8Array | number[]): GoResult;
+  getCallStack(threadID: number): GoResult<{ frames: GoStackFrame[]; truncated: boolean }>;
+  onSymbolize(callback: ((pc: GoAddress) => string | null) | null): boolean;
+  createThreads(specs: GoThreadSpec[]): GoResult<{
+    threadIDs: number[];
+    errors: ({ error: GoErrorCode; message: string } | null)[];
+  }>;
+  getCyclesPerInstruction(): number;
+  setFreezeOnFault(enabled: boolean): boolean;
+  onFreeze(callback: ((threadID: number) => void) | null): boolean;
+  getGuestTicks(): number;
+  setTickRate(hz: number, clockHz?: number | null): boolean;
+  setStatsCacheMs(ms: number): boolean;
+  listThreadIDs(includeZombies?: boolean): number[];
+  resetPeakThreadCount(): boolean;
+  setAllowThreadsWhileStopped(allowed: boolean): boolean;
+  replayTrace(
+    threadID: number,
+    trace: { threadID?: number; pc: GoAddress }[],
+    expected?: { pc?: GoAddress; registers?: number[] }
+  ): GoResult<GoReplayResult>;
+  killThread(threadID: number): GoResult;
+  getFaultLog(): GoFaultRecord[];
+  clearFaultLog(): boolean;
+  setFaultLogSize(size: number): boolean;
+  getErrno(threadID: number): number | null;
+  setErrno(threadID: number, value: number): GoResult;
+  registerSyscall(
+    number: number,
+    handler: ((args: unknown, threadID: number) => number | void) | null
+  ): boolean;
+  dispatchSyscall(threadID: number, number: number, args?: unknown): GoResult<{ handled: boolean; value: unknown }>;
+  onUnhandledSyscall(callback: ((threadID: number, number: number) => void) | null): boolean;
+  setCpuFeatures(flags: number): boolean;
+  getCpuFeatures(): number;
+  enableScheduleTrace(capacity?: number): boolean;
+  disableScheduleTrace(): boolean;
+  getScheduleTrace(): GoScheduleEvent[];
+  setMaxThreads(limit: number): boolean;
+  onThreadLimitReached(callback: ((limit: number) => void) | null): boolean;
+  saveContext(threadID: number, label: string): GoResult;
+  restoreContext(threadID: number, label: string): GoResult;
   getStats(): GoVMStats;
   getThreadCount(): number;
   isRunning(): boolean;
 }
 
+export interface GoThreadExit {
+  id: number;
+  name: string;
+  pc: GoAddress;
+  registers: number[];
+  reason: 'halted' | 'stopped' | 'instruction_limit' | 'killed' | 'migrated' | 'faulted';
+  limitExceeded: boolean;
+  instructionsExecuted: number;
+  /** Why the thread faulted, empty if it did not */
+  faultMessage: string;
+}
+
+export interface GoThreadSnapshot {
+  id: number;
+  name: string;
+  pc: GoAddress;
+  registers: number[];
+  stack: number[];
+  status: string;
+  priority: number;
+  tls: Record<string, number | string | boolean>;
+  /** Creation time in Unix milliseconds */
+  createdAt: number;
+  errno: number;
+  /** True if a "waiting" thread can be woken with wakeThread */
+  wakeable: boolean;
+  /** Status a "waiting" thread returns to when its wait ends */
+  resumeStatus?: 'running' | 'paused';
+  instructionsExecuted: number;
+  cyclesExecuted: number;
+  cpuTimeMs: number;
+}
+
+export interface GoVMSnapshot {
+  threadCounter: number;
+  registerCount: number;
+  threads: GoThreadSnapshot[];
+  stats: Pick<
+    GoVMStats,
+    | 'instructionsExecuted'
+    | 'memoryAllocated'
+    | 'threadsCreated'
+    | 'threadsTerminated'
+    | 'executionTime'
+    | 'cyclesExecuted'
+    | 'peakMemoryAllocated'
+    | 'allocationErrors'
+    | 'faults'
+    | 'yields'
+    | 'stackGrowths'
+    | 'peakActiveThreads'
+    | 'cpuTimeMs'
+    | 'threadCreationRejections'
+  >;
+}
+
+export interface GoThreadStats {
+  id: number;
+  name: string;
+  pc: GoAddress;
+  status: string;
+  instructionsExecuted: number;
+  stackDepth: number;
+  faultMessage: string;
+  /** Address of the memory access that faulted the thread, if any */
+  faultAddress: GoAddress | null;
+  affinity: number;
+  cpuTimeMs: number;
+  /** Real time since creation */
+  ageMs: number;
+  cyclesExecuted: number;
+}
+
+export interface GoCreateThreadOptions {
+  /** false creates the thread "ready"; call startThread to run it */
+  autostart?: boolean;
+  /** Initial stack capacity in entries (default 1024) */
+  stackCapacity?: number;
+}
+
+export interface GoOrchestratorOptions {
+  registerCount?: number;
+  /** Return bare booleans/IDs instead of GoResult objects */
+  legacyResults?: boolean;
+}
+
+export type GoErrorCode =
+  | 'invalid_argument'
+  | 'already_running'
+  | 'not_running'
+  | 'unknown_thread'
+  | 'invalid_state'
+  | 'out_of_range'
+  | 'stack_overflow'
+  | 'stack_empty'
+  | 'halted'
+  | 'unknown_mutex'
+  | 'not_owner'
+  | 'unknown_condvar'
+  | 'region_overlap'
+  | 'unknown_region'
+  | 'unknown_group'
+  | 'access_denied'
+  | 'unsupported'
+  | 'thread_limit'
+  | 'unknown_context'
+  | 'bridge_exception';
+
+export type GoResult<T extends object = {}> =
+  | ({ ok: true } & T)
+  | { ok: false; error: GoErrorCode; message: string };
+
+/** Guest address: a number, or a decimal/0x string above 2^53 */
+export type GoAddress = number | string;
+
+export interface GoTraceEntry {
+  threadID: number;
+  name: string;
+  pc: GoAddress;
+  /** Global instruction sequence number, unique and increasing across threads */
+  seq: number;
+}
+
+export type GoAccessType = 'read' | 'write' | 'execute';
+
+/** Region permissions: bitmask of 1 (read), 2 (write), 4 (execute) */
+export interface GoMemoryRegion {
+  base: GoAddress;
+  size: GoAddress;
+  perms: number;
+}
+
+export interface GoBreakpointCondition {
+  register: number;
+  op: '==' | '!=' | '<' | '>' | '<=' | '>=';
+  value: number;
+}
+
+export interface GoThreadInfo {
+  id: number;
+  name: string;
+  pc: GoAddress;
+  /** The instruction executing or last executed */
+  lastPC: GoAddress;
+  status: string;
+  registers: number[];
+  stackDepth: number;
+  priority: number;
+  /** Priority including any boost inherited from threads waiting on its mutexes */
+  effectivePriority: number;
+  /** Thread group ID, 0 when the thread is in no group */
+  group: number;
+  instructionsExecuted: number;
+  cyclesExecuted: number;
+  cpuTimeMs: number;
+  /** Real time since creation, frozen at termination */
+  ageMs: number;
+  /** True for a terminated thread kept until reapThread */
+  zombie: boolean;
+  /** Empty until the thread terminates */
+  exitReason: string;
+  /** True if the last waitThread wait ended by its timeout */
+  timedOut: boolean;
+  /** True if a wakeThread is waiting to end the next waitThread */
+  wakePending: boolean;
+  errno: number;
+}
+
+export type GoLatencyBucket = '<1us' | '<10us' | '<100us' | '<1ms' | '<10ms' | '<100ms' | '>=100ms';
+
+export interface GoThreadDiff {
+  id: number;
+  registers: { index: number; old: number | null; new: number | null }[];
+  pc?: { old: GoAddress; new: GoAddress };
+  status?: { old: string; new: string };
+}
+
+export interface GoSnapshotDiff {
+  threads: GoThreadDiff[];
+  added: number[];
+  removed: number[];
+}
+
+export type GoLogLevel = 'off' | 'error' | 'info' | 'debug';
+
+export interface GoStackFrame {
+  pc: GoAddress;
+  symbol: string | null;
+}
+
+export interface GoThreadSpec extends GoCreateThreadOptions {
+  startPC: GoAddress;
+  name?: string;
+  priority?: number;
+  mode?: GoThreadMode;
+}
+
+export interface GoReplayResult {
+  executed: number;
+  divergedAt: number;
+  pc: GoAddress;
+  registers: number[];
+  pcMatches: boolean | null;
+  mismatches: { index: number; expected: number; actual: number | null }[] | null;
+}
+
+export interface GoFaultRecord {
+  threadID: number;
+  pc: GoAddress;
+  reason: 'exception' | 'segfault' | 'error';
+  message: string;
+  /** Faulting address for segfaults, null otherwise */
+  address: GoAddress | null;
+  /** Milliseconds since the Unix epoch */
+  timestamp: number;
+}
+
+export interface GoScheduleEvent {
+  /** Fractional milliseconds since the Unix epoch */
+  timestamp: number;
+  threadID: number;
+  event: 'dispatch' | 'preempt' | 'block' | 'wake';
+}
+
+export interface GoAutoSnapshot {
+  /** The interval multiple that triggered the snapshot */
+  instructionCount: number;
+  /** Milliseconds since the Unix epoch */
+  timestamp: number;
+  snapshot: GoVMSnapshot;
+}
+
+export type GoInstructionStatus = 'ok' | 'halt' | 'fault' | 'syscall' | 'branch';
+
+/**
+ * What an emulator's executeInstruction may return instead of a boolean.
+ * executeInstructions results may add the same fields for the last
+ * instruction in the batch.
+ */
+export interface GoInstructionResult {
+  status: GoInstructionStatus;
+  /** Next PC; required for "branch" */
+  pc?: GoAddress;
+  /** Fault message for "fault" */
+  faultReason?: string;
+  /** Syscall number for "syscall" */
+  syscall?: number;
+  /** Syscall arguments for "syscall", defaulting to the thread's registers */
+  args?: number[];
+  length?: number;
+  cycles?: number;
+  yield?: boolean;
+}
+
 export interface GoVMStats {
   instructionsExecuted: number;
   memoryAllocated: number;
@@ -22,6 +454,30 @@ export interface GoVMStats {
   threadsTerminated: number;
   executionTime: number;
   activeThreads: number;
+  schedulingPolicy: string;
+  workerPoolSize: number;
+  runQueueDepth: number;
+  threadStats: GoThreadStats[];
+  instructionsPerSecond: number;
+  peakMemoryAllocated: number;
+  allocationErrors: number;
+  pendingTicks: number;
+  faults: number;
+  yields: number;
+  cpuTimeMs: number;
+  droppedEvents: number;
+  /** Callbacks that threw; the dispatcher skips them and carries on */
+  failedEvents: number;
+  longestWaitMs: number;
+  /** -1 when no thread is waiting */
+  longestWaitThreadID: number;
+  stackGrowths: number;
+  parallelism: number;
+  cyclesExecuted: number;
+  threadCreationRejections: number;
+  /** Most threads active at once since the last resetPeakThreadCount */
+  peakActiveThreads: number;
+  instructionSequence: number;
 }
 
 export class GoWASMBridge {
@@ -138,7 +594,7 @@ export class GoWASMBridge {
    */
   start(): boolean {
     this._ensureReady();
-    return this.orchestrator!.start();
+    return this.orchestrator!.start().ok;
   }
 
   /**
@@ -146,15 +602,16 @@ export class GoWASMBridge {
    */
   stop(): boolean {
     this._ensureReady();
-    return this.orchestrator!.stop();
+    return this.orchestrator!.stop().ok;
   }
 
   /**
    * Create a new execution thread
    */
-  createThread(startPC: number): number {
+  createThread(startPC: GoAddress, priority?: number, mode?: GoThreadMode, name?: string): number {
     this._ensureReady();
-    return this.orchestrator!.createThread(startPC);
+    const result = this.orchestrator!.createThread(startPC, priority, mode, name);
+    return result.ok ? result.threadID : -1;
   }
 
   /**
@@ -0,0 +1,100 @@
+// Address Space Bounds
+// Decides what happens when the PC advances past the end of guest memory
+//
+// By default the PC is a 64-bit value that wraps modulo 2^64, which is what
+// the Go arithmetic did before bounds were configurable. SetAddressSpaceSize
+// declares a smaller space and a policy for running off its end:
+//
+//   - "wrap" continues at address (pc+length) mod size. RISC-V defines
+//     address arithmetic to wrap modulo 2^XLEN, real-mode x86 wraps IP
+//     within a 64 KiB segment, and 8-bit CPUs such as the 6502 and Z80 wrap
+//     their 16-bit PC.
+//   - "fault" faults the thread instead. On AArch64 and x86-64, executing
+//     past the end of the canonical or mapped range raises a translation
+//     fault or #GP, so guests never observe a wrapped PC.
+
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"syscall/js"
+)
+
+// addressSpace bounds PC advancement
+type addressSpace struct {
+	size uint64 // bytes; 0 = the full 64-bit space
+	wrap bool   // wrap at the end instead of faulting
+}
+
+// SetAddressSpaceSize sets the guest address space size in bytes and the
+// policy ("wrap" or "fault", default "wrap") for advancing past its end.
+// A size of 0 means the full 64-bit space.
+// Arguments: bytes, optional policy
+func (vo *VMOrchestrator) SetAddressSpaceSize(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(false)
+	}
+
+	size, ok := jsToAddress(args[0])
+	if !ok {
+		return js.ValueOf(false)
+	}
+
+	policy := "wrap"
+	if len(args) > 1 && args[1].Type() == js.TypeString {
+		policy = args[1].String()
+	}
+	if policy != "wrap" && policy != "fault" {
+		return js.ValueOf(false)
+	}
+
+	vo.addressSpace.Store(&addressSpace{size: size, wrap: policy == "wrap"})
+	return js.ValueOf(true)
+}
+
+// advancePC returns the address length bytes after pc, applying the
+// address space policy. Returns false if the thread should fault.
+func (vo *VMOrchestrator) advancePC(pc, length uint64) (uint64, bool) {
+	space := vo.addressSpace.Load()
+	if space == nil {
+		return pc + length, true
+	}
+
+	next, carry := bits.Add64(pc, length, 0)
+	if space.size == 0 {
+		return next, carry == 0 || space.wrap
+	}
+	if carry == 0 && next < space.size {
+		return next, true
+	}
+	if !space.wrap {
+		return 0, false
+	}
+
+	// Both terms are below size, so one subtraction reduces the sum; with a
+	// carry the true sum is next+2^64 and unsigned wraparound absorbs it
+	next, carry = bits.Add64(pc%space.size, length%space.size, 0)
+	if carry != 0 || next >= space.size {
+		next -= space.size
+	}
+	return next, true
+}
+
+// boundPC applies the address space policy to a PC reported by the bridge.
+// Returns false if the thread should fault.
+func (vo *VMOrchestrator) boundPC(pc uint64) (uint64, bool) {
+	space := vo.addressSpace.Load()
+	if space == nil || space.size == 0 || pc < space.size {
+		return pc, true
+	}
+	if !space.wrap {
+		return 0, false
+	}
+	return pc % space.size, true
+}
+
+// pcOutOfRange faults a thread whose PC left the address space
+func (vo *VMOrchestrator) pcOutOfRange(thread *VMThread, pc uint64) {
+	vo.faultThread(thread, fmt.Sprintf("pc advanced past the end of the address space from %#x", pc))
+}
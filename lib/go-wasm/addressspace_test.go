@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAdvancePCAtTheBoundary(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	tests := []struct {
+		size     interface{}
+		policy   string
+		pc, want uint64
+		ok       bool
+	}{
+		{0x10000, "wrap", 0xfff8, 0xfffc, true},
+		{0x10000, "wrap", 0xfffc, 0x0000, true},
+		{0x10000, "wrap", 0xfffe, 0x0002, true},
+		{0x10000, "fault", 0xfff8, 0xfffc, true},
+		{0x10000, "fault", 0xfffc, 0, false},
+		{"0x100000000", "wrap", 0xfffffffc, 0, true},
+		{"0x100000000", "fault", 0xfffffffc, 0, false},
+		{0, "wrap", 1<<64 - 4, 0, true},
+		{0, "fault", 1<<64 - 4, 0, false},
+	}
+	for _, test := range tests {
+		if !call(vo.SetAddressSpaceSize, test.size, test.policy).Bool() {
+			t.Fatalf("setAddressSpaceSize(%v, %s) failed", test.size, test.policy)
+		}
+		got, ok := vo.advancePC(test.pc, 4)
+		if ok != test.ok || (ok && got != test.want) {
+			t.Errorf("size %v %s: advancePC(%#x) = %#x, %v, want %#x, %v", test.size, test.policy, test.pc, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestThreadWrapsAtTheBoundary(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	call(vo.SetAddressSpaceSize, 0x10000, "wrap")
+
+	id := createThread(t, vo, 0xfffc, 1, "paused")
+	requireOK(t, call(vo.StepThread, id))
+	if got := threadPC(t, vo, id); got != 0 {
+		t.Errorf("pc = %#x after stepping off the end, want 0", got)
+	}
+}
+
+func TestThreadFaultsAtTheBoundary(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	call(vo.SetAddressSpaceSize, 0x10000, "fault")
+
+	id := createThread(t, vo, 0xfffc, 1, "paused")
+	call(vo.StepThread, id)
+	if got := threadStatus(vo, id); got != "faulted" {
+		t.Fatalf("thread is %q after stepping off the end, want faulted", got)
+	}
+	if got := threadPC(t, vo, id); got != 0xfffc {
+		t.Errorf("faulted pc = %#x, want the last instruction at 0xfffc", got)
+	}
+}
@@ -0,0 +1,87 @@
+// Guest Atomics
+// Compare-and-swap on guest memory, serialized by the orchestrator
+//
+// Several VM threads may execute concurrently on different workers, so a
+// guest CAS implemented as a separate read and write through the bridge
+// could interleave with another thread's CAS on the same word and lose an
+// update. CompareAndSwap performs the read-compare-write under a lock
+// striped by address: CAS operations on the same word are serialized, while
+// those on unrelated words rarely contend. Plain guest stores made by the
+// emulator itself are not covered, just as a hardware CAS does not order
+// non-atomic accesses.
+//
+// The bridge must implement readWord(address) and writeWord(address, value)
+// for 32-bit words. If either throws, the call fails with bridge_exception
+// and the stripe is unlocked.
+
+package main
+
+import (
+	"fmt"
+	"syscall/js"
+)
+
+// casStripes is the number of locks CAS addresses are spread over
+const casStripes = 64
+
+// CompareAndSwap atomically replaces the 32-bit word at address with
+// newValue if it equals expected. Returns { observed, swapped }, where
+// observed is the value read before any write.
+// Arguments: address, expected, newValue
+func (vo *VMOrchestrator) CompareAndSwap(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return vo.fail(false, errInvalidArgument, "compareAndSwap requires an address, an expected value and a new value")
+	}
+
+	address, ok := jsToAddress(args[0])
+	if !ok {
+		return vo.fail(false, errInvalidArgument, "invalid address")
+	}
+	expected, newValue := uint32(args[1].Int()), uint32(args[2].Int())
+
+	if !vo.accessAllowed(address, permRead) || !vo.accessAllowed(address, permWrite) {
+		return vo.fail(false, errAccessDenied, "address %#x is not mapped read-write", address)
+	}
+
+	emulator := vo.emulator()
+	if !emulator.Truthy() || emulator.Get("readWord").Type() != js.TypeFunction ||
+		emulator.Get("writeWord").Type() != js.TypeFunction {
+		return vo.fail(false, errUnsupported, "the emulator bridge does not implement readWord and writeWord")
+	}
+
+	observed, swapped, err := vo.compareAndSwap(emulator, address, expected, newValue)
+	if err != nil {
+		return vo.fail(false, errBridgeException, "compare-and-swap at %#x failed: %v", address, err)
+	}
+
+	return vo.succeed(swapped, map[string]interface{}{
+		"observed": observed,
+		"swapped":  swapped,
+	})
+}
+
+// compareAndSwap performs the read-compare-write under the address's stripe
+// lock, which is released even if the bridge throws
+func (vo *VMOrchestrator) compareAndSwap(emulator js.Value, address uint64, expected, newValue uint32) (observed uint32, swapped bool, err error) {
+	// Words are 4-byte aligned, so stripe on the word index
+	stripe := &vo.casLocks[(address>>2)%casStripes]
+	stripe.Lock()
+	defer stripe.Unlock()
+
+	word, err := callJS(func() js.Value { return emulator.Call("readWord", addressToJS(address)) })
+	if err != nil {
+		return 0, false, err
+	}
+	if word.Type() != js.TypeNumber {
+		return 0, false, fmt.Errorf("readWord returned %s, not a number", word.Type())
+	}
+
+	observed = uint32(word.Int())
+	if observed != expected {
+		return observed, false, nil
+	}
+	if _, err := callJS(func() js.Value { return emulator.Call("writeWord", addressToJS(address), newValue) }); err != nil {
+		return observed, false, err
+	}
+	return observed, true, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"syscall/js"
+	"testing"
+)
+
+// wordBridge returns a bridge backed by a Go word map. readWord yields so
+// a CAS that is not serialized would interleave with others.
+func wordBridge(t *testing.T, memory map[float64]uint32) js.Value {
+	return newBridge(t, map[string]func([]js.Value) interface{}{
+		"readWord": func(args []js.Value) interface{} {
+			value := memory[args[0].Float()]
+			runtime.Gosched()
+			return value
+		},
+		"writeWord": func(args []js.Value) interface{} {
+			memory[args[0].Float()] = uint32(args[1].Int())
+			return nil
+		},
+	})
+}
+
+func TestCompareAndSwapHammer(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	memory := map[float64]uint32{}
+	call(vo.Initialize, wordBridge(t, memory))
+
+	const workers, increments, address = 4, 50, 0x8000
+	var wg sync.WaitGroup
+	errs := make(chan string, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < increments; i++ {
+				for {
+					observed := call(vo.CompareAndSwap, address, 0, 0)
+					if !observed.Get("ok").Truthy() {
+						errs <- observed.Get("message").String()
+						return
+					}
+					value := observed.Get("observed").Int()
+					if call(vo.CompareAndSwap, address, value, value+1).Get("swapped").Bool() {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if got := memory[address]; got != workers*increments {
+		t.Fatalf("word = %d after %d increments, lost %d updates", got, workers*increments, workers*increments-int(got))
+	}
+}
+
+func TestCompareAndSwapMismatch(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	memory := map[float64]uint32{0x8000: 7}
+	call(vo.Initialize, wordBridge(t, memory))
+
+	result := requireOK(t, call(vo.CompareAndSwap, 0x8000, 3, 9))
+	if result.Get("swapped").Bool() || result.Get("observed").Int() != 7 {
+		t.Errorf("cas(3, 9) on 7 = %v/%v, want observed 7 and no swap", result.Get("observed"), result.Get("swapped"))
+	}
+	if memory[0x8000] != 7 {
+		t.Errorf("word = %d after a failed cas, want 7", memory[0x8000])
+	}
+}
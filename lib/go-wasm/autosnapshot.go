@@ -0,0 +1,122 @@
+// Automatic Snapshots
+// Periodic checkpoints for rewinding to the nearest recent state
+//
+// SetAutoSnapshot takes a snapshot each time the global instructionsExecuted
+// counter crosses a multiple of the interval and keeps the most recent ones
+// in a ring, which GetAutoSnapshots returns for Restore. Crossings are
+// detected in countInstructions like instruction milestones, but the
+// snapshot itself is taken on the event dispatcher under a safe point, as
+// Snapshot does: the worker that crossed the interval cannot wait for the
+// others to park while it is itself in flight. A snapshot therefore lands
+// at the first safe point after its interval and may include a few more
+// instructions; crossings that happen while one is still pending are
+// coalesced into it. The snapshot event is critical, so a full event queue
+// delays it but never drops it.
+
+package main
+
+import (
+	"syscall/js"
+	"time"
+
+	"github.com/aquifer/vm-orchestrator/internal/ring"
+)
+
+// defaultAutoSnapshotKeep is how many automatic snapshots are retained when
+// SetAutoSnapshot is not given a count
+const defaultAutoSnapshotKeep = 8
+
+// autoSnapshotter is an automatic snapshot configuration and its retained
+// snapshots
+type autoSnapshotter struct {
+	interval  uint64
+	snapshots *ring.Buffer[autoSnapshot]
+}
+
+// autoSnapshot is one retained automatic snapshot
+type autoSnapshot struct {
+	interval uint64 // the interval multiple that triggered it
+	at       time.Time
+	snapshot *vmSnapshot
+}
+
+// SetAutoSnapshot snapshots the VM every everyNInstructions instructions
+// executed VM-wide, keeping the last keep snapshots (default 8). Any
+// previously retained snapshots are discarded; 0 turns automatic snapshots
+// off.
+// Arguments: everyNInstructions, optional keep
+func (vo *VMOrchestrator) SetAutoSnapshot(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber {
+		return js.ValueOf(false)
+	}
+
+	interval := args[0].Int()
+	if interval < 0 {
+		return js.ValueOf(false)
+	}
+	if interval == 0 {
+		vo.snapshots.Store(nil)
+		return js.ValueOf(true)
+	}
+
+	keep := defaultAutoSnapshotKeep
+	if len(args) > 1 && args[1].Type() == js.TypeNumber {
+		if keep = args[1].Int(); keep < 1 {
+			return js.ValueOf(false)
+		}
+	}
+
+	vo.snapshots.Store(&autoSnapshotter{
+		interval:  uint64(interval),
+		snapshots: ring.New[autoSnapshot](keep),
+	})
+	return js.ValueOf(true)
+}
+
+// GetAutoSnapshots returns the retained automatic snapshots, oldest first,
+// as [{ instructionCount, timestamp, snapshot }]. instructionCount is the
+// interval multiple that triggered the snapshot and snapshot can be passed
+// to Restore; timestamp is in milliseconds since the Unix epoch.
+func (vo *VMOrchestrator) GetAutoSnapshots(this js.Value, args []js.Value) interface{} {
+	auto := vo.snapshots.Load()
+	if auto == nil {
+		return js.ValueOf([]interface{}{})
+	}
+
+	entries := auto.snapshots.Snapshot()
+	result := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		result[i] = map[string]interface{}{
+			"instructionCount": float64(entry.interval),
+			"timestamp":        entry.at.UnixMilli(),
+			"snapshot":         entry.snapshot.toJSObject(),
+		}
+	}
+	return js.ValueOf(result)
+}
+
+// queueAutoSnapshot queues an automatic snapshot if the instruction counter
+// moving from before by n crossed a snapshot interval
+func (vo *VMOrchestrator) queueAutoSnapshot(before, n uint64) {
+	auto := vo.snapshots.Load()
+	if auto == nil || (before+n)/auto.interval == before/auto.interval {
+		return
+	}
+
+	crossed := (before + n) / auto.interval * auto.interval
+	vo.postEvent(eventCritical, "autoSnapshot", func() { vo.takeAutoSnapshot(auto, crossed) })
+}
+
+// takeAutoSnapshot snapshots the VM at a safe point into auto's ring.
+// Runs on the event dispatcher.
+func (vo *VMOrchestrator) takeAutoSnapshot(auto *autoSnapshotter, crossed uint64) {
+	if vo.snapshots.Load() != auto {
+		return // reconfigured since the interval was crossed
+	}
+
+	token := vo.holdSafepoint()
+	snapshot := vo.takeSnapshot()
+	vo.releaseSafepoint(token)
+
+	auto.snapshots.Push(autoSnapshot{interval: crossed, at: time.Now(), snapshot: snapshot})
+}
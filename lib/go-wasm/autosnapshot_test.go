@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAutoSnapshotIntervalsAndEviction(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	if !call(vo.SetAutoSnapshot, 10, 3).Bool() {
+		t.Fatal("setAutoSnapshot(10, 3) failed")
+	}
+	id := createThread(t, vo, 0x1000, 1, "paused")
+
+	// Stepping one instruction at a time, waiting for each snapshot, puts
+	// every snapshot exactly on its interval
+	for executed := 1; executed <= 55; executed++ {
+		requireOK(t, call(vo.StepThread, id))
+		if executed%10 == 0 {
+			taken := executed / 10
+			if taken > 3 {
+				taken = 3
+			}
+			eventually(t, "the snapshot to be taken", func() bool {
+				snapshots := call(vo.GetAutoSnapshots)
+				return snapshots.Length() == taken &&
+					snapshots.Index(taken-1).Get("instructionCount").Int() == executed
+			})
+		}
+	}
+
+	snapshots := call(vo.GetAutoSnapshots)
+	if snapshots.Length() != 3 {
+		t.Fatalf("%d snapshots retained, want 3", snapshots.Length())
+	}
+	for i, want := range []int{30, 40, 50} {
+		entry := snapshots.Index(i)
+		if got := entry.Get("instructionCount").Int(); got != want {
+			t.Fatalf("snapshot %d taken at %d instructions, want %d (oldest evicted first)", i, got, want)
+		}
+		thread := entry.Get("snapshot").Get("threads").Index(0)
+		if got, pc := uint64(thread.Get("pc").Float()), uint64(0x1000+4*want); got != pc {
+			t.Errorf("snapshot %d has pc %#x, want %#x", i, got, pc)
+		}
+	}
+
+	// A retained snapshot rewinds the thread to its checkpoint
+	if !call(vo.Restore, snapshots.Index(0).Get("snapshot")).Bool() {
+		t.Fatal("restoring an automatic snapshot failed")
+	}
+	if got := threadPC(t, vo, id); got != 0x1000+4*30 {
+		t.Errorf("pc = %#x after restoring the oldest snapshot, want %#x", got, 0x1000+4*30)
+	}
+
+	call(vo.SetAutoSnapshot, 0)
+	if got := call(vo.GetAutoSnapshots).Length(); got != 0 {
+		t.Errorf("%d snapshots after turning automatic snapshots off", got)
+	}
+	for _, bad := range [][]interface{}{{-1}, {10, 0}} {
+		if call(vo.SetAutoSnapshot, bad...).Bool() {
+			t.Errorf("setAutoSnapshot%v succeeded", bad)
+		}
+	}
+}
+
+func TestAutoSnapshotSurvivesFullEventQueue(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	call(vo.SetAutoSnapshot, 10)
+	call(vo.SetEventQueueSize, 2)
+	id := createThread(t, vo, 0x1000, 1, "paused")
+
+	// Stall the dispatcher like a slow callback, queue the snapshot, then
+	// overflow the queue with callbacks that would evict older events
+	started, release := make(chan struct{}), make(chan struct{})
+	vo.postEvent(eventNormal, "", func() {
+		close(started)
+		<-release
+	})
+	<-started
+	for i := 0; i < 10; i++ {
+		requireOK(t, call(vo.StepThread, id))
+	}
+	for i := 0; i < 4; i++ {
+		vo.postEvent(eventNormal, "", func() {})
+	}
+	close(release)
+	eventually(t, "the snapshot to be taken", func() bool { return call(vo.GetAutoSnapshots).Length() == 1 })
+	if got := call(vo.GetAutoSnapshots).Index(0).Get("instructionCount").Int(); got != 10 {
+		t.Errorf("snapshot taken at %d instructions, want 10", got)
+	}
+}
@@ -0,0 +1,148 @@
+// Batched Execution
+// Runs several instructions per WASM/JS boundary crossing
+//
+// When the batch size is above 1 and the bridge implements
+// executeInstructions(pc, n), a thread's quantum is executed in batches:
+// the bridge runs up to n instructions natively and returns
+// { executed, pc, lastPC, halted, yield }. A batch that stops on an
+// instruction needing the orchestrator's attention may also report that
+// instruction's status, faultReason, syscall and args exactly as
+// executeInstruction does (see bridgestatus.go); halted is the legacy
+// spelling of status "halt". Per-instruction hooks cannot observe the inside
+// of a batch, so the orchestrator falls back to single-stepping while any
+// breakpoint is set, the thread has register watches, tracing or opcode
+// profiling is on or memory regions are mapped, and batches never run past
+// a thread's instruction limit.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+)
+
+// SetBatchSize sets how many instructions to run per bridge call (1 disables batching)
+func (vo *VMOrchestrator) SetBatchSize(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(false)
+	}
+
+	size := args[0].Int()
+	if size < 1 {
+		return js.ValueOf(false)
+	}
+
+	atomic.StoreInt32(&vo.batchSize, int32(size))
+	return js.ValueOf(true)
+}
+
+// batchLength returns how many instructions the next bridge call may run for
+// a thread, given the instructions remaining in its quantum. A result of 1
+// means the single-step path must be used.
+func (vo *VMOrchestrator) batchLength(thread *VMThread, remaining int) int {
+	batch := int(atomic.LoadInt32(&vo.batchSize))
+	if batch <= 1 || remaining <= 1 || vo.trace.Load() != nil || vo.opcodes.Load() != nil || vo.regionsMapped() {
+		return 1
+	}
+	if remaining < batch {
+		batch = remaining
+	}
+
+	vo.breakpointMutex.RLock()
+	breakpoints := len(vo.breakpoints)
+	vo.breakpointMutex.RUnlock()
+	if breakpoints > 0 {
+		return 1
+	}
+
+	thread.mutex.RLock()
+	defer thread.mutex.RUnlock()
+
+	if len(thread.watches) > 0 {
+		return 1
+	}
+	if thread.instructionLimit > 0 {
+		if thread.instructionsExecuted >= thread.instructionLimit {
+			return 1 // let stepInstruction terminate the thread
+		}
+		if left := thread.instructionLimit - thread.instructionsExecuted; left < uint64(batch) {
+			batch = int(left)
+		}
+	}
+
+	emulator := thread.emulator
+	if !emulator.Truthy() {
+		emulator = vo.emulator()
+	}
+	if !emulator.Truthy() || emulator.Get("executeInstructions").Type() != js.TypeFunction {
+		return 1
+	}
+	return batch
+}
+
+// stepBatch runs up to n instructions in one bridge call, updates the PC
+// from the bridge and adds the executed count to the stats. Returns the
+// number executed and false if the emulator halted the thread.
+func (vo *VMOrchestrator) stepBatch(thread *VMThread, pc uint64, n int) (int, bool) {
+	result, ok := vo.callBridge(thread, vo.threadEmulator(thread), "executeInstructions", addressToJS(pc), n)
+	if !ok {
+		return 0, false
+	}
+	if result.Type() != js.TypeObject {
+		vo.terminateThread(thread, "halted")
+		return 0, false
+	}
+
+	executed := 0
+	if count := result.Get("executed"); count.Type() == js.TypeNumber {
+		executed = min(max(count.Int(), 0), n)
+	}
+
+	next, ok := jsToAddress(result.Get("pc"))
+	if ok {
+		next, ok = vo.boundPC(next)
+	} else {
+		next, ok = vo.advancePC(pc, uint64(defaultInstructionLength*executed))
+	}
+
+	last, reported := jsToAddress(result.Get("lastPC"))
+	if !reported {
+		last = pc
+	}
+
+	thread.mutex.Lock()
+	if ok {
+		thread.pc = next
+	}
+	if executed > 0 {
+		thread.lastPC = last
+	}
+	cycles := reportedCycles(result, uint64(executed))
+	thread.instructionsExecuted += uint64(executed)
+	thread.cyclesExecuted += cycles
+	thread.bypassBreakpoint = false
+	thread.mutex.Unlock()
+
+	vo.countInstructions(uint64(executed), cycles)
+	vo.instructionSequence(uint64(executed))
+
+	// A status describes the last instruction run, like executeInstruction's
+	status := okStatus(!result.Get("halted").Truthy())
+	if reported := result.Get("status"); reported.Type() == js.TypeString {
+		status = reported.String()
+	}
+	if !vo.checkStatus(thread, result, status, last) {
+		return executed, false
+	}
+	if !ok {
+		vo.pcOutOfRange(thread, pc)
+		return executed, false
+	}
+	if status == statusSyscall && !vo.instructionSyscall(thread, result, last) {
+		return executed, false
+	}
+	if result.Get("yield").Truthy() {
+		vo.yieldThread(thread)
+	}
+	return executed, true
+}
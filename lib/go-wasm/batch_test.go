@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// batchBridge returns a bridge that implements both executeInstruction and
+// executeInstructions, counting the instructions and bridge calls each runs
+func batchBridge(t testing.TB, instructions, calls *int64) js.Value {
+	bridge := js.Global().Get("Object").New()
+	single := js.FuncOf(func(js.Value, []js.Value) interface{} {
+		atomic.AddInt64(instructions, 1)
+		atomic.AddInt64(calls, 1)
+		return true
+	})
+	batch := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		n := args[1].Int()
+		atomic.AddInt64(instructions, int64(n))
+		atomic.AddInt64(calls, 1)
+		return map[string]interface{}{"executed": n, "pc": args[0].Float() + float64(4*n)}
+	})
+	t.Cleanup(single.Release)
+	t.Cleanup(batch.Release)
+	bridge.Set("executeInstruction", single)
+	bridge.Set("executeInstructions", batch)
+	return bridge
+}
+
+func TestBatchedExecution(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	var instructions, calls int64
+	call(vo.Initialize, batchBridge(t, &instructions, &calls))
+	if !call(vo.SetBatchSize, 16).Bool() {
+		t.Fatal("setBatchSize(16) failed")
+	}
+	requireOK(t, call(vo.Start))
+
+	eventually(t, "batches to run", func() bool { return atomic.LoadInt64(&instructions) >= 1024 })
+	requireOK(t, call(vo.Pause))
+	// A batch may still be in flight when Pause returns
+	eventually(t, "the last batch to be counted", func() bool {
+		return stat(vo, "instructionsExecuted") == float64(atomic.LoadInt64(&instructions))
+	})
+
+	ran, crossings := atomic.LoadInt64(&instructions), atomic.LoadInt64(&calls)
+	if crossings*8 > ran {
+		t.Errorf("%d bridge calls for %d instructions, want batches of about 16", crossings, ran)
+	}
+	if got, want := threadPC(t, vo, 1), uint64(0x1000+4*ran); got != want {
+		t.Errorf("pc = %#x, want %#x", got, want)
+	}
+}
+
+func TestSetBatchSizeRejectsZero(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	if call(vo.SetBatchSize, 0).Bool() {
+		t.Error("setBatchSize(0) succeeded")
+	}
+}
+
+// BenchmarkBatchSize measures instruction throughput at several batch sizes
+func BenchmarkBatchSize(b *testing.B) {
+	const perOp = 1024
+	for _, size := range []int{1, 16, 256} {
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			vo := newOrchestrator(defaultRegisterCount)
+			var instructions, calls int64
+			call(vo.Initialize, batchBridge(b, &instructions, &calls))
+			call(vo.SetBatchSize, size)
+			call(vo.Start)
+			defer call(vo.Stop)
+
+			b.ResetTimer()
+			for i := 1; i <= b.N; i++ {
+				for atomic.LoadInt64(&instructions) < int64(i*perOp) {
+					time.Sleep(time.Microsecond) // lets timers fire under js/wasm
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(atomic.LoadInt64(&instructions))/float64(atomic.LoadInt64(&calls)), "instructions/call")
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestBranchesSetPCWithoutAdvancing(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func(args []js.Value) interface{} {
+			switch uint64(args[0].Float()) {
+			case 0x40000000: // call
+				return map[string]interface{}{"status": statusBranch, "pc": 0x40001000}
+			case 0x40001004: // return, in the legacy { ok, pc } form
+				return map[string]interface{}{"ok": true, "pc": 0x40000004}
+			case 0x40000008: // jump to the next instruction
+				return map[string]interface{}{"status": statusBranch, "pc": 0x4000000c}
+			case 0x4000000c: // branch that forgets its target
+				return map[string]interface{}{"status": statusBranch}
+			}
+			return true
+		},
+	}))
+	id := createThread(t, vo, 0x40000000, 1, "paused")
+
+	for _, want := range []uint64{0x40001000, 0x40001004, 0x40000004, 0x40000008, 0x4000000c} {
+		result := requireOK(t, call(vo.StepThread, id))
+		if got := uint64(result.Get("pc").Float()); got != want {
+			t.Fatalf("stepped to %#x, want %#x", got, want)
+		}
+	}
+	if got := call(vo.GetThread, id).Get("instructionsExecuted").Int(); got != 5 {
+		t.Errorf("instructionsExecuted = %d, want 5", got)
+	}
+
+	// A branch without a target faults rather than guessing at +4
+	requireError(t, call(vo.StepThread, id), errHalted)
+	if got := threadStatus(vo, id); got != "faulted" {
+		t.Errorf("thread is %q after a branch without a target, want faulted", got)
+	}
+	if got := threadPC(t, vo, id); got != 0x4000000c {
+		t.Errorf("pc = %#x after a branch without a target, want 0x4000000c", got)
+	}
+}
@@ -0,0 +1,184 @@
+// Software Breakpoints
+// Pauses threads when they reach a registered PC address
+//
+// The breakpoint set is shared by all threads and guarded by an RWMutex, so
+// many threads can test it concurrently. A thread that stops at a breakpoint
+// transitions to "paused"; the instruction at that address runs without
+// re-triggering when the thread is stepped or set back to "running".
+//
+// A breakpoint may carry a condition comparing one register against a
+// value; it then only pauses threads whose register satisfies it when they
+// reach the address. Registers compare as unsigned 32-bit values.
+
+package main
+
+import (
+	"sort"
+	"sync/atomic"
+	"syscall/js"
+)
+
+// breakpointCondition restricts a breakpoint to threads whose register
+// compares true against value
+type breakpointCondition struct {
+	register int
+	op       string // "==", "!=", "<", ">", "<=" or ">="
+	value    uint32
+}
+
+// SetBreakpoint adds a PC address to the breakpoint set
+// Arguments: address, optional condition { register, op, value }
+// Setting a breakpoint again replaces its condition. While regions are
+// mapped, an address ValidateAddress rejects for "execute" is refused.
+func (vo *VMOrchestrator) SetBreakpoint(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(false)
+	}
+
+	address, ok := jsToAddress(args[0])
+	if !ok {
+		return js.ValueOf(false)
+	}
+	if code, _ := vo.validateAddress(address, permExecute); code != "" {
+		return js.ValueOf(false) // could never be hit
+	}
+
+	var condition *breakpointCondition
+	if len(args) > 1 && !args[1].IsNull() && !args[1].IsUndefined() {
+		if condition, ok = vo.conditionFromJS(args[1]); !ok {
+			return js.ValueOf(false)
+		}
+	}
+
+	vo.breakpointMutex.Lock()
+	vo.breakpoints[address] = condition
+	vo.breakpointMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// conditionFromJS parses a { register, op, value } breakpoint condition
+func (vo *VMOrchestrator) conditionFromJS(v js.Value) (*breakpointCondition, bool) {
+	if v.Type() != js.TypeObject {
+		return nil, false
+	}
+
+	register, op, value := v.Get("register"), v.Get("op"), v.Get("value")
+	if register.Type() != js.TypeNumber || op.Type() != js.TypeString || value.Type() != js.TypeNumber {
+		return nil, false
+	}
+
+	condition := &breakpointCondition{
+		register: register.Int(),
+		op:       op.String(),
+		value:    uint32(value.Int()),
+	}
+	if condition.register < 0 || condition.register >= int(atomic.LoadInt32(&vo.registerCount)) {
+		return nil, false
+	}
+	switch condition.op {
+	case "==", "!=", "<", ">", "<=", ">=":
+	default:
+		return nil, false
+	}
+	return condition, true
+}
+
+// ClearBreakpoint removes a PC address from the breakpoint set
+// Returns false if no breakpoint was set at that address
+func (vo *VMOrchestrator) ClearBreakpoint(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(false)
+	}
+
+	address, ok := jsToAddress(args[0])
+	if !ok {
+		return js.ValueOf(false)
+	}
+
+	vo.breakpointMutex.Lock()
+	defer vo.breakpointMutex.Unlock()
+
+	if _, ok := vo.breakpoints[address]; !ok {
+		return js.ValueOf(false)
+	}
+	delete(vo.breakpoints, address)
+	return js.ValueOf(true)
+}
+
+// ListBreakpoints returns all breakpoint addresses in ascending order
+func (vo *VMOrchestrator) ListBreakpoints(this js.Value, args []js.Value) interface{} {
+	vo.breakpointMutex.RLock()
+	addresses := make([]uint64, 0, len(vo.breakpoints))
+	for address := range vo.breakpoints {
+		addresses = append(addresses, address)
+	}
+	vo.breakpointMutex.RUnlock()
+
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	return js.ValueOf(addressesToJS(addresses))
+}
+
+// OnBreakpoint registers a callback invoked as callback(threadID, address)
+// whenever a thread stops at a breakpoint. Passing null clears it.
+func (vo *VMOrchestrator) OnBreakpoint(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	vo.breakpointCallback = args[0]
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// breakpointHit reports whether a breakpoint at address pauses the thread,
+// evaluating its condition if it has one. Caller must hold thread.mutex.
+func (vo *VMOrchestrator) breakpointHit(thread *VMThread, address uint64) bool {
+	vo.breakpointMutex.RLock()
+	condition, ok := vo.breakpoints[address]
+	vo.breakpointMutex.RUnlock()
+
+	if !ok {
+		return false
+	}
+	return condition == nil || condition.matches(thread.registers)
+}
+
+// matches evaluates the condition against a register file
+func (condition *breakpointCondition) matches(registers []uint32) bool {
+	if condition.register >= len(registers) {
+		return false
+	}
+
+	actual := registers[condition.register]
+	switch condition.op {
+	case "==":
+		return actual == condition.value
+	case "!=":
+		return actual != condition.value
+	case "<":
+		return actual < condition.value
+	case ">":
+		return actual > condition.value
+	case "<=":
+		return actual <= condition.value
+	case ">=":
+		return actual >= condition.value
+	}
+	return false
+}
+
+// fireBreakpoint queues a call to the registered breakpoint callback
+func (vo *VMOrchestrator) fireBreakpoint(threadID int, address uint64) {
+	vo.postEvent(eventNormal, "", func() {
+		vo.callbackMutex.RLock()
+		callback := vo.breakpointCallback
+		vo.callbackMutex.RUnlock()
+
+		if callback.Type() == js.TypeFunction {
+			callback.Invoke(threadID, addressToJS(address))
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestBreakpointAbove32Bits(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+
+	const start, breakpoint = 0x100000000, 0x100000040
+	if !call(vo.SetBreakpoint, "0x100000040").Bool() {
+		t.Fatal("setBreakpoint failed")
+	}
+	hits := make(chan uint64, 1)
+	call(vo.OnBreakpoint, newCallback(t, func(args []js.Value) {
+		address, _ := jsToAddress(args[1])
+		hits <- address
+	}))
+
+	requireOK(t, call(vo.Start))
+	id := createThread(t, vo, start)
+	eventually(t, "the breakpoint to pause the thread", func() bool { return threadStatus(vo, id) == "paused" })
+
+	if got := threadPC(t, vo, id); got != breakpoint {
+		t.Fatalf("paused at %#x, want %#x", got, breakpoint)
+	}
+	var hit uint64
+	eventually(t, "the breakpoint callback", func() bool {
+		select {
+		case hit = <-hits:
+			return true
+		default:
+			return false
+		}
+	})
+	if hit != breakpoint {
+		t.Errorf("OnBreakpoint reported %#x, want %#x", hit, breakpoint)
+	}
+}
+
+func TestThreadPCAboveSafeIntegers(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	const pc = "18446744073709547520" // 0xfffffffffffff000
+
+	id := requireOK(t, call(vo.CreateThread, pc, 1, "paused")).Get("threadID").Int()
+	if got := call(vo.GetThread, id).Get("pc"); got.Type() != js.TypeString || got.String() != pc {
+		t.Fatalf("pc = %v, want the string %s", got, pc)
+	}
+
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.StepThread, id))
+	if got, want := threadPC(t, vo, id), uint64(0xfffffffffffff004); got != want {
+		t.Errorf("pc = %#x after one step, want %#x", got, want)
+	}
+}
+
+func TestConditionalBreakpointFiresOnFifthIteration(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	// A three-instruction loop far from the main thread: the body bumps r3
+	// and the last instruction branches back to the top
+	const top, body, branch = 0x40000000, 0x40000004, 0x40000008
+	var id int
+	var iterations uint32
+	call(vo.Initialize, newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func(args []js.Value) interface{} {
+			switch args[0].Float() {
+			case body:
+				iterations++
+				call(vo.SetRegister, id, 3, iterations)
+			case branch:
+				return map[string]interface{}{"status": "branch", "pc": top}
+			}
+			return true
+		},
+	}))
+	if !call(vo.SetBreakpoint, body, map[string]interface{}{"register": 3, "op": "==", "value": 4}).Bool() {
+		t.Fatal("setBreakpoint with a condition failed")
+	}
+
+	requireOK(t, call(vo.Start))
+	id = createThread(t, vo, top, 1, "running", "loop", map[string]interface{}{"autostart": false})
+	requireOK(t, call(vo.StartThread, id))
+	eventually(t, "the breakpoint to pause the loop", func() bool { return threadStatus(vo, id) == "paused" })
+
+	if got := threadPC(t, vo, id); got != body {
+		t.Fatalf("paused at %#x, want %#x", got, body)
+	}
+	if iterations != 4 {
+		t.Errorf("paused after the body ran %d times, want 4 (the 5th iteration)", iterations)
+	}
+	if got := call(vo.GetRegisters, id).Index(3).Int(); got != 4 {
+		t.Errorf("r3 = %d, want 4", got)
+	}
+}
+
+func TestSetBreakpointRejectsBadConditions(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	for _, condition := range []map[string]interface{}{
+		{"register": 3, "op": "=~", "value": 1},
+		{"register": 99, "op": "==", "value": 1},
+		{"op": "==", "value": 1},
+	} {
+		if call(vo.SetBreakpoint, 0x1000, condition).Bool() {
+			t.Errorf("setBreakpoint accepted condition %v", condition)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Per-Thread Bridges
+// Lets threads run on different emulator instances
+//
+// Initialize attaches the orchestrator's default bridge. SetThreadEmulator
+// overrides it for one thread, e.g. to back big and little cores with
+// differently configured emulators. Instruction execution and batching use
+// the thread's bridge; memory operations that are not tied to a thread
+// (compareAndSwap) always use the default bridge.
+
+package main
+
+import "syscall/js"
+
+// SetThreadEmulator assigns an emulator bridge to a thread. Passing null
+// returns the thread to the orchestrator's default bridge.
+func (vo *VMOrchestrator) SetThreadEmulator(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(false, errInvalidArgument, "setThreadEmulator requires a thread ID and an emulator")
+	}
+
+	emulator := args[1]
+	if !emulator.IsNull() && (!emulator.Truthy() || emulator.Get("executeInstruction").Type() != js.TypeFunction) {
+		return vo.fail(false, errInvalidArgument, "the emulator does not implement executeInstruction")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.Lock()
+	if emulator.IsNull() {
+		thread.emulator = js.Undefined()
+	} else {
+		thread.emulator = emulator
+	}
+	thread.mutex.Unlock()
+
+	return vo.succeed(true, nil)
+}
+
+// threadEmulator returns the bridge a thread executes on
+func (vo *VMOrchestrator) threadEmulator(thread *VMThread) js.Value {
+	thread.mutex.RLock()
+	emulator := thread.emulator
+	thread.mutex.RUnlock()
+
+	if emulator.Truthy() {
+		return emulator
+	}
+	return vo.emulator()
+}
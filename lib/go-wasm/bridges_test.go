@@ -0,0 +1,107 @@
+package main
+
+import (
+	"sync"
+	"syscall/js"
+	"testing"
+)
+
+// pcRecorder is a bridge that records every PC it executes
+type pcRecorder struct {
+	mutex sync.Mutex
+	pcs   []uint64
+}
+
+func (r *pcRecorder) bridge(t *testing.T) js.Value {
+	return newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func(args []js.Value) interface{} {
+			r.mutex.Lock()
+			r.pcs = append(r.pcs, uint64(args[0].Float()))
+			r.mutex.Unlock()
+			return true
+		},
+	})
+}
+
+// seen returns how many PCs the bridge executed and how many fell outside
+// [low, high)
+func (r *pcRecorder) seen(low, high uint64) (total, outside int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, pc := range r.pcs {
+		if pc < low || pc >= high {
+			outside++
+		}
+	}
+	return len(r.pcs), outside
+}
+
+func TestThreadsOnSeparateBridges(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	var main, big, little pcRecorder
+	call(vo.Initialize, main.bridge(t))
+	call(vo.SetYieldStrategy, "gosched")
+	requireOK(t, call(vo.Start))
+
+	noStart := map[string]interface{}{"autostart": false}
+	a := createThread(t, vo, 0x40000000, 1, "running", "big", noStart)
+	b := createThread(t, vo, 0x80000000, 1, "running", "little", noStart)
+	requireOK(t, call(vo.SetThreadEmulator, a, big.bridge(t)))
+	requireOK(t, call(vo.SetThreadEmulator, b, little.bridge(t)))
+	requireOK(t, call(vo.StartThread, a))
+	requireOK(t, call(vo.StartThread, b))
+
+	eventually(t, "every bridge to run", func() bool {
+		n1, _ := main.seen(0, 0x40000000)
+		n2, _ := big.seen(0x40000000, 0x80000000)
+		n3, _ := little.seen(0x80000000, 0xc0000000)
+		return n1 > 100 && n2 > 100 && n3 > 100
+	})
+	requireOK(t, call(vo.Pause))
+
+	for _, check := range []struct {
+		name      string
+		recorder  *pcRecorder
+		low, high uint64
+	}{
+		{"default", &main, 0x1000, 0x40000000},
+		{"big", &big, 0x40000000, 0x80000000},
+		{"little", &little, 0x80000000, 0xc0000000},
+	} {
+		if total, outside := check.recorder.seen(check.low, check.high); outside > 0 {
+			t.Errorf("%s bridge executed %d of %d PCs belonging to other threads", check.name, outside, total)
+		}
+	}
+
+	// Each thread's PC reflects only its own bridge's instructions
+	for id, recorder := range map[int]*pcRecorder{a: &big, b: &little} {
+		eventually(t, "in-flight instructions to finish", func() bool {
+			recorder.mutex.Lock()
+			last := recorder.pcs[len(recorder.pcs)-1]
+			recorder.mutex.Unlock()
+			return threadPC(t, vo, id) == last+4
+		})
+	}
+}
+
+func TestSetThreadEmulatorValidates(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	var main, other pcRecorder
+	call(vo.Initialize, main.bridge(t))
+	id := createThread(t, vo, 0x40000000, 1, "paused")
+
+	requireError(t, call(vo.SetThreadEmulator, id, js.Global().Get("Object").New()), errInvalidArgument)
+	requireError(t, call(vo.SetThreadEmulator, id, 0), errInvalidArgument)
+	requireError(t, call(vo.SetThreadEmulator, 99, other.bridge(t)), errUnknownThread)
+
+	// Null falls back to the default bridge
+	requireOK(t, call(vo.SetThreadEmulator, id, other.bridge(t)))
+	requireOK(t, call(vo.SetThreadEmulator, id, js.Null()))
+	requireOK(t, call(vo.StepThread, id))
+	if n, _ := main.seen(0, 0); n != 1 {
+		t.Errorf("default bridge executed %d instructions after the override was cleared, want 1", n)
+	}
+	if n, _ := other.seen(0, 0); n != 0 {
+		t.Errorf("cleared bridge executed %d instructions", n)
+	}
+}
@@ -0,0 +1,79 @@
+// Instruction Statuses
+// Typed executeInstruction results
+//
+// Besides a boolean, executeInstruction may return an object whose status
+// says what the instruction did, so the orchestrator can react to it:
+//
+//	"ok"      the instruction completed and the PC advances past it
+//	"halt"    the thread terminates with exit reason "halted"
+//	"fault"   the thread faults with faultReason as its message
+//	"syscall" syscall is dispatched with the optional args as
+//	          dispatchSyscall would (see syscalls.go), then the PC advances
+//	"branch"  the PC moves to pc, which must be reported
+//
+// A plain boolean, or an object with { ok } and no status, still means
+// "ok" when true and "halt" when false. A pc reported with any status is
+// still followed (see branchTarget). Batched executeInstructions results
+// may report the status of the last instruction they ran (see batch.go).
+
+package main
+
+import (
+	"fmt"
+	"syscall/js"
+)
+
+// Instruction statuses
+const (
+	statusOK      = "ok"
+	statusHalt    = "halt"
+	statusFault   = "fault"
+	statusSyscall = "syscall"
+	statusBranch  = "branch"
+)
+
+// okStatus maps a legacy boolean result to its status
+func okStatus(ok bool) string {
+	if ok {
+		return statusOK
+	}
+	return statusHalt
+}
+
+// checkStatus acts on the status of the executeInstruction result for the
+// instruction at pc. Returns false if the thread halted or faulted.
+func (vo *VMOrchestrator) checkStatus(thread *VMThread, result js.Value, status string, pc uint64) bool {
+	switch status {
+	case statusOK, statusSyscall:
+		return true
+	case statusBranch:
+		if _, ok := branchTarget(result); ok {
+			return true
+		}
+		vo.faultThread(thread, fmt.Sprintf("branch at %#x reported no target pc", pc))
+	case statusHalt:
+		vo.terminateThread(thread, "halted")
+	case statusFault:
+		message := fmt.Sprintf("emulator fault at %#x", pc)
+		if reason := result.Get("faultReason"); reason.Type() == js.TypeString && reason.String() != "" {
+			message = reason.String()
+		}
+		vo.faultThread(thread, message)
+	default:
+		vo.faultThread(thread, fmt.Sprintf("unknown executeInstruction status %q at %#x", status, pc))
+	}
+	return false
+}
+
+// instructionSyscall dispatches the syscall a "syscall" result reports for
+// the instruction at pc. Returns false if the thread faulted.
+func (vo *VMOrchestrator) instructionSyscall(thread *VMThread, result js.Value, pc uint64) bool {
+	number := result.Get("syscall")
+	if number.Type() != js.TypeNumber {
+		vo.faultThread(thread, fmt.Sprintf("syscall at %#x reported no syscall number", pc))
+		return false
+	}
+
+	_, _, err := vo.dispatchSyscall(thread, number.Int(), result.Get("args"))
+	return err == nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestInstructionStatuses(t *testing.T) {
+	tests := []struct {
+		name   string
+		result interface{}
+		status string // thread status after the step
+		pc     uint64 // PC after the step
+		reason string // exit reason or fault message
+	}{
+		{"legacy true", true, "paused", 0x1004, ""},
+		{"legacy false", false, "terminated", 0x1000, "halted"},
+		{"ok", map[string]interface{}{"status": statusOK}, "paused", 0x1004, ""},
+		{"halt", map[string]interface{}{"status": statusHalt}, "terminated", 0x1000, "halted"},
+		{"fault", map[string]interface{}{"status": statusFault, "faultReason": "undefined instruction"}, "faulted", 0x1000, "undefined instruction"},
+		{"fault without reason", map[string]interface{}{"status": statusFault}, "faulted", 0x1000, "emulator fault at 0x1000"},
+		{"syscall", map[string]interface{}{"status": statusSyscall, "syscall": sysAdd, "args": []interface{}{0, 1, 2}}, "paused", 0x1004, ""},
+		{"branch", map[string]interface{}{"status": statusBranch, "pc": 0x8000}, "paused", 0x8000, ""},
+		{"unknown", map[string]interface{}{"status": "jump"}, "faulted", 0x1000, `unknown executeInstruction status "jump" at 0x1000`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vo := newTestOrchestrator(t)
+			call(vo.Initialize, newBridge(t, map[string]func([]js.Value) interface{}{
+				"executeInstruction": func([]js.Value) interface{} { return test.result },
+			}))
+			call(vo.RegisterSyscall, sysAdd, jsFunction("args", "threadID", "return args[1] + args[2];"))
+			id := createThread(t, vo, 0x1000, 1, "paused")
+
+			result := call(vo.StepThread, id)
+			if continues := test.status == "paused"; result.Get("ok").Bool() != continues {
+				t.Fatalf("stepThread ok = %v, want %v", result.Get("ok").Bool(), continues)
+			}
+
+			thread := call(vo.GetThread, id)
+			if got := thread.Get("status").String(); got != test.status {
+				t.Errorf("status = %q, want %q", got, test.status)
+			}
+			if got := uint64(thread.Get("pc").Float()); got != test.pc {
+				t.Errorf("pc = %#x, want %#x", got, test.pc)
+			}
+			switch test.status {
+			case "terminated":
+				if got := thread.Get("exitReason").String(); got != test.reason {
+					t.Errorf("exit reason = %q, want %q", got, test.reason)
+				}
+			case "faulted":
+				if got := call(vo.GetFaultLog).Index(0).Get("message").String(); got != test.reason {
+					t.Errorf("fault message = %q, want %q", got, test.reason)
+				}
+			}
+			if test.name == "syscall" {
+				if got := call(vo.GetRegisters, id).Index(syscallResultRegister).Int(); got != 3 {
+					t.Errorf("syscall result register = %d, want 3", got)
+				}
+			}
+		})
+	}
+}
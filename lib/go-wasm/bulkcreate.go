@@ -0,0 +1,112 @@
+// Bulk Thread Creation
+// Creates many threads in one bridge crossing
+//
+// CreateThreads takes an array of specs, { startPC, name, priority, mode,
+// autostart, stackCapacity } with everything but startPC optional and
+// defaulting as in CreateThread. Every valid spec gets a thread, in order,
+// so IDs are sequential across the valid entries; the threads are
+// published and scheduled together. An invalid spec is reported in its
+// position and does not stop the others, as is a start PC the memory map
+// rejects (see ValidateAddress).
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// CreateThreads creates a thread per spec. Returns { threadIDs, errors }:
+// threadIDs[i] is the new thread's ID or -1, and errors[i] is null or the
+// { error, message } explaining why spec i was rejected. In legacy mode
+// only the threadIDs array is returned.
+func (vo *VMOrchestrator) CreateThreads(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !args[0].InstanceOf(js.Global().Get("Array")) {
+		return vo.fail(nil, errInvalidArgument, "createThreads requires an array of thread specs")
+	}
+	if !vo.creationAllowed() {
+		return vo.fail(nil, errNotRunning, "threads cannot be created while the VM is stopped")
+	}
+
+	count := args[0].Length()
+	ids := make([]interface{}, count)
+	errors := make([]interface{}, count)
+	threads := make([]*VMThread, 0, count)
+
+	specs := make([]threadSpec, count)
+	valid := 0
+	for i := range specs {
+		spec, message := threadSpecFromJS(args[0].Index(i))
+		if message != "" {
+			ids[i] = -1
+			errors[i] = map[string]interface{}{"error": errInvalidArgument, "message": message}
+			continue
+		}
+		if code, message := vo.validateAddress(spec.startPC, permExecute); code != "" {
+			ids[i] = -1
+			errors[i] = map[string]interface{}{"error": code, "message": "invalid start PC: " + message}
+			continue
+		}
+		specs[i] = spec
+		valid++
+	}
+
+	// Valid specs beyond the thread limit are refused in order
+	admitted := vo.admitThreads(valid)
+	for i, spec := range specs {
+		if errors[i] != nil {
+			continue
+		}
+		if admitted == 0 {
+			ids[i] = -1
+			errors[i] = map[string]interface{}{"error": errThreadLimit, "message": "the thread limit has been reached"}
+			continue
+		}
+		admitted--
+
+		thread := vo.newThread(spec)
+		threads = append(threads, thread)
+		ids[i] = thread.id
+	}
+	vo.addThreads(threads...)
+
+	return vo.succeed(ids, map[string]interface{}{
+		"threadIDs": ids,
+		"errors":    errors,
+	})
+}
+
+// threadSpecFromJS reads one CreateThreads spec. Returns an error message,
+// or "" if the spec is valid.
+func threadSpecFromJS(v js.Value) (threadSpec, string) {
+	spec := defaultThreadSpec()
+	if v.Type() != js.TypeObject {
+		return spec, "thread spec must be an object"
+	}
+
+	var ok bool
+	if spec.startPC, ok = jsToAddress(v.Get("startPC")); !ok {
+		return spec, "invalid start PC"
+	}
+	if priority := v.Get("priority"); !priority.IsUndefined() {
+		if priority.Type() != js.TypeNumber {
+			return spec, "priority must be a number"
+		}
+		spec.priority = priority.Int()
+	}
+	if mode := v.Get("mode"); !mode.IsUndefined() {
+		if mode.Type() != js.TypeString {
+			return spec, "mode must be a string"
+		}
+		spec.status = mode.String()
+	}
+	if name := v.Get("name"); !name.IsUndefined() {
+		if name.Type() != js.TypeString {
+			return spec, "name must be a string"
+		}
+		spec.name = name.String()
+	}
+	if message := spec.applyOptions(v); message != "" {
+		return spec, message
+	}
+	return spec, spec.validate()
+}
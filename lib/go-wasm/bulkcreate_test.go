@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCreateHundredThreads(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	specs := make([]interface{}, 100)
+	for i := range specs {
+		specs[i] = map[string]interface{}{
+			"startPC":  0x40000000 + 0x1000*i,
+			"name":     fmt.Sprintf("worker-%d", i),
+			"priority": 1 + i%3,
+			"mode":     "paused",
+		}
+	}
+
+	result := requireOK(t, call(vo.CreateThreads, specs))
+	ids := jsToInts(result.Get("threadIDs"))
+	if len(ids) != len(specs) {
+		t.Fatalf("got %d IDs for %d specs", len(ids), len(specs))
+	}
+	for i, id := range ids {
+		if id != i+1 {
+			t.Fatalf("thread IDs %v are not sequential from 1", ids)
+		}
+		if !result.Get("errors").Index(i).IsNull() {
+			t.Errorf("spec %d failed: %v", i, result.Get("errors").Index(i).Get("message"))
+		}
+		info := call(vo.GetThread, id)
+		if name := info.Get("name").String(); name != fmt.Sprintf("worker-%d", i) {
+			t.Errorf("thread %d is named %q, want worker-%d", id, name, i)
+		}
+		if pc := uint64(info.Get("pc").Float()); pc != uint64(0x40000000+0x1000*i) {
+			t.Errorf("thread %d starts at %#x", id, pc)
+		}
+	}
+	if got := stat(vo, "threadsCreated"); got != 100 {
+		t.Errorf("threadsCreated = %v, want 100", got)
+	}
+}
+
+func TestCreateThreadsReportsBadSpecs(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	specs := []interface{}{
+		map[string]interface{}{"startPC": 0x1000, "mode": "paused"},
+		map[string]interface{}{"startPC": "nowhere"},
+		map[string]interface{}{"startPC": 0x2000, "autostart": false},
+		"not a spec",
+		map[string]interface{}{"startPC": 0x3000, "priority": 0},
+		map[string]interface{}{"startPC": 0x4000, "mode": "paused"},
+	}
+
+	result := requireOK(t, call(vo.CreateThreads, specs))
+	if got, want := jsToInts(result.Get("threadIDs")), []int{1, -1, 2, -1, -1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("threadIDs = %v, want %v", got, want)
+	}
+	for i, failed := range []bool{false, true, false, true, true, false} {
+		entry := result.Get("errors").Index(i)
+		if entry.IsNull() == failed {
+			t.Errorf("spec %d: error = %v, want failure %v", i, entry, failed)
+		} else if failed && entry.Get("error").String() != errInvalidArgument {
+			t.Errorf("spec %d failed with %v, want %s", i, entry.Get("error"), errInvalidArgument)
+		}
+	}
+	if got := threadStatus(vo, 2); got != "ready" {
+		t.Errorf("autostart: false thread is %q, want ready", got)
+	}
+}
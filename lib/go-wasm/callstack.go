@@ -0,0 +1,96 @@
+// Call Stacks
+// Reconstructs a backtrace from a thread's stack
+//
+// Return frames use the layout interrupt delivery pushes: two words per
+// frame, high half of the return PC first. GetCallStack walks the stack
+// from the top, one frame per pair, after frame 0 for the current (or
+// faulting) PC. A stack that does not hold valid frames throughout stops
+// the walk early and marks the result truncated: an odd word left over, or
+// a return PC outside the address space or, while memory regions are
+// mapped, outside executable memory.
+//
+// OnSymbolize registers a host function that names a PC, e.g. from an ELF
+// symbol table; frames it cannot name have a null symbol. A symbolizer that
+// throws fails GetCallStack with bridge_exception.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// OnSymbolize registers a symbolizer invoked as callback(pc), which returns
+// a symbol name or null. Passing null clears it.
+func (vo *VMOrchestrator) OnSymbolize(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	vo.symbolizer = args[0]
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// GetCallStack returns a thread's backtrace as { frames, truncated }, where
+// frames[0] is the current PC and each frame is { pc, symbol }
+func (vo *VMOrchestrator) GetCallStack(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(nil, errInvalidArgument, "getCallStack requires a thread ID")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(nil, threadID)
+	}
+
+	thread.mutex.RLock()
+	pcs := []uint64{thread.pc}
+	stack := append([]uint32(nil), thread.stack...)
+	thread.mutex.RUnlock()
+
+	truncated := len(stack)%2 != 0
+	for top := len(stack); top >= 2; top -= 2 {
+		pc := uint64(stack[top-2])<<32 | uint64(stack[top-1])
+		if !vo.validReturnPC(pc) {
+			truncated = true
+			break
+		}
+		pcs = append(pcs, pc)
+	}
+
+	// The symbolizer runs without locks so it may call back into the VM
+	vo.callbackMutex.RLock()
+	symbolizer := vo.symbolizer
+	vo.callbackMutex.RUnlock()
+
+	frames := make([]interface{}, len(pcs))
+	for i, pc := range pcs {
+		var symbol interface{}
+		if symbolizer.Type() == js.TypeFunction {
+			name, err := callJS(func() js.Value { return symbolizer.Invoke(addressToJS(pc)) })
+			if err != nil {
+				return vo.fail(nil, errBridgeException, "symbolizer failed at %#x: %v", pc, err)
+			}
+			if name.Type() == js.TypeString {
+				symbol = name.String()
+			}
+		}
+		frames[i] = map[string]interface{}{"pc": addressToJS(pc), "symbol": symbol}
+	}
+
+	return vo.succeed(frames, map[string]interface{}{
+		"frames":    frames,
+		"truncated": truncated,
+	})
+}
+
+// validReturnPC reports whether a stacked value can be a return address
+func (vo *VMOrchestrator) validReturnPC(pc uint64) bool {
+	if space := vo.addressSpace.Load(); space != nil && space.size != 0 && pc >= space.size {
+		return false
+	}
+	return vo.accessAllowed(pc, permExecute)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"reflect"
+	"syscall/js"
+	"testing"
+)
+
+// pushFrames pushes return frames, outermost first, in the interrupt layout
+func pushFrames(t *testing.T, vo *VMOrchestrator, threadID int, pcs ...uint64) {
+	t.Helper()
+	for _, pc := range pcs {
+		requireOK(t, call(vo.PushStack, threadID, uint32(pc>>32)))
+		requireOK(t, call(vo.PushStack, threadID, uint32(pc)))
+	}
+}
+
+// backtrace returns a call stack's frame PCs and symbols and whether it was
+// truncated
+func backtrace(t *testing.T, vo *VMOrchestrator, threadID int) (pcs []uint64, symbols []interface{}, truncated bool) {
+	t.Helper()
+	result := requireOK(t, call(vo.GetCallStack, threadID))
+	frames := result.Get("frames")
+	for i := 0; i < frames.Length(); i++ {
+		frame := frames.Index(i)
+		pcs = append(pcs, uint64(frame.Get("pc").Float()))
+		if symbol := frame.Get("symbol"); symbol.IsNull() {
+			symbols = append(symbols, nil)
+		} else {
+			symbols = append(symbols, symbol.String())
+		}
+	}
+	return pcs, symbols, result.Get("truncated").Bool()
+}
+
+func TestCallStackFromKnownFrames(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	id := createThread(t, vo, 0x40000000, 1, "paused")
+	pushFrames(t, vo, id, 0x1100, 0x1200, 0x100001300)
+
+	call(vo.OnSymbolize, newJSFunc(t, func(args []js.Value) interface{} {
+		if args[0].Float() == 0x1200 {
+			return "parse"
+		}
+		return nil
+	}))
+
+	pcs, symbols, truncated := backtrace(t, vo, id)
+	if want := []uint64{0x40000000, 0x100001300, 0x1200, 0x1100}; !reflect.DeepEqual(pcs, want) {
+		t.Errorf("frames = %#x, want %#x", pcs, want)
+	}
+	if want := []interface{}{nil, nil, "parse", nil}; !reflect.DeepEqual(symbols, want) {
+		t.Errorf("symbols = %v, want %v", symbols, want)
+	}
+	if truncated {
+		t.Error("a well-formed stack was reported truncated")
+	}
+}
+
+func TestCallStackOfFaultedThread(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	id := createThread(t, vo, 0x40000000, 1, "paused")
+	pushFrames(t, vo, id, 0x1100)
+	call(vo.SetMaxStackDepth, 2)
+	requireError(t, call(vo.PushStack, id, 0), errStackOverflow)
+
+	if pcs, _, _ := backtrace(t, vo, id); !reflect.DeepEqual(pcs, []uint64{0x40000000, 0x1100}) {
+		t.Errorf("faulted thread frames = %#x, want the faulting PC then 0x1100", pcs)
+	}
+}
+
+func TestCallStackStopsAtCorruptFrames(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.SetAddressSpaceSize, 0x100000)
+	id := createThread(t, vo, 0x8000, 1, "paused")
+	pushFrames(t, vo, id, 0x1100, 0xdeadbeef, 0x1300)
+
+	pcs, _, truncated := backtrace(t, vo, id)
+	if !reflect.DeepEqual(pcs, []uint64{0x8000, 0x1300}) || !truncated {
+		t.Errorf("frames = %#x, truncated %v; want [0x8000 0x1300], truncated", pcs, truncated)
+	}
+
+	// A leftover odd word is not a frame
+	odd := createThread(t, vo, 0x8000, 1, "paused")
+	requireOK(t, call(vo.PushStack, odd, 7))
+	pushFrames(t, vo, odd, 0x1100)
+	pcs, _, truncated = backtrace(t, vo, odd)
+	if !reflect.DeepEqual(pcs, []uint64{0x8000, 0x1100}) || !truncated {
+		t.Errorf("frames = %#x, truncated %v; want [0x8000 0x1100], truncated", pcs, truncated)
+	}
+}
+
+func TestCallStackSymbolizerThrows(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	id := createThread(t, vo, 0x40000000, 1, "paused")
+	call(vo.OnSymbolize, jsFunction("pc", `throw new Error("no symbols")`))
+	requireError(t, call(vo.GetCallStack, id), errBridgeException)
+	requireError(t, call(vo.GetCallStack, 99), errUnknownThread)
+}
@@ -0,0 +1,149 @@
+// Guest Condition Variables
+// Wait/notify on top of the guest mutex registry
+//
+// CondWait releases a mutex and parks the thread in "waiting" in one step,
+// so a signal sent after the release cannot be missed. Parked threads only
+// wake through CondSignal or CondBroadcast, never spuriously. A woken
+// thread re-acquires its mutex before running again: if the mutex is held
+// it joins the mutex's wait queue and stays "waiting" until handed the lock.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// guestCondVar is a queue of threads parked on a condition
+type guestCondVar struct {
+	waiters []condWaiter // oldest first
+}
+
+// condWaiter is a parked thread and the mutex it must re-acquire
+type condWaiter struct {
+	threadID int
+	mutexID  int
+}
+
+// CreateCondVar registers a new condition variable and returns its ID
+func (vo *VMOrchestrator) CreateCondVar(this js.Value, args []js.Value) interface{} {
+	vo.guestSyncMutex.Lock()
+	defer vo.guestSyncMutex.Unlock()
+
+	vo.guestCondCounter++
+	vo.guestCondVars[vo.guestCondCounter] = &guestCondVar{}
+	return js.ValueOf(vo.guestCondCounter)
+}
+
+// CondWait releases a mutex the thread owns and parks the thread on a
+// condition variable. Like LockMutex, only a running or paused thread can
+// wait.
+// Arguments: threadID, condID, mutexID
+func (vo *VMOrchestrator) CondWait(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return vo.fail(false, errInvalidArgument, "condWait requires a thread ID, condition ID and mutex ID")
+	}
+
+	threadID, condID, mutexID := args[0].Int(), args[1].Int(), args[2].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	vo.guestSyncMutex.Lock()
+	cond := vo.guestCondVars[condID]
+	if cond == nil {
+		vo.guestSyncMutex.Unlock()
+		return vo.fail(false, errUnknownCondVar, "condition variable %d does not exist", condID)
+	}
+	mutex := vo.guestMutexes[mutexID]
+	if mutex == nil {
+		vo.guestSyncMutex.Unlock()
+		return vo.fail(false, errUnknownMutex, "mutex %d does not exist", mutexID)
+	}
+	if mutex.owner != threadID {
+		vo.guestSyncMutex.Unlock()
+		return vo.fail(false, errNotOwner, "thread %d does not own mutex %d", threadID, mutexID)
+	}
+
+	thread.mutex.Lock()
+	if status := thread.status; status != "running" && status != "paused" && !thread.yielding {
+		thread.mutex.Unlock()
+		vo.guestSyncMutex.Unlock()
+		return vo.fail(false, errInvalidState, "thread %d is %s", threadID, status)
+	}
+	vo.beginWait(thread)
+	thread.yielding = false
+	thread.waitingOn = nil
+	thread.mutex.Unlock()
+	cond.waiters = append(cond.waiters, condWaiter{threadID: threadID, mutexID: mutexID})
+
+	next := vo.handOff(mutex)
+	vo.inheritPriorities()
+	vo.guestSyncMutex.Unlock()
+
+	if next != nil {
+		vo.enqueueThread(next)
+	}
+	return vo.succeed(true, nil)
+}
+
+// CondSignal wakes the oldest thread parked on a condition variable.
+// Returns the number of threads woken (0 or 1).
+func (vo *VMOrchestrator) CondSignal(this js.Value, args []js.Value) interface{} {
+	return vo.notify(args, 1)
+}
+
+// CondBroadcast wakes every thread parked on a condition variable.
+// Returns the number of threads woken.
+func (vo *VMOrchestrator) CondBroadcast(this js.Value, args []js.Value) interface{} {
+	return vo.notify(args, -1)
+}
+
+// notify wakes up to limit waiters of the condition variable in args[0]
+// (-1 = all), handing each its mutex or queueing it on the mutex
+func (vo *VMOrchestrator) notify(args []js.Value, limit int) interface{} {
+	if len(args) < 1 {
+		return vo.fail(-1, errInvalidArgument, "a condition variable ID is required")
+	}
+
+	condID := args[0].Int()
+
+	vo.guestSyncMutex.Lock()
+	cond := vo.guestCondVars[condID]
+	if cond == nil {
+		vo.guestSyncMutex.Unlock()
+		return vo.fail(-1, errUnknownCondVar, "condition variable %d does not exist", condID)
+	}
+
+	var runnable []*VMThread
+	woken := 0
+	for len(cond.waiters) > 0 && (limit < 0 || woken < limit) {
+		waiter := cond.waiters[0]
+		cond.waiters = cond.waiters[1:]
+
+		thread := vo.getThread(waiter.threadID)
+		if thread == nil {
+			continue // terminated while parked
+		}
+
+		thread.mutex.Lock()
+		if thread.status != "waiting" {
+			thread.mutex.Unlock()
+			continue
+		}
+		woken++
+		if mutex := vo.guestMutexes[waiter.mutexID]; mutex == nil || vo.acquireOrQueue(mutex, thread) {
+			vo.resumeFromWait(thread)
+			thread.waitingOn = nil
+			runnable = append(runnable, thread)
+		}
+		thread.mutex.Unlock()
+	}
+	vo.inheritPriorities()
+	vo.guestSyncMutex.Unlock()
+
+	for _, thread := range runnable {
+		vo.enqueueThread(thread)
+	}
+	return vo.succeed(woken, map[string]interface{}{"woken": woken})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+)
+
+// parkOnCond locks mutex for each thread and parks it on cond
+func parkOnCond(t *testing.T, vo *VMOrchestrator, cond, mutex int, ids ...int) {
+	t.Helper()
+	for _, id := range ids {
+		if !requireOK(t, call(vo.LockMutex, id, mutex)).Get("acquired").Bool() {
+			t.Fatalf("thread %d could not take the free mutex", id)
+		}
+		requireOK(t, call(vo.CondWait, id, cond, mutex))
+		if got := threadStatus(vo, id); got != "waiting" {
+			t.Fatalf("parked thread %d is %q, want waiting", id, got)
+		}
+	}
+}
+
+func TestCondSignalWakesOneWaiter(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	ids := pausedThreads(t, vo, 3)
+	mutex, cond := call(vo.CreateMutex).Int(), call(vo.CreateCondVar).Int()
+	parkOnCond(t, vo, cond, mutex, ids...)
+
+	// Nothing wakes parked threads but a notification
+	if got := threadStatus(vo, ids[0]); got != "waiting" {
+		t.Fatalf("thread %d woke spuriously: %q", ids[0], got)
+	}
+
+	if woken := requireOK(t, call(vo.CondSignal, cond)).Get("woken").Int(); woken != 1 {
+		t.Fatalf("condSignal woke %d threads, want 1", woken)
+	}
+	// It waited while single-stepping, so it comes back paused
+	if got := threadStatus(vo, ids[0]); got != "paused" {
+		t.Errorf("signalled thread is %q, want paused", got)
+	}
+	for _, id := range ids[1:] {
+		if got := threadStatus(vo, id); got != "waiting" {
+			t.Errorf("unsignalled thread %d is %q, want waiting", id, got)
+		}
+	}
+	// The woken thread re-acquired the mutex
+	requireOK(t, call(vo.UnlockMutex, ids[0], mutex))
+}
+
+func TestCondBroadcastWakesAllWaiters(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	ids := pausedThreads(t, vo, 3)
+	mutex, cond := call(vo.CreateMutex).Int(), call(vo.CreateCondVar).Int()
+	parkOnCond(t, vo, cond, mutex, ids...)
+
+	if woken := requireOK(t, call(vo.CondBroadcast, cond)).Get("woken").Int(); woken != 3 {
+		t.Fatalf("condBroadcast woke %d threads, want 3", woken)
+	}
+	if woken := requireOK(t, call(vo.CondSignal, cond)).Get("woken").Int(); woken != 0 {
+		t.Errorf("condSignal after a broadcast woke %d threads, want 0", woken)
+	}
+
+	// Each woken thread returns to its paused mode once it gets the mutex
+	// back, in wait order
+	for i, id := range ids {
+		if got := threadStatus(vo, id); got != "paused" {
+			t.Fatalf("thread %d is %q on its turn, want paused", id, got)
+		}
+		for _, queued := range ids[i+1:] {
+			if got := threadStatus(vo, queued); got != "waiting" {
+				t.Fatalf("thread %d is %q before re-acquiring the mutex", queued, got)
+			}
+		}
+		requireOK(t, call(vo.UnlockMutex, id, mutex))
+	}
+}
+
+func TestCondWaitRequiresTheMutex(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	ids := pausedThreads(t, vo, 1)
+	mutex, cond := call(vo.CreateMutex).Int(), call(vo.CreateCondVar).Int()
+
+	requireError(t, call(vo.CondWait, ids[0], cond, mutex), errNotOwner)
+	requireError(t, call(vo.CondWait, ids[0], 99, mutex), errUnknownCondVar)
+}
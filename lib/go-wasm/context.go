@@ -0,0 +1,86 @@
+// Thread Contexts
+// Named register checkpoints for setjmp/longjmp-style control flow
+//
+// SaveContext records a thread's PC, register file and stack depth under a
+// label; RestoreContext puts them back, dropping any stack entries pushed
+// since. Contexts belong to their thread (each thread has its own label
+// namespace), are kept until overwritten or the thread is gone, and are
+// not part of snapshots or exported state. Like register writes, a restore
+// on a running thread races with its next instruction, so suspend the
+// thread first for deterministic results.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// threadContext is a thread state saved by SaveContext
+type threadContext struct {
+	pc         uint64
+	registers  []uint32
+	stackDepth int
+}
+
+// SaveContext saves a thread's PC, registers and stack depth under a label,
+// replacing any context already saved under it
+// Arguments: threadID, label
+func (vo *VMOrchestrator) SaveContext(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[1].Type() != js.TypeString {
+		return vo.fail(false, errInvalidArgument, "saveContext requires a thread ID and a label")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.Lock()
+	if thread.contexts == nil {
+		thread.contexts = make(map[string]threadContext)
+	}
+	thread.contexts[args[1].String()] = threadContext{
+		pc:         thread.pc,
+		registers:  append([]uint32(nil), thread.registers...),
+		stackDepth: len(thread.stack),
+	}
+	thread.mutex.Unlock()
+
+	return vo.succeed(true, nil)
+}
+
+// RestoreContext rolls a thread back to a context saved by SaveContext.
+// Fails if no context has the label or the stack is shallower than when
+// the context was saved, since the entries it depended on are gone.
+// Arguments: threadID, label
+func (vo *VMOrchestrator) RestoreContext(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[1].Type() != js.TypeString {
+		return vo.fail(false, errInvalidArgument, "restoreContext requires a thread ID and a label")
+	}
+
+	threadID, label := args[0].Int(), args[1].String()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.Lock()
+	defer thread.mutex.Unlock()
+
+	context, ok := thread.contexts[label]
+	if !ok {
+		return vo.fail(false, errUnknownContext, "thread %d has no context %q", threadID, label)
+	}
+	if len(thread.stack) < context.stackDepth {
+		return vo.fail(false, errInvalidState, "stack depth %d is below the saved depth %d", len(thread.stack), context.stackDepth)
+	}
+	if len(thread.registers) != len(context.registers) {
+		return vo.fail(false, errInvalidState, "the register file was resized since context %q was saved", label)
+	}
+
+	thread.pc = context.pc
+	copy(thread.registers, context.registers)
+	thread.stack = thread.stack[:context.stackDepth]
+	return vo.succeed(true, nil)
+}
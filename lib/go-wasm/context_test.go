@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSaveAndRestoreContext(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	id := createThread(t, vo, 0x2000, 1, "paused")
+	requireOK(t, call(vo.SetRegister, id, 1, 7))
+	requireOK(t, call(vo.PushStack, id, 0xaa))
+	requireOK(t, call(vo.SaveContext, id, "jmp"))
+
+	// Run on, clobber registers and push past the saved depth
+	for i := 0; i < 3; i++ {
+		requireOK(t, call(vo.StepThread, id))
+	}
+	requireOK(t, call(vo.SetRegister, id, 1, 99))
+	requireOK(t, call(vo.SetRegister, id, 2, 5))
+	requireOK(t, call(vo.PushStack, id, 0xbb))
+	requireOK(t, call(vo.PushStack, id, 0xcc))
+
+	requireOK(t, call(vo.RestoreContext, id, "jmp"))
+	if got := threadPC(t, vo, id); got != 0x2000 {
+		t.Errorf("pc = %#x after restore, want 0x2000", got)
+	}
+	registers := call(vo.GetRegisters, id)
+	if r1, r2 := registers.Index(1).Int(), registers.Index(2).Int(); r1 != 7 || r2 != 0 {
+		t.Errorf("r1, r2 = %d, %d after restore, want 7, 0", r1, r2)
+	}
+	if got := call(vo.GetThread, id).Get("stackDepth").Int(); got != 1 {
+		t.Errorf("stack depth = %d after restore, want 1", got)
+	}
+
+	// A context can be restored again, but not once the stack it relied on
+	// has been popped
+	requireOK(t, call(vo.RestoreContext, id, "jmp"))
+	call(vo.PopStack, id)
+	requireError(t, call(vo.RestoreContext, id, "jmp"), errInvalidState)
+}
+
+func TestRestoreUnknownContextFails(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	ids := pausedThreads(t, vo, 2)
+	requireOK(t, call(vo.SaveContext, ids[0], "jmp"))
+
+	requireError(t, call(vo.RestoreContext, ids[0], "other"), errUnknownContext)
+	// Labels are per thread
+	requireError(t, call(vo.RestoreContext, ids[1], "jmp"), errUnknownContext)
+	requireError(t, call(vo.RestoreContext, 99, "jmp"), errUnknownThread)
+}
@@ -0,0 +1,58 @@
+// CPU Features
+// The feature set the virtual CPU advertises to guest code
+//
+// Guests probe for optional instruction sets before using them. The
+// orchestrator holds one feature bitmask so the bridge answers every probe
+// (CPUID, HWCAP, /proc/cpuinfo) consistently; GetCpuFeatures is what the
+// bridge consults. The bits are the orchestrator's own, listed below, and
+// the bridge maps them to whatever the guest ISA reports. The mask is part
+// of ExportState.
+//
+// The default is baselineCPUFeatures, the ARMv7-A baseline Android requires
+// (VFPv3 and NEON). Bits outside the known set are kept as they are, so a
+// host can define its own above bit 15.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+)
+
+// CPU feature bits
+const (
+	cpuFeatureVFP     = 1 << 0 // VFPv3 floating point
+	cpuFeatureNEON    = 1 << 1 // Advanced SIMD
+	cpuFeatureIDIV    = 1 << 2 // hardware integer divide
+	cpuFeatureAES     = 1 << 3
+	cpuFeatureSHA1    = 1 << 4
+	cpuFeatureSHA2    = 1 << 5
+	cpuFeatureCRC32   = 1 << 6
+	cpuFeatureAtomics = 1 << 7 // large system extensions (ARMv8.1 atomics)
+	cpuFeatureSSE2    = 1 << 8 // x86 guests
+	cpuFeatureSSE42   = 1 << 9
+	cpuFeatureAVX     = 1 << 10
+	cpuFeatureAVX2    = 1 << 11
+
+	baselineCPUFeatures = cpuFeatureVFP | cpuFeatureNEON
+)
+
+// SetCpuFeatures replaces the advertised feature bitmask
+func (vo *VMOrchestrator) SetCpuFeatures(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber {
+		return js.ValueOf(false)
+	}
+
+	flags := args[0].Float()
+	if flags < 0 || flags > 0xFFFFFFFF || flags != float64(uint32(flags)) {
+		return js.ValueOf(false)
+	}
+
+	atomic.StoreUint32(&vo.cpuFeatures, uint32(flags))
+	return js.ValueOf(true)
+}
+
+// GetCpuFeatures returns the advertised feature bitmask
+func (vo *VMOrchestrator) GetCpuFeatures(this js.Value, args []js.Value) interface{} {
+	return js.ValueOf(atomic.LoadUint32(&vo.cpuFeatures))
+}
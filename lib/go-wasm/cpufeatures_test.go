@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCpuFeaturesRoundTrip(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	if got := uint32(call(vo.GetCpuFeatures).Float()); got != baselineCPUFeatures {
+		t.Fatalf("default features = %#x, want the baseline %#x", got, baselineCPUFeatures)
+	}
+
+	// Host-defined bits above the known set are kept too
+	const features = cpuFeatureNEON | cpuFeatureAES | cpuFeatureCRC32 | 1<<31
+	if !call(vo.SetCpuFeatures, features).Bool() {
+		t.Fatal("setCpuFeatures failed")
+	}
+	if got := uint32(call(vo.GetCpuFeatures).Float()); got != features {
+		t.Fatalf("features = %#x, want %#x", got, features)
+	}
+	for _, bad := range []interface{}{-1, 1 << 32, 1.5, "3"} {
+		if call(vo.SetCpuFeatures, bad).Bool() {
+			t.Errorf("setCpuFeatures(%v) succeeded", bad)
+		}
+	}
+
+	exported := call(vo.ExportState).String()
+	var state exportedState
+	if err := json.Unmarshal([]byte(exported), &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Config.CPUFeatures == nil || *state.Config.CPUFeatures != features {
+		t.Fatalf("exported config.cpuFeatures = %v, want %#x", state.Config.CPUFeatures, features)
+	}
+
+	restored := newTestOrchestrator(t)
+	requireOK(t, call(restored.ImportState, exported))
+	if got := uint32(call(restored.GetCpuFeatures).Float()); got != features {
+		t.Errorf("imported features = %#x, want %#x", got, features)
+	}
+}
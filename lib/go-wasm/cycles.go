@@ -0,0 +1,36 @@
+// Cycle Counting
+// Tracks emulated clock cycles alongside instruction counts
+//
+// The executeInstruction bridge may report the cost of the instruction it
+// ran as { ok, cycles }, and executeInstructions the total for its batch;
+// an instruction without a reported cost counts as one cycle. Cycles are
+// summed per thread and globally, so hosts doing timing-sensitive
+// emulation can pace guest time or budget work in cycles rather than
+// instructions.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// GetCyclesPerInstruction returns the average cycles per instruction over
+// everything executed so far, or 0 before the first instruction
+func (vo *VMOrchestrator) GetCyclesPerInstruction(this js.Value, args []js.Value) interface{} {
+	vo.statsMutex.RLock()
+	defer vo.statsMutex.RUnlock()
+
+	return js.ValueOf(vo.stats.CyclesPerInstruction())
+}
+
+// reportedCycles returns the cycle count in a bridge result, or fallback if
+// the bridge did not report a non-negative one
+func reportedCycles(result js.Value, fallback uint64) uint64 {
+	if result.Type() != js.TypeObject {
+		return fallback
+	}
+	if cycles := result.Get("cycles"); cycles.Type() == js.TypeNumber && cycles.Float() >= 0 {
+		return uint64(cycles.Float())
+	}
+	return fallback
+}
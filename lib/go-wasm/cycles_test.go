@@ -0,0 +1,44 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestCyclesAccumulate(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	costs := []interface{}{1, 3, 5, -2} // a negative cost is ignored and counts as 1
+	call(vo.Initialize, newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func(args []js.Value) interface{} {
+			pc := uint64(args[0].Float())
+			if pc < 0x40000000 {
+				return true // no reported cost
+			}
+			return map[string]interface{}{"ok": true, "cycles": costs[(pc-0x40000000)/4%uint64(len(costs))]}
+		},
+	}))
+	variable := createThread(t, vo, 0x40000000, 1, "paused")
+	fixed := createThread(t, vo, 0x1000, 1, "paused")
+
+	if got := call(vo.GetCyclesPerInstruction).Float(); got != 0 {
+		t.Errorf("cycles per instruction before running = %v, want 0", got)
+	}
+	for i := 0; i < 8; i++ {
+		requireOK(t, call(vo.StepThread, variable))
+	}
+	for i := 0; i < 6; i++ {
+		requireOK(t, call(vo.StepThread, fixed))
+	}
+
+	for id, want := range map[int]int{variable: 2 * (1 + 3 + 5 + 1), fixed: 6} {
+		if got := call(vo.GetThread, id).Get("cyclesExecuted").Int(); got != want {
+			t.Errorf("thread %d executed %d cycles, want %d", id, got, want)
+		}
+	}
+	if got := stat(vo, "cyclesExecuted"); got != 26 {
+		t.Errorf("cyclesExecuted = %v, want 26", got)
+	}
+	if got := call(vo.GetCyclesPerInstruction).Float(); got != 26.0/14 {
+		t.Errorf("cycles per instruction = %v, want %v", got, 26.0/14)
+	}
+}
@@ -0,0 +1,179 @@
+// Deadlock Detection
+// Finds circular waits between threads in the "waiting" status
+//
+// WaitThread records a wait-for edge from one thread to another. The edges of
+// all waiting threads form a wait-for graph; any cycle in it is a deadlock.
+// Detection runs whenever a new edge is added and on demand.
+
+package main
+
+import (
+	"sort"
+	"syscall/js"
+	"time"
+)
+
+// WaitThread puts a thread into "waiting" on another thread until
+// WakeThread, or until timeoutMs elapses if given (see wait.go). A wake
+// left pending by an earlier WakeThread is consumed instead and the thread
+// does not wait.
+// Arguments: threadID, onThreadID, optional timeoutMs
+func (vo *VMOrchestrator) WaitThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(false, errInvalidArgument, "waitThread requires a thread ID and the thread ID to wait on")
+	}
+
+	threadID, onThreadID := args[0].Int(), args[1].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+	if vo.getThread(onThreadID) == nil {
+		return vo.unknownThread(false, onThreadID)
+	}
+
+	var timeout time.Duration
+	if len(args) > 2 && !args[2].IsUndefined() && !args[2].IsNull() {
+		if args[2].Type() != js.TypeNumber || args[2].Float() < 0 {
+			return vo.fail(false, errInvalidArgument, "timeoutMs must be a non-negative number")
+		}
+		timeout = time.Duration(args[2].Float() * float64(time.Millisecond))
+	}
+
+	thread.mutex.Lock()
+	switch {
+	case (thread.status == "running" || thread.status == "paused") && thread.wakePending:
+		thread.wakePending = false
+		thread.timedOut = false
+		thread.mutex.Unlock()
+		return vo.succeed(true, nil)
+	case thread.status == "running" || thread.status == "paused":
+		vo.beginWait(thread)
+		thread.wakeable = true
+		thread.timedOut = false
+	case thread.status == "waiting":
+		if timeout > 0 && !thread.wakeable {
+			thread.mutex.Unlock()
+			return vo.fail(false, errInvalidState, "thread %d is blocked on a guest lock and cannot time out", threadID)
+		}
+	default:
+		status := thread.status
+		thread.mutex.Unlock()
+		return vo.fail(false, errInvalidState, "thread %d is %s", threadID, status)
+	}
+	thread.waitingOn = append(thread.waitingOn, onThreadID)
+	if timeout > 0 {
+		vo.armWaitTimeout(thread, timeout)
+	}
+	thread.mutex.Unlock()
+
+	// A new edge is the only way a cycle can form
+	if cycle := vo.findDeadlock(); cycle != nil {
+		vo.fireDeadlock(cycle)
+	}
+	return vo.succeed(true, nil)
+}
+
+// DetectDeadlock returns the thread IDs forming a wait cycle, or an empty
+// array if there is none
+func (vo *VMOrchestrator) DetectDeadlock(this js.Value, args []js.Value) interface{} {
+	return js.ValueOf(intsToJS(vo.findDeadlock()))
+}
+
+// OnDeadlock registers a callback invoked as callback(threadIDs) when a wait
+// cycle is detected. Passing null clears it.
+func (vo *VMOrchestrator) OnDeadlock(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	vo.deadlockCallback = args[0]
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// waitForGraph collects wait-for edges of every waiting thread
+func (vo *VMOrchestrator) waitForGraph() map[int][]int {
+	vo.threadMutex.RLock()
+	defer vo.threadMutex.RUnlock()
+
+	graph := make(map[int][]int)
+	for id, thread := range vo.threads {
+		thread.mutex.RLock()
+		if thread.status == "waiting" && len(thread.waitingOn) > 0 {
+			graph[id] = append([]int(nil), thread.waitingOn...)
+		}
+		thread.mutex.RUnlock()
+	}
+	return graph
+}
+
+// findDeadlock returns the first wait cycle found, visiting threads in ID
+// order so the result is deterministic. Returns nil if there is no cycle.
+func (vo *VMOrchestrator) findDeadlock() []int {
+	graph := vo.waitForGraph()
+
+	nodes := make([]int, 0, len(graph))
+	for id := range graph {
+		nodes = append(nodes, id)
+	}
+	sort.Ints(nodes)
+
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[int]int, len(graph))
+	var path []int
+
+	var visit func(id int) []int
+	visit = func(id int) []int {
+		state[id] = onPath
+		path = append(path, id)
+
+		for _, next := range graph[id] {
+			switch state[next] {
+			case onPath:
+				// Back edge: the cycle is the path from next to here
+				for i, pathID := range path {
+					if pathID == next {
+						return append([]int(nil), path[i:]...)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[id] = done
+		return nil
+	}
+
+	for _, id := range nodes {
+		if state[id] == unvisited {
+			if cycle := visit(id); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// fireDeadlock queues a call to the registered deadlock callback
+func (vo *VMOrchestrator) fireDeadlock(cycle []int) {
+	vo.postEvent(eventCritical, "", func() {
+		vo.callbackMutex.RLock()
+		callback := vo.deadlockCallback
+		vo.callbackMutex.RUnlock()
+
+		if callback.Type() == js.TypeFunction {
+			callback.Invoke(js.ValueOf(intsToJS(cycle)))
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"syscall/js"
+	"testing"
+)
+
+func TestDetectDeadlockTwoThreadCycle(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	a, b := createThread(t, vo, 0x1000), createThread(t, vo, 0x2000)
+
+	requireOK(t, call(vo.WaitThread, a, b))
+	if got := call(vo.DetectDeadlock).Length(); got != 0 {
+		t.Fatalf("found a cycle of %d threads with one wait edge", got)
+	}
+	requireOK(t, call(vo.WaitThread, b, a))
+
+	if got := deadlockCycle(vo); !reflect.DeepEqual(got, []int{a, b}) {
+		t.Fatalf("cycle = %v, want %v", got, []int{a, b})
+	}
+}
+
+func TestDetectDeadlockThreeThreadCycle(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	a, b, c := createThread(t, vo, 0x1000), createThread(t, vo, 0x2000), createThread(t, vo, 0x3000)
+
+	cycles := make(chan []int, 1)
+	call(vo.OnDeadlock, newCallback(t, func(args []js.Value) {
+		cycles <- jsToInts(args[0])
+	}))
+
+	requireOK(t, call(vo.WaitThread, a, b))
+	requireOK(t, call(vo.WaitThread, b, c))
+	if got := call(vo.DetectDeadlock).Length(); got != 0 {
+		t.Fatalf("found a cycle of %d threads in a wait chain", got)
+	}
+	requireOK(t, call(vo.WaitThread, c, a))
+
+	want := []int{a, b, c}
+	if got := deadlockCycle(vo); !reflect.DeepEqual(got, want) {
+		t.Fatalf("cycle = %v, want %v", got, want)
+	}
+	var reported []int
+	eventually(t, "the deadlock callback", func() bool {
+		select {
+		case reported = <-cycles:
+			return true
+		default:
+			return false
+		}
+	})
+	if !reflect.DeepEqual(reported, want) {
+		t.Errorf("OnDeadlock reported %v, want %v", reported, want)
+	}
+
+	// Waking one thread breaks the cycle
+	requireOK(t, call(vo.WakeThread, b))
+	if got := call(vo.DetectDeadlock).Length(); got != 0 {
+		t.Errorf("cycle of %d threads remains after a wake", got)
+	}
+}
+
+// deadlockCycle returns DetectDeadlock's result as Go ints
+func deadlockCycle(vo *VMOrchestrator) []int {
+	return jsToInts(call(vo.DetectDeadlock))
+}
+
+// jsToInts converts a JS array of numbers
+func jsToInts(array js.Value) []int {
+	ints := make([]int, array.Length())
+	for i := range ints {
+		ints[i] = array.Index(i).Int()
+	}
+	return ints
+}
@@ -0,0 +1,64 @@
+// Deterministic Scheduling
+// Reproducible thread interleaving driven by a seeded PRNG
+//
+// With several workers the Go runtime decides which quanta run concurrently,
+// so instruction interleaving differs between runs. SetDeterministic(seed)
+// collapses the pool to a single worker that steps one thread at a time and
+// picks the next thread from the run queue with a PRNG seeded from seed.
+// The PRNG is reseeded on every Start, so the same seed, program and host
+// inputs produce the same interleaving and the same trace on every run.
+// Thread affinity is ignored while deterministic mode is on.
+
+package main
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"syscall/js"
+)
+
+// SetDeterministic enables seeded deterministic scheduling, or disables it
+// when passed null. If the VM is running the worker pool is restarted.
+// Arguments: seed (integer) or null
+func (vo *VMOrchestrator) SetDeterministic(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(false)
+	}
+
+	vo.schedMutex.Lock()
+	switch {
+	case args[0].Type() == js.TypeNumber:
+		vo.deterministic = true
+		vo.deterministicSeed = int64(args[0].Float())
+		vo.schedRand = rand.New(rand.NewSource(vo.deterministicSeed))
+	case args[0].IsNull() || args[0].IsUndefined():
+		vo.deterministic = false
+		vo.schedRand = nil
+	default:
+		vo.schedMutex.Unlock()
+		return js.ValueOf(false)
+	}
+	vo.schedMutex.Unlock()
+
+	if atomic.LoadInt32(&vo.isRunning) == 1 {
+		vo.startScheduler()
+	}
+	return js.ValueOf(true)
+}
+
+// reseedScheduler restarts the deterministic PRNG from its seed so a new
+// run replays the same choices. Caller must hold schedMutex.
+func (vo *VMOrchestrator) reseedScheduler() {
+	if vo.deterministic {
+		vo.schedRand = rand.New(rand.NewSource(vo.deterministicSeed))
+	}
+}
+
+// poolSize returns the number of workers to start: one in deterministic
+// mode, otherwise the configured maximum. Caller must hold schedMutex.
+func (vo *VMOrchestrator) poolSize() int {
+	if vo.deterministic {
+		return 1
+	}
+	return vo.maxWorkers
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// deterministicTrace runs four threads to fixed instruction limits under
+// the given seed and returns the executed (threadID, pc) pairs in order
+func deterministicTrace(t *testing.T, seed int) [][2]int {
+	t.Helper()
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	call(vo.SetDeterministic, seed)
+	call(vo.EnableTrace, 4096)
+	// Hold dispatch until the main thread Start creates is queued too
+	call(vo.SetTickMode, true)
+
+	for i := 1; i <= 3; i++ {
+		createThread(t, vo, uint64(0x10000*i), i)
+	}
+	requireOK(t, call(vo.Start))
+	for id := 1; id <= 4; id++ {
+		requireOK(t, call(vo.SetThreadInstructionLimit, id, 300))
+	}
+	call(vo.Tick, 1000)
+	eventually(t, "every thread to reach its limit", func() bool { return stat(vo, "activeThreads") == 0 })
+
+	trace := call(vo.GetTrace)
+	entries := make([][2]int, trace.Length())
+	for i := range entries {
+		entry := trace.Index(i)
+		entries[i] = [2]int{entry.Get("threadID").Int(), entry.Get("pc").Int()}
+	}
+	return entries
+}
+
+func TestSameSeedGivesIdenticalTraces(t *testing.T) {
+	first := deterministicTrace(t, 42)
+	if len(first) != 4*300 {
+		t.Fatalf("traced %d instructions, want %d", len(first), 4*300)
+	}
+	if second := deterministicTrace(t, 42); !reflect.DeepEqual(first, second) {
+		t.Fatal("two runs with seed 42 interleaved differently")
+	}
+	if other := deterministicTrace(t, 7); reflect.DeepEqual(first, other) {
+		t.Error("seeds 42 and 7 gave the same interleaving")
+	}
+}
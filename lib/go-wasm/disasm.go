@@ -0,0 +1,110 @@
+// Disassembly Cache
+// Memoizes PC-to-mnemonic lookups made through a JS disassembler
+//
+// The orchestrator does not decode instructions itself. OnDisassemble
+// registers a host function that turns a PC into text; GetDisassembly
+// answers from a cache keyed by PC and only crosses into JS on a miss, so
+// repeated lookups inside loops stay cheap. Code that rewrites itself must
+// call InvalidateDisassembly for the bytes it changed.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// OnDisassemble registers a disassembler invoked as callback(pc), which
+// returns the instruction at pc as a string. Passing null clears it.
+// Replacing the disassembler empties the cache.
+func (vo *VMOrchestrator) OnDisassemble(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.disasmMutex.Lock()
+	vo.disassembler = args[0]
+	vo.disasmCache = make(map[uint64]string)
+	vo.disasmMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// GetDisassembly returns the text of the instruction at pc, or null if no
+// disassembler is registered, it threw, or it returned something other
+// than a string
+func (vo *VMOrchestrator) GetDisassembly(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.Null()
+	}
+
+	pc, ok := jsToAddress(args[0])
+	if !ok {
+		return js.Null()
+	}
+
+	if text, ok := vo.disassemble(pc); ok {
+		return js.ValueOf(text)
+	}
+	return js.Null()
+}
+
+// InvalidateDisassembly drops cached text for instructions starting in
+// [address, address+size)
+// Arguments: address, size
+func (vo *VMOrchestrator) InvalidateDisassembly(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return js.ValueOf(false)
+	}
+
+	address, ok := jsToAddress(args[0])
+	size, sizeOK := jsToAddress(args[1])
+	if !ok || !sizeOK {
+		return js.ValueOf(false)
+	}
+
+	vo.disasmMutex.Lock()
+	defer vo.disasmMutex.Unlock()
+
+	// Offsets from address avoid overflow at the top of the address space
+	for pc := range vo.disasmCache {
+		if pc >= address && pc-address < size {
+			delete(vo.disasmCache, pc)
+		}
+	}
+	return js.ValueOf(true)
+}
+
+// disassemble returns the cached text for pc, querying the disassembler on
+// a miss. The disassembler is invoked without holding disasmMutex so it may
+// call back into the VM.
+func (vo *VMOrchestrator) disassemble(pc uint64) (string, bool) {
+	vo.disasmMutex.RLock()
+	text, cached := vo.disasmCache[pc]
+	disassembler := vo.disassembler
+	vo.disasmMutex.RUnlock()
+
+	if cached {
+		return text, true
+	}
+	if disassembler.Type() != js.TypeFunction {
+		return "", false
+	}
+
+	result, err := callJS(func() js.Value { return disassembler.Invoke(addressToJS(pc)) })
+	if err != nil {
+		vo.logf(logError, "disassembler threw at %#x: %v", pc, err)
+		return "", false
+	}
+	if result.Type() != js.TypeString {
+		return "", false
+	}
+	text = result.String()
+
+	vo.disasmMutex.Lock()
+	// Skip caching if the disassembler was replaced during the call
+	if vo.disassembler.Equal(disassembler) {
+		vo.disasmCache[pc] = text
+	}
+	vo.disasmMutex.Unlock()
+	return text, true
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"syscall/js"
+	"testing"
+)
+
+func TestDisassemblyCache(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	queries := map[uint64]int{}
+	version := 1
+	call(vo.OnDisassemble, newJSFunc(t, func(args []js.Value) interface{} {
+		pc, _ := jsToAddress(args[0])
+		queries[pc]++
+		return fmt.Sprintf("nop.%d @%#x", version, pc)
+	}))
+
+	for i := 0; i < 3; i++ {
+		if got := call(vo.GetDisassembly, 0x1000).String(); got != "nop.1 @0x1000" {
+			t.Fatalf("disassembly = %q", got)
+		}
+	}
+	call(vo.GetDisassembly, 0x1004)
+	call(vo.GetDisassembly, 0x1008)
+	if queries[0x1000] != 1 {
+		t.Fatalf("disassembler queried %d times for a cached pc, want 1", queries[0x1000])
+	}
+
+	// The code at 0x1004 is rewritten; only that entry is re-queried
+	version = 2
+	if !call(vo.InvalidateDisassembly, 0x1004, 4).Bool() {
+		t.Fatal("invalidateDisassembly failed")
+	}
+	if got := call(vo.GetDisassembly, 0x1004).String(); got != "nop.2 @0x1004" {
+		t.Errorf("disassembly after invalidation = %q, want the new text", got)
+	}
+	if got := call(vo.GetDisassembly, 0x1000).String(); got != "nop.1 @0x1000" {
+		t.Errorf("an entry outside the invalidated range changed: %q", got)
+	}
+	if queries[0x1004] != 2 || queries[0x1000] != 1 || queries[0x1008] != 1 {
+		t.Errorf("queries = %v, want 0x1004 re-queried once and nothing else", queries)
+	}
+}
+
+func TestGetDisassemblyWithoutDisassembler(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	if got := call(vo.GetDisassembly, 0x1000); !got.IsNull() {
+		t.Errorf("disassembly = %v with no disassembler, want null", got)
+	}
+}
+
+func TestThrowingDisassemblerGivesNull(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.OnDisassemble, jsFunction("pc", `if (pc === 0x1000) throw new Error("bad encoding"); return "nop";`))
+	if got := call(vo.GetDisassembly, 0x1000); !got.IsNull() {
+		t.Errorf("disassembly = %v from a throwing disassembler, want null", got)
+	}
+	if got := call(vo.GetDisassembly, 0x1004); got.String() != "nop" {
+		t.Errorf("disassembly = %v after a throw, want nop", got)
+	}
+}
@@ -0,0 +1,80 @@
+// Thread Dumps
+// Renders every thread as text for crash reports, like a Java thread dump
+//
+// The format is stable; tools may parse it. Threads appear in ID order, one
+// block each, separated by a blank line:
+//
+//	Thread 1 "main": running
+//	  pc     0x0000000000001000
+//	  stack  2
+//	  r0  0x00000000  r1  0x0000002a  r2  0x00000000  r3  0x00000000
+//	  r4  ...
+//
+// The name is omitted for unnamed threads ("Thread 2: waiting"). Faulted
+// threads get a "fault" line after "stack" with the fault message. Zombies
+// (see zombies.go) are included with their exit reason in the status,
+// e.g. "Thread 3: terminated (halted)". The PC is always 16 hex digits and
+// registers 8, with four registers per line.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"syscall/js"
+)
+
+// dumpRegistersPerLine is how many registers each register line shows
+const dumpRegistersPerLine = 4
+
+// DumpThreads returns a text dump of every active thread and zombie
+func (vo *VMOrchestrator) DumpThreads(this js.Value, args []js.Value) interface{} {
+	vo.threadMutex.RLock()
+	threads := make([]*VMThread, 0, len(vo.threads)+len(vo.zombies))
+	for _, thread := range vo.threads {
+		threads = append(threads, thread)
+	}
+	for _, thread := range vo.zombies {
+		threads = append(threads, thread)
+	}
+	vo.threadMutex.RUnlock()
+
+	sort.Slice(threads, func(i, j int) bool { return threads[i].id < threads[j].id })
+
+	blocks := make([]string, len(threads))
+	for i, thread := range threads {
+		thread.mutex.RLock()
+		blocks[i] = thread.dump()
+		thread.mutex.RUnlock()
+	}
+	return js.ValueOf(strings.Join(blocks, "\n"))
+}
+
+// dump formats one thread block; caller must hold thread.mutex
+func (thread *VMThread) dump() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Thread %d", thread.id)
+	if thread.name != "" {
+		fmt.Fprintf(&b, " %q", thread.name)
+	}
+	if thread.status == "terminated" {
+		fmt.Fprintf(&b, ": terminated (%s)\n", thread.exitReason)
+	} else {
+		fmt.Fprintf(&b, ": %s\n", thread.status)
+	}
+	fmt.Fprintf(&b, "  pc     %#016x\n", thread.pc)
+	fmt.Fprintf(&b, "  stack  %d\n", len(thread.stack))
+	if thread.status == "faulted" && thread.faultMessage != "" {
+		fmt.Fprintf(&b, "  fault  %s\n", thread.faultMessage)
+	}
+
+	for i, value := range thread.registers {
+		fmt.Fprintf(&b, "  %-3s %#08x", fmt.Sprintf("r%d", i), value)
+		if i%dumpRegistersPerLine == dumpRegistersPerLine-1 || i == len(thread.registers)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
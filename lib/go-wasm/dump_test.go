@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDumpThreads(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	main := createThread(t, vo, 0x1000, 1, "paused", "main")
+	worker := createThread(t, vo, 0x2000, 1, "paused")
+	requireOK(t, call(vo.SetRegister, main, 1, 42))
+	requireOK(t, call(vo.SetRegister, worker, 15, 0xdeadbeef))
+	requireOK(t, call(vo.WaitThread, worker, main))
+
+	dump := call(vo.DumpThreads).String()
+	for _, want := range []string{
+		`Thread 1 "main": paused`,
+		"  pc     0x0000000000001000\n",
+		"  stack  0\n",
+		"  r0  0x00000000  r1  0x0000002a  r2  0x00000000  r3  0x00000000\n",
+		"Thread 2: waiting\n",
+		"  pc     0x0000000000002000\n",
+		"  r12 0x00000000  r13 0x00000000  r14 0x00000000  r15 0xdeadbeef\n",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump is missing %q:\n%s", want, dump)
+		}
+	}
+	if first, second := strings.Index(dump, "Thread 1"), strings.Index(dump, "Thread 2"); first < 0 || second < first {
+		t.Errorf("threads are not in ID order:\n%s", dump)
+	}
+	if !strings.Contains(dump, "\n\nThread 2") {
+		t.Errorf("thread blocks are not separated by a blank line:\n%s", dump)
+	}
+}
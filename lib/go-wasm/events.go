@@ -0,0 +1,163 @@
+// Event Dispatch
+// Delivers orchestrator events to JavaScript off the execution path
+//
+// Callbacks for breakpoints, watches, faults, deadlocks, stack overflows,
+// status changes, stats heartbeats, instruction milestones, thread
+// terminations and idle are not invoked where the event is raised. They
+// are queued and a dispatcher goroutine invokes them in the order they
+// were posted, with no orchestrator lock held, so callbacks may call back
+// into the VM and a slow callback never stalls executeThread. A callback
+// that throws is counted in failedEvents and the dispatcher moves on to
+// the next event.
+//
+// The queue is bounded (SetEventQueueSize). When it is full, a new event
+// evicts the oldest pending event of the lowest priority, or is itself
+// dropped if everything pending matters more; either way droppedEvents is
+// incremented. Events with a coalescing key (stats) replace a pending event
+// with the same key instead of queueing a second one. Critical events are
+// never dropped and may exceed the bound.
+
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"syscall/js"
+)
+
+// defaultEventQueueSize is the initial bound on pending events
+const defaultEventQueueSize = 1024
+
+// Event priorities, lowest first
+const (
+	eventLow      = iota // status changes and log messages
+	eventNormal          // breakpoints, watches, stats and logged errors
+	eventCritical        // faults, deadlocks, stack overflows, terminations, idle and automatic snapshots; never dropped
+)
+
+// event is a pending callback invocation
+type event struct {
+	priority int
+	key      string // non-empty: replaces a pending event with the same key
+	run      func()
+}
+
+// eventQueue is a bounded FIFO of pending events
+type eventQueue struct {
+	mutex    sync.Mutex
+	pending  []event
+	capacity int
+	dropped  uint64        // atomic: events discarded because the queue was full
+	failed   uint64        // atomic: events whose callback threw or panicked
+	wake     chan struct{} // signals the dispatcher that events are pending
+	once     sync.Once     // starts the dispatcher on first use
+}
+
+// SetEventQueueSize bounds the number of pending non-critical events
+func (vo *VMOrchestrator) SetEventQueueSize(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(false)
+	}
+
+	size := args[0].Int()
+	if size < 1 {
+		return js.ValueOf(false)
+	}
+
+	vo.events.mutex.Lock()
+	vo.events.capacity = size
+	vo.events.mutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// postEvent queues run for the dispatcher goroutine. Never blocks on JS,
+// so it is safe to call while holding any orchestrator lock.
+func (vo *VMOrchestrator) postEvent(priority int, key string, run func()) {
+	queue := &vo.events
+	queue.once.Do(func() {
+		queue.wake = make(chan struct{}, 1)
+		go queue.dispatch()
+	})
+
+	if queue.push(event{priority, key, run}) {
+		select {
+		case queue.wake <- struct{}{}:
+		default: // a wakeup is already pending
+		}
+	}
+}
+
+// push adds an event, coalescing or evicting as needed. Returns false if
+// the event was dropped or merged into one already pending.
+func (queue *eventQueue) push(e event) bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if e.key != "" {
+		for i := range queue.pending {
+			if queue.pending[i].key == e.key {
+				queue.pending[i] = e
+				return false
+			}
+		}
+	}
+
+	if e.priority != eventCritical && len(queue.pending) >= queue.capacity {
+		atomic.AddUint64(&queue.dropped, 1)
+
+		victim := -1
+		for i, pending := range queue.pending {
+			if pending.priority < eventCritical && (victim < 0 || pending.priority < queue.pending[victim].priority) {
+				victim = i
+			}
+		}
+		if victim < 0 || queue.pending[victim].priority > e.priority {
+			return false
+		}
+		queue.pending = append(queue.pending[:victim], queue.pending[victim+1:]...)
+	}
+
+	queue.pending = append(queue.pending, e)
+	return true
+}
+
+// dispatch runs queued events in order, forever. Events are popped one at
+// a time so those still pending remain subject to the bound.
+func (queue *eventQueue) dispatch() {
+	for range queue.wake {
+		for {
+			e, ok := queue.pop()
+			if !ok {
+				break
+			}
+			queue.run(e)
+		}
+	}
+}
+
+// run invokes one event, recovering from a throwing callback so it cannot
+// take the dispatcher down. The failure is only counted: reporting it
+// through onLog could throw again.
+func (queue *eventQueue) run(e event) {
+	defer func() {
+		if r := recover(); r != nil {
+			atomic.AddUint64(&queue.failed, 1)
+		}
+	}()
+	e.run()
+}
+
+// pop removes and returns the oldest pending event
+func (queue *eventQueue) pop() (event, bool) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if len(queue.pending) == 0 {
+		return event{}, false
+	}
+	e := queue.pending[0]
+	queue.pending[0] = event{}
+	queue.pending = queue.pending[1:]
+	return e, true
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+func TestEventQueueOverflowWithSlowConsumer(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.SetEventQueueSize, 4)
+
+	// The first event stalls the dispatcher like a slow JS callback
+	started, release := make(chan struct{}), make(chan struct{})
+	vo.postEvent(eventNormal, "", func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var mutex sync.Mutex
+	var ran []string
+	post := func(priority int, name string) {
+		vo.postEvent(priority, "", func() {
+			mutex.Lock()
+			ran = append(ran, name)
+			mutex.Unlock()
+		})
+	}
+	for _, name := range []string{"low1", "low2", "low3", "low4", "low5", "low6"} {
+		post(eventLow, name)
+	}
+	post(eventCritical, "fault1")
+	post(eventCritical, "fault2")
+	post(eventNormal, "breakpoint")
+
+	close(release)
+	want := []string{"low4", "low5", "low6", "fault1", "fault2", "breakpoint"}
+	eventually(t, "the queue to drain", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(ran) == len(want)
+	})
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if got := stat(vo, "droppedEvents"); got != 3 {
+		t.Errorf("droppedEvents = %v, want 3", got)
+	}
+}
+
+func TestEventQueueDropsLowPriorityArrivals(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.SetEventQueueSize, 2)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	vo.postEvent(eventNormal, "", func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var mutex sync.Mutex
+	var ran []string
+	for _, e := range []struct {
+		priority int
+		name     string
+	}{{eventNormal, "watch1"}, {eventNormal, "watch2"}, {eventLow, "status"}} {
+		name := e.name
+		vo.postEvent(e.priority, "", func() {
+			mutex.Lock()
+			ran = append(ran, name)
+			mutex.Unlock()
+		})
+	}
+
+	close(release)
+	eventually(t, "the queue to drain", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(ran) == 2
+	})
+	mutex.Lock()
+	defer mutex.Unlock()
+	if want := []string{"watch1", "watch2"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if got := stat(vo, "droppedEvents"); got != 1 {
+		t.Errorf("droppedEvents = %v, want 1", got)
+	}
+}
+
+func TestThrowingCallbackDoesNotStopDispatch(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.OnThreadTerminated, jsFunction("exit", `throw new Error("listener bug")`))
+	ids := pausedThreads(t, vo, 2)
+	requireOK(t, call(vo.KillThread, ids[0]))
+	eventually(t, "the throwing callback to be counted", func() bool { return stat(vo, "failedEvents") == 1 })
+
+	// Later events are still delivered
+	call(vo.OnThreadTerminated, js.Null())
+	terminated := make(chan int, 1)
+	call(vo.OnThreadTerminated, newCallback(t, func(args []js.Value) { terminated <- args[0].Get("id").Int() }))
+	requireOK(t, call(vo.KillThread, ids[1]))
+	select {
+	case id := <-terminated:
+		if id != ids[1] {
+			t.Errorf("termination callback got thread %d, want %d", id, ids[1])
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("the dispatcher stopped after a callback threw")
+	}
+
+	vo.postEvent(eventNormal, "", func() { panic("go callback bug") })
+	eventually(t, "the panicking event to be counted", func() bool { return stat(vo, "failedEvents") == 2 })
+}
@@ -0,0 +1,85 @@
+// Fault Log
+// Keeps the most recent thread faults for later inspection
+//
+// OnFault is fire-and-forget: a host that was not listening, or that only
+// looks after the fact, has no record of what failed. Every fault is also
+// appended to a ring buffer (64 entries by default, SetFaultLogSize to
+// change), which GetFaultLog returns oldest first. Faults raised by a JS
+// exception inside the emulator bridge are logged with reason "exception"
+// and the exception text as message; memory access violations with reason
+// "segfault" and the faulting address; stack overflows with reason
+// "stack_overflow"; faults injected as "illegal_instruction" or
+// "div_by_zero" under that type; everything else with reason "error".
+
+package main
+
+import (
+	"syscall/js"
+	"time"
+
+	"github.com/aquifer/vm-orchestrator/internal/ring"
+)
+
+// defaultFaultLogSize is how many faults are kept until SetFaultLogSize
+const defaultFaultLogSize = 64
+
+// Fault log reasons
+const (
+	faultException = "exception" // the bridge threw while executing the thread
+	faultSegfault  = "segfault"  // a memory access outside the memory map
+	faultError     = "error"     // any other fault
+
+	faultStackOverflow      = "stack_overflow"      // the thread's stack exceeded its limit
+	faultIllegalInstruction = "illegal_instruction" // injected by InjectFault
+	faultDivByZero          = "div_by_zero"         // injected by InjectFault
+)
+
+// faultRecord is one logged fault
+type faultRecord struct {
+	threadID int
+	pc       uint64
+	reason   string
+	message  string
+	address  *uint64 // faulting address for segfaults
+	at       time.Time
+}
+
+// GetFaultLog returns the logged faults, oldest first, as [{ threadID, pc,
+// reason, message, address, timestamp }]. address is null except for
+// segfaults; timestamp is in milliseconds since the Unix epoch.
+func (vo *VMOrchestrator) GetFaultLog(this js.Value, args []js.Value) interface{} {
+	records := vo.faultLog.Load().Snapshot()
+	result := make([]interface{}, len(records))
+	for i, record := range records {
+		var address interface{}
+		if record.address != nil {
+			address = addressToJS(*record.address)
+		}
+		result[i] = map[string]interface{}{
+			"threadID":  record.threadID,
+			"pc":        addressToJS(record.pc),
+			"reason":    record.reason,
+			"message":   record.message,
+			"address":   address,
+			"timestamp": record.at.UnixMilli(),
+		}
+	}
+	return js.ValueOf(result)
+}
+
+// ClearFaultLog discards every logged fault
+func (vo *VMOrchestrator) ClearFaultLog(this js.Value, args []js.Value) interface{} {
+	vo.faultLog.Load().Clear()
+	return js.ValueOf(true)
+}
+
+// SetFaultLogSize sets how many faults are kept. Logged faults are
+// discarded.
+func (vo *VMOrchestrator) SetFaultLogSize(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber || args[0].Int() < 1 {
+		return js.ValueOf(false)
+	}
+
+	vo.faultLog.Store(ring.New[faultRecord](args[0].Int()))
+	return js.ValueOf(true)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"syscall/js"
+	"testing"
+)
+
+// throwingThreads starts n threads whose first instruction throws, waiting
+// until they have all faulted, and returns their IDs in creation order
+func throwingThreads(t *testing.T, vo *VMOrchestrator, n int) []int {
+	t.Helper()
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = createThread(t, vo, 0x40000000)
+		id := ids[i]
+		eventually(t, "the thread to fault", func() bool { return threadStatus(vo, id) == "faulted" })
+	}
+	return ids
+}
+
+// faultLogThreads returns the thread ID of each logged fault, oldest first
+func faultLogThreads(vo *VMOrchestrator) []int {
+	log := call(vo.GetFaultLog)
+	ids := make([]int, log.Length())
+	for i := range ids {
+		ids[i] = log.Index(i).Get("threadID").Int()
+	}
+	return ids
+}
+
+func TestFaultLog(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	bridge := js.Global().Get("Object").New()
+	bridge.Set("executeInstruction", jsFunction("pc", `if (pc === 0x40000000) throw new Error("boom"); return true;`))
+	call(vo.Initialize, bridge)
+	call(vo.SetYieldStrategy, "gosched")
+	requireOK(t, call(vo.Start))
+
+	ids := throwingThreads(t, vo, 2)
+	log := call(vo.GetFaultLog)
+	if got := faultLogThreads(vo); !reflect.DeepEqual(got, ids) {
+		t.Fatalf("fault log holds threads %v, want %v", got, ids)
+	}
+	for i := 0; i < log.Length(); i++ {
+		record := log.Index(i)
+		if got := record.Get("reason").String(); got != faultException {
+			t.Errorf("fault %d reason = %q, want %q", i, got, faultException)
+		}
+		if got := record.Get("message").String(); !strings.Contains(got, "boom") {
+			t.Errorf("fault %d message %q does not mention the exception", i, got)
+		}
+		if got := uint64(record.Get("pc").Float()); got != 0x40000000 {
+			t.Errorf("fault %d pc = %#x, want 0x40000000", i, got)
+		}
+		if !record.Get("address").IsNull() {
+			t.Errorf("fault %d has an address, but only segfaults do", i)
+		}
+	}
+	if first, second := log.Index(0).Get("timestamp").Float(), log.Index(1).Get("timestamp").Float(); first <= 0 || second < first {
+		t.Errorf("timestamps %v, %v are missing or out of order", first, second)
+	}
+
+	call(vo.ClearFaultLog)
+	if got := call(vo.GetFaultLog).Length(); got != 0 {
+		t.Fatalf("fault log holds %d faults after clearFaultLog", got)
+	}
+}
+
+func TestFaultLogSizeCapsEntries(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	bridge := js.Global().Get("Object").New()
+	bridge.Set("executeInstruction", jsFunction("pc", `if (pc === 0x40000000) throw new Error("boom"); return true;`))
+	call(vo.Initialize, bridge)
+	call(vo.SetYieldStrategy, "gosched")
+	if call(vo.SetFaultLogSize, 0).Bool() {
+		t.Fatal("setFaultLogSize(0) succeeded")
+	}
+	if !call(vo.SetFaultLogSize, 3).Bool() {
+		t.Fatal("setFaultLogSize(3) failed")
+	}
+	requireOK(t, call(vo.Start))
+
+	// Only the newest faults are kept
+	ids := throwingThreads(t, vo, 5)
+	if got := faultLogThreads(vo); !reflect.DeepEqual(got, ids[2:]) {
+		t.Fatalf("fault log holds threads %v, want %v", got, ids[2:])
+	}
+}
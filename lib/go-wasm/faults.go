@@ -0,0 +1,198 @@
+// Thread Faults
+// Contains emulator bridge failures to the thread that caused them
+//
+// A JS exception thrown by the emulator surfaces in Go as a panic inside
+// js.Value.Call. Each step through the bridge recovers such panics and
+// faults only the offending thread: it is marked "faulted", keeps its last
+// state for inspection, and the fault callback is fired. Other threads and
+// the worker pool keep running.
+//
+// "faulted" is final short of termination. A faulted thread stays in the
+// thread map so it can be inspected, but it no longer counts as active: it
+// is left out of the thread limit, the thread count and idle detection.
+// Its joiners resolve right away with exit reason "faulted"; terminating it
+// later (KillThread, Stop) replaces the reason as usual.
+
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"syscall/js"
+	"time"
+
+	"github.com/aquifer/vm-orchestrator/internal/engine"
+)
+
+// step executes the next instruction (or batch, see batch.go) of a thread
+// with at most remaining instructions left in its quantum. Returns how many
+// quantum slots were used and false if the thread stopped or faulted.
+func (vo *VMOrchestrator) step(thread *VMThread, pc uint64, remaining int) (used int, ok bool) {
+	defer vo.recoverFault(thread, &ok)
+	if histogram := vo.latency.Load(); histogram != nil {
+		defer histogram.observe(time.Now())
+	}
+
+	if batch := vo.batchLength(thread, remaining); batch > 1 {
+		ran, ok := vo.stepBatch(thread, pc, batch)
+		return max(ran, 1), ok // a zero-length batch still costs a slot
+	}
+	return 1, vo.stepInstruction(thread, pc)
+}
+
+// recoverFault converts a panic raised while stepping a thread into a fault
+// on that thread and clears *ok. Must be deferred directly.
+func (vo *VMOrchestrator) recoverFault(thread *VMThread, ok *bool) {
+	if r := recover(); r != nil {
+		*ok = false
+		vo.fault(thread, faultException, fmt.Sprint(r), nil)
+	}
+}
+
+// faultThread marks a thread faulted with the given message, counts the
+// fault and fires the fault callback. Terminated threads are left alone.
+func (vo *VMOrchestrator) faultThread(thread *VMThread, message string) {
+	vo.fault(thread, faultError, message, nil)
+}
+
+// faultThreadAt is faultThread for faults caused by a memory access, which
+// also record the faulting address
+func (vo *VMOrchestrator) faultThreadAt(thread *VMThread, message string, address *uint64) {
+	vo.fault(thread, faultSegfault, message, address)
+}
+
+// fault faults a thread and logs it with the given fault log reason
+func (vo *VMOrchestrator) fault(thread *VMThread, reason, message string, address *uint64) {
+	// threadMutex makes the status change and the idle check one step, as
+	// in terminateThread, so only one fault or termination sees the last
+	// active thread go
+	vo.threadMutex.Lock()
+	thread.mutex.Lock()
+	if !engine.Active(thread.status) {
+		thread.mutex.Unlock()
+		vo.threadMutex.Unlock()
+		return
+	}
+	vo.setStatus(thread, "faulted")
+	thread.faultMessage = message
+	thread.faultAddress = address
+	thread.exitReason = "faulted"
+	thread.closeDone()
+	pc, lastPC := thread.pc, thread.lastPC
+	thread.mutex.Unlock()
+	idle := vo.threads.Active() == 0 && atomic.LoadInt32(&vo.isRunning) == 1
+	vo.threadMutex.Unlock()
+
+	vo.faultLog.Load().Push(faultRecord{
+		threadID: thread.id,
+		pc:       pc,
+		reason:   reason,
+		message:  message,
+		address:  address,
+		at:       time.Now(),
+	})
+
+	vo.statsMutex.Lock()
+	vo.stats.Faults++
+	vo.statsMutex.Unlock()
+
+	vo.logf(logError, "thread %d faulted: %s", thread.id, message)
+	vo.freezeAfterFault(thread.id)
+	vo.fireFault(thread.id, message, lastPC)
+	if idle {
+		vo.fireIdle()
+	}
+}
+
+// restoreFault gives a thread restored in the "faulted" state the exit its
+// fault gave it originally, waking joiners at once
+func (thread *VMThread) restoreFault() {
+	if thread.status == "faulted" {
+		thread.exitReason = "faulted"
+		thread.closeDone()
+	}
+}
+
+// OnFault registers a callback invoked as callback(threadID, message, lastPC)
+// when the emulator bridge fails while executing a thread; lastPC is the
+// faulting instruction's address. Passing null clears it.
+func (vo *VMOrchestrator) OnFault(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	vo.faultCallback = args[0]
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// fireFault queues a call to the registered fault callback
+func (vo *VMOrchestrator) fireFault(threadID int, message string, lastPC uint64) {
+	vo.postEvent(eventCritical, "", func() {
+		vo.callbackMutex.RLock()
+		callback := vo.faultCallback
+		vo.callbackMutex.RUnlock()
+
+		if callback.Type() == js.TypeFunction {
+			callback.Invoke(threadID, message, addressToJS(lastPC))
+		}
+	})
+}
+
+// InjectFault faults a thread on purpose, for testing how the host handles
+// VM errors. The thread goes through the same path as a natural fault of
+// that kind: it is marked "faulted", the fault is counted, logged under the
+// fault type and OnFault fires. A stack overflow also fires
+// OnStackOverflow, and a segfault records its fault address (the optional
+// address argument, defaulting to the PC) and reports the optional access
+// ("read", "write" or "execute", default "injected").
+// Arguments: threadID, faultType ("segfault", "illegal_instruction",
+// "div_by_zero" or "stack_overflow"), optional address, optional access
+func (vo *VMOrchestrator) InjectFault(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[1].Type() != js.TypeString {
+		return vo.fail(false, errInvalidArgument, "injectFault requires a thread ID and a fault type")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.RLock()
+	status, pc, depth := thread.status, thread.pc, len(thread.stack)
+	thread.mutex.RUnlock()
+	if !engine.Active(status) {
+		return vo.fail(false, errInvalidState, "thread %d is %s", threadID, status)
+	}
+
+	switch faultType := args[1].String(); faultType {
+	case faultSegfault:
+		address := pc
+		if len(args) > 2 && !args[2].IsUndefined() {
+			var ok bool
+			if address, ok = jsToAddress(args[2]); !ok {
+				return vo.fail(false, errInvalidArgument, "invalid fault address")
+			}
+		}
+		access := "injected"
+		if len(args) > 3 && !args[3].IsUndefined() {
+			if args[3].Type() != js.TypeString || accessPerms[args[3].String()] == 0 {
+				return vo.fail(false, errInvalidArgument, "access must be \"read\", \"write\" or \"execute\"")
+			}
+			access = args[3].String()
+		}
+		vo.segfault(thread, address, access)
+	case faultIllegalInstruction:
+		vo.fault(thread, faultIllegalInstruction, fmt.Sprintf("illegal instruction at %#x", pc), nil)
+	case faultDivByZero:
+		vo.fault(thread, faultDivByZero, fmt.Sprintf("division by zero at %#x", pc), nil)
+	case faultStackOverflow:
+		vo.stackOverflow(thread, depth)
+	default:
+		return vo.fail(false, errInvalidArgument, "unknown fault type %q", faultType)
+	}
+	return vo.succeed(true, nil)
+}
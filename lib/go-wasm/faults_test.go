@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// jsFunction compiles a JS function, so bridges can throw real JS exceptions
+func jsFunction(params ...string) js.Value {
+	args := make([]interface{}, len(params))
+	for i, param := range params {
+		args[i] = param
+	}
+	return js.Global().Get("Function").New(args...)
+}
+
+func TestThrowingBridgeFaultsOnlyItsThread(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	bridge := js.Global().Get("Object").New()
+	bridge.Set("executeInstruction", jsFunction("pc", `if (pc === 0x40000000) throw new Error("boom"); return true;`))
+	call(vo.Initialize, bridge)
+	// A JS bridge never re-enters Go, so the worker must yield for the test
+	// goroutine to run
+	call(vo.SetYieldStrategy, "gosched")
+
+	faults := make(chan string, 1)
+	call(vo.OnFault, newCallback(t, func(args []js.Value) {
+		faults <- args[1].String()
+	}))
+
+	requireOK(t, call(vo.Start))
+	bad := createThread(t, vo, 0x40000000)
+	eventually(t, "the thread to fault", func() bool { return threadStatus(vo, bad) == "faulted" })
+
+	var message string
+	eventually(t, "the fault callback", func() bool {
+		select {
+		case message = <-faults:
+			return true
+		default:
+			return false
+		}
+	})
+	if !strings.Contains(message, "boom") {
+		t.Errorf("fault message %q does not mention the exception", message)
+	}
+	if got := stat(vo, "faults"); got != 1 {
+		t.Errorf("faults = %v, want 1", got)
+	}
+	thread := vo.getThread(bad)
+	thread.mutex.RLock()
+	recorded := thread.faultMessage
+	thread.mutex.RUnlock()
+	if !strings.Contains(recorded, "boom") {
+		t.Errorf("thread fault message = %q", recorded)
+	}
+
+	// The VM and the main thread carry on
+	before := threadPC(t, vo, 1)
+	eventually(t, "the main thread to keep running", func() bool { return threadPC(t, vo, 1) > before })
+	if threadStatus(vo, 1) != "running" {
+		t.Errorf("main thread is %q, want running", threadStatus(vo, 1))
+	}
+	requireOK(t, call(vo.Stop))
+}
+
+func TestInjectFault(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []interface{} // after the thread ID
+		reason   string        // fault log reason
+		message  string
+		address  interface{} // fault log address, nil for none
+		overflow bool        // OnStackOverflow fires
+	}{
+		{"segfault", []interface{}{"segfault"}, faultSegfault, "segmentation fault: injected at 0x1000", 0x1000, false},
+		{"segfault with access", []interface{}{"segfault", 0x8000, "write"}, faultSegfault, "segmentation fault: write at 0x8000", 0x8000, false},
+		{"illegal instruction", []interface{}{"illegal_instruction"}, faultIllegalInstruction, "illegal instruction at 0x1000", nil, false},
+		{"division by zero", []interface{}{"div_by_zero"}, faultDivByZero, "division by zero at 0x1000", nil, false},
+		{"stack overflow", []interface{}{"stack_overflow"}, faultStackOverflow, "stack overflow at depth 2", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vo := newTestOrchestrator(t)
+			faults := faultMessages(t, vo)
+			overflows := make(chan [2]int, 1)
+			call(vo.OnStackOverflow, newCallback(t, func(args []js.Value) {
+				overflows <- [2]int{args[0].Int(), args[1].Int()}
+			}))
+			id := createThread(t, vo, 0x1000, 1, "paused")
+			requireOK(t, call(vo.PushStack, id, 1))
+			requireOK(t, call(vo.PushStack, id, 2))
+
+			requireOK(t, call(vo.InjectFault, append([]interface{}{id}, test.args...)...))
+			if got := threadStatus(vo, id); got != "faulted" {
+				t.Errorf("status = %q, want faulted", got)
+			}
+			requireFault(t, faults, id, test.message)
+
+			if test.overflow {
+				select {
+				case got := <-overflows:
+					if got != [2]int{id, 2} {
+						t.Errorf("onStackOverflow got thread %d depth %d, want thread %d depth 2", got[0], got[1], id)
+					}
+				case <-time.After(testTimeout):
+					t.Fatal("timed out waiting for onStackOverflow")
+				}
+			} else if len(overflows) != 0 {
+				t.Error("onStackOverflow fired for a fault that is not a stack overflow")
+			}
+
+			log := call(vo.GetFaultLog)
+			if log.Length() != 1 {
+				t.Fatalf("fault log holds %d faults, want 1", log.Length())
+			}
+			record := log.Index(0)
+			if got := record.Get("reason").String(); got != test.reason {
+				t.Errorf("fault log reason = %q, want %q", got, test.reason)
+			}
+			if got, want := record.Get("address"), js.ValueOf(test.address); !got.Equal(want) {
+				t.Errorf("fault log address = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestInjectFaultRejectsBadArguments(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	id := createThread(t, vo, 0x1000, 1, "paused")
+
+	requireError(t, call(vo.InjectFault, id, "cosmic_ray"), errInvalidArgument)
+	requireError(t, call(vo.InjectFault, id, "segfault", 0x8000, "jump"), errInvalidArgument)
+	requireError(t, call(vo.InjectFault, 99, "segfault"), errUnknownThread)
+	if got := threadStatus(vo, id); got != "paused" {
+		t.Fatalf("rejected injections left the thread %q", got)
+	}
+
+	requireOK(t, call(vo.InjectFault, id, "div_by_zero"))
+	requireError(t, call(vo.InjectFault, id, "div_by_zero"), errInvalidState)
+}
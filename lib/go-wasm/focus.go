@@ -0,0 +1,90 @@
+// Thread Focus
+// Single-thread debugging by holding every other thread suspended
+//
+// FocusThread suspends every running thread except one and marks them as
+// held by the focus. While focused, any other thread that would start
+// running is held the same way instead: threads that are created, started,
+// resumed, switched to "running" or woken from a wait. Unfocus resumes
+// exactly the held threads, so threads suspended with SuspendThread and not
+// resumed since stay suspended.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+)
+
+// FocusThread holds every thread but threadID suspended until Unfocus.
+// Focusing another thread releases the previously focused one if it was
+// held. Returns { count }, the number of threads newly held.
+func (vo *VMOrchestrator) FocusThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(-1, errInvalidArgument, "focusThread requires a thread ID")
+	}
+
+	threadID := args[0].Int()
+	if vo.getThread(threadID) == nil {
+		return vo.unknownThread(-1, threadID)
+	}
+
+	// Holding threadMutex for writing keeps addThreads from publishing a
+	// thread between the store and the sweep
+	vo.threadMutex.Lock()
+	atomic.StoreInt32(&vo.focused, int32(threadID))
+	count := 0
+	var released []*VMThread
+	for id, thread := range vo.threads {
+		thread.mutex.Lock()
+		switch {
+		case id == threadID && thread.focusHeld && thread.status == "suspended":
+			vo.setStatus(thread, "running")
+			released = append(released, thread)
+		case id != threadID && thread.status == "running":
+			vo.setStatus(thread, "suspended")
+			thread.focusHeld = true
+			count++
+		}
+		thread.mutex.Unlock()
+	}
+	vo.threadMutex.Unlock()
+
+	for _, thread := range released {
+		vo.enqueueThread(thread)
+	}
+	return vo.succeed(count, map[string]interface{}{"count": count})
+}
+
+// Unfocus ends FocusThread and resumes every thread it held. Returns
+// { count }, the number of threads resumed.
+func (vo *VMOrchestrator) Unfocus(this js.Value, args []js.Value) interface{} {
+	vo.threadMutex.Lock()
+	atomic.StoreInt32(&vo.focused, 0)
+	var resumed []*VMThread
+	for _, thread := range vo.threads {
+		thread.mutex.Lock()
+		if thread.focusHeld && thread.status == "suspended" {
+			vo.setStatus(thread, "running")
+			resumed = append(resumed, thread)
+		}
+		thread.mutex.Unlock()
+	}
+	vo.threadMutex.Unlock()
+
+	for _, thread := range resumed {
+		vo.enqueueThread(thread)
+	}
+	return vo.succeed(len(resumed), map[string]interface{}{"count": len(resumed)})
+}
+
+// holdForFocus reports whether a thread about to run must be held
+// suspended because another thread is focused, and marks it held if so.
+// Caller must hold thread.mutex.
+func (vo *VMOrchestrator) holdForFocus(thread *VMThread) bool {
+	focused := int(atomic.LoadInt32(&vo.focused))
+	if focused == 0 || focused == thread.id {
+		return false
+	}
+	thread.focusHeld = true
+	return true
+}
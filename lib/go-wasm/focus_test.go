@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// pcsOf returns the PCs of several threads, in order
+func pcsOf(t *testing.T, vo *VMOrchestrator, ids []int) []uint64 {
+	t.Helper()
+	pcs := make([]uint64, len(ids))
+	for i, id := range ids {
+		pcs[i] = threadPC(t, vo, id)
+	}
+	return pcs
+}
+
+func TestOnlyFocusedThreadAdvances(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+	other, focused := createThread(t, vo, 0x20000), createThread(t, vo, 0x30000)
+	held := []int{1, other}
+
+	if got := requireOK(t, call(vo.FocusThread, focused)).Get("count").Int(); got != 2 {
+		t.Fatalf("focusThread held %d threads, want 2", got)
+	}
+	late := createThread(t, vo, 0x40000)
+	if got := threadStatus(vo, late); got != "suspended" {
+		t.Errorf("thread created while focused is %q, want suspended", got)
+	}
+	held = append(held, late)
+	for _, id := range held {
+		if got := threadStatus(vo, id); got != "suspended" {
+			t.Errorf("thread %d is %q while another is focused, want suspended", id, got)
+		}
+	}
+
+	// Let quanta already in flight finish before comparing PCs
+	time.Sleep(20 * time.Millisecond)
+	before, focusedBefore := pcsOf(t, vo, held), threadPC(t, vo, focused)
+	time.Sleep(50 * time.Millisecond)
+	if after := pcsOf(t, vo, held); !reflect.DeepEqual(before, after) {
+		t.Errorf("held threads moved from %x to %x while focused", before, after)
+	}
+	if threadPC(t, vo, focused) == focusedBefore {
+		t.Error("the focused thread did not advance")
+	}
+
+	if got := requireOK(t, call(vo.Unfocus)).Get("count").Int(); got != len(held) {
+		t.Fatalf("unfocus resumed %d threads, want %d", got, len(held))
+	}
+	eventually(t, "the held threads to run again", func() bool {
+		after := pcsOf(t, vo, held)
+		for i := range after {
+			if after[i] == before[i] {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestUnfocusLeavesExplicitlySuspendedThreads(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+	suspended, focused := createThread(t, vo, 0x20000), createThread(t, vo, 0x30000)
+	requireOK(t, call(vo.SuspendThread, suspended))
+
+	requireOK(t, call(vo.FocusThread, focused))
+	requireOK(t, call(vo.Unfocus))
+	if got := threadStatus(vo, suspended); got != "suspended" {
+		t.Errorf("unfocus resumed a thread suspended before the focus: %q", got)
+	}
+	if got := threadStatus(vo, 1); got != "running" {
+		t.Errorf("held main thread is %q after unfocus, want running", got)
+	}
+	requireError(t, call(vo.FocusThread, 99), errUnknownThread)
+}
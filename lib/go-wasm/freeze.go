@@ -0,0 +1,62 @@
+// Freeze on Fault
+// Stops the whole VM at the moment any thread faults
+//
+// With SetFreezeOnFault(true), a thread fault pauses the VM (see pause.go)
+// right after the thread is marked "faulted", before the other workers
+// reach their next instruction boundary, so every thread's state can be
+// inspected as it was when the failure happened. OnFreeze is fired with the
+// faulting thread's ID; the VM stays frozen until Resume. A fault while the
+// VM is already paused or not running does not fire OnFreeze.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+)
+
+// SetFreezeOnFault enables or disables pausing the VM when a thread faults
+func (vo *VMOrchestrator) SetFreezeOnFault(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeBoolean {
+		return js.ValueOf(false)
+	}
+
+	var enabled int32
+	if args[0].Bool() {
+		enabled = 1
+	}
+	atomic.StoreInt32(&vo.freezeOnFault, enabled)
+	return js.ValueOf(true)
+}
+
+// OnFreeze registers a callback invoked as callback(threadID) when a fault
+// freezes the VM. Passing null clears it.
+func (vo *VMOrchestrator) OnFreeze(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	vo.freezeCallback = args[0]
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// freezeAfterFault pauses the VM after a thread fault if freezing is enabled
+func (vo *VMOrchestrator) freezeAfterFault(threadID int) {
+	if atomic.LoadInt32(&vo.freezeOnFault) != 1 || vo.pause() != "" {
+		return
+	}
+
+	vo.logf(logInfo, "VM frozen by fault in thread %d", threadID)
+	vo.postEvent(eventCritical, "", func() {
+		vo.callbackMutex.RLock()
+		callback := vo.freezeCallback
+		vo.callbackMutex.RUnlock()
+
+		if callback.Type() == js.TypeFunction {
+			callback.Invoke(threadID)
+		}
+	})
+}
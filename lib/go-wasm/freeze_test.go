@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// faultAtBridge returns a bridge on which the instruction at pc faults
+func faultAtBridge(t *testing.T, pc uint64) js.Value {
+	return newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func(args []js.Value) interface{} {
+			if uint64(args[0].Float()) == pc {
+				return map[string]interface{}{"status": statusFault}
+			}
+			return true
+		},
+	})
+}
+
+func TestFaultFreezesVM(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, faultAtBridge(t, 0x40000000+400))
+	call(vo.SetYieldStrategy, "gosched")
+	if !call(vo.SetFreezeOnFault, true).Bool() {
+		t.Fatal("setFreezeOnFault failed")
+	}
+	frozen := make(chan int, 1)
+	call(vo.OnFreeze, newCallback(t, func(args []js.Value) { frozen <- args[0].Int() }))
+
+	requireOK(t, call(vo.Start))
+	bystander := createThread(t, vo, 0x50000000)
+	bad := createThread(t, vo, 0x40000000)
+
+	select {
+	case id := <-frozen:
+		if id != bad {
+			t.Fatalf("freeze reported thread %d, want %d", id, bad)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the freeze")
+	}
+	if !call(vo.IsPaused).Bool() {
+		t.Fatal("VM is not paused after the fault")
+	}
+	if got := threadStatus(vo, bad); got != "faulted" {
+		t.Errorf("faulting thread is %q", got)
+	}
+
+	// Instructions in flight at the fault finish, then nothing moves
+	var state map[int]threadState
+	eventually(t, "in-flight instructions to finish", func() bool {
+		state = captureThreads(vo)
+		time.Sleep(5 * time.Millisecond)
+		return reflect.DeepEqual(state, captureThreads(vo))
+	})
+	time.Sleep(30 * time.Millisecond)
+	if after := captureThreads(vo); !reflect.DeepEqual(state, after) {
+		t.Fatal("threads advanced while the VM was frozen")
+	}
+	for id, start := range map[int]uint64{1: 0x1000, bystander: 0x50000000} {
+		if state[id].pc == start {
+			t.Errorf("thread %d was frozen before it ran", id)
+		}
+	}
+
+	requireOK(t, call(vo.Resume))
+	eventually(t, "the other threads to resume", func() bool {
+		return threadPC(t, vo, bystander) != state[bystander].pc && threadPC(t, vo, 1) != state[1].pc
+	})
+	if got := threadStatus(vo, bad); got != "faulted" {
+		t.Errorf("faulted thread is %q after Resume", got)
+	}
+}
+
+func TestFaultWithoutFreeze(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, faultAtBridge(t, 0x40000000))
+	call(vo.SetYieldStrategy, "gosched")
+	call(vo.SetFreezeOnFault, true)
+	call(vo.SetFreezeOnFault, false)
+
+	requireOK(t, call(vo.Start))
+	bad := createThread(t, vo, 0x40000000)
+	eventually(t, "the thread to fault", func() bool { return threadStatus(vo, bad) == "faulted" })
+	if call(vo.IsPaused).Bool() {
+		t.Error("a fault paused the VM with freezing disabled")
+	}
+}
@@ -0,0 +1,137 @@
+// Thread Groups
+// Bulk suspend, resume and terminate for pools of related threads
+//
+// Mirrors Android thread groups: CreateThreadGroup allocates a group ID and
+// AddThreadToGroup moves a thread into it (a thread belongs to at most one
+// group). Group operations act on the members present when they start;
+// groupMutex serializes them against membership changes, so a thread added
+// concurrently is either fully included or not affected at all.
+
+package main
+
+import (
+	"sort"
+	"syscall/js"
+)
+
+// CreateThreadGroup allocates an empty thread group and returns its ID
+func (vo *VMOrchestrator) CreateThreadGroup(this js.Value, args []js.Value) interface{} {
+	vo.groupMutex.Lock()
+	defer vo.groupMutex.Unlock()
+
+	vo.groupCounter++
+	vo.threadGroups[vo.groupCounter] = struct{}{}
+	return js.ValueOf(vo.groupCounter)
+}
+
+// AddThreadToGroup moves a thread into a group, leaving any previous group
+// Arguments: groupID, threadID
+func (vo *VMOrchestrator) AddThreadToGroup(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(false, errInvalidArgument, "addThreadToGroup requires a group ID and a thread ID")
+	}
+
+	groupID, threadID := args[0].Int(), args[1].Int()
+
+	vo.groupMutex.Lock()
+	defer vo.groupMutex.Unlock()
+
+	if _, ok := vo.threadGroups[groupID]; !ok {
+		return vo.fail(false, errUnknownGroup, "thread group %d does not exist", groupID)
+	}
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.Lock()
+	thread.groupID = groupID
+	thread.mutex.Unlock()
+	return vo.succeed(true, nil)
+}
+
+// SuspendGroup suspends every running member of a group and returns how
+// many were suspended
+func (vo *VMOrchestrator) SuspendGroup(this js.Value, args []js.Value) interface{} {
+	return vo.applyToGroup(args, "suspendGroup", func(members []*VMThread) int {
+		count := 0
+		for _, thread := range members {
+			thread.mutex.Lock()
+			if thread.status == "running" {
+				vo.setStatus(thread, "suspended")
+				count++
+			}
+			thread.mutex.Unlock()
+		}
+		return count
+	})
+}
+
+// ResumeGroup resumes every suspended member of a group and returns how
+// many were resumed
+func (vo *VMOrchestrator) ResumeGroup(this js.Value, args []js.Value) interface{} {
+	return vo.applyToGroup(args, "resumeGroup", func(members []*VMThread) int {
+		var resumed []*VMThread
+		for _, thread := range members {
+			thread.mutex.Lock()
+			if thread.status == "suspended" {
+				vo.setStatus(thread, "running")
+				resumed = append(resumed, thread)
+			}
+			thread.mutex.Unlock()
+		}
+		for _, thread := range resumed {
+			vo.enqueueThread(thread)
+		}
+		return len(resumed)
+	})
+}
+
+// TerminateGroup kills every member of a group as KillThread does and
+// returns how many were terminated. The group itself remains and can be
+// reused.
+func (vo *VMOrchestrator) TerminateGroup(this js.Value, args []js.Value) interface{} {
+	return vo.applyToGroup(args, "terminateGroup", func(members []*VMThread) int {
+		for _, thread := range members {
+			vo.killThread(thread)
+		}
+		return len(members)
+	})
+}
+
+// applyToGroup runs op on the current members of the group named by
+// args[0], in ID order, while holding groupMutex, and returns { count }
+func (vo *VMOrchestrator) applyToGroup(args []js.Value, method string, op func(members []*VMThread) int) interface{} {
+	if len(args) < 1 {
+		return vo.fail(-1, errInvalidArgument, "%s requires a group ID", method)
+	}
+
+	groupID := args[0].Int()
+
+	vo.groupMutex.Lock()
+	defer vo.groupMutex.Unlock()
+
+	if _, ok := vo.threadGroups[groupID]; !ok {
+		return vo.fail(-1, errUnknownGroup, "thread group %d does not exist", groupID)
+	}
+
+	count := op(vo.groupMembers(groupID))
+	return vo.succeed(count, map[string]interface{}{"count": count})
+}
+
+// groupMembers returns the active threads in a group ordered by ID
+func (vo *VMOrchestrator) groupMembers(groupID int) []*VMThread {
+	vo.threadMutex.RLock()
+	var members []*VMThread
+	for _, thread := range vo.threads {
+		thread.mutex.RLock()
+		if thread.groupID == groupID {
+			members = append(members, thread)
+		}
+		thread.mutex.RUnlock()
+	}
+	vo.threadMutex.RUnlock()
+
+	sort.Slice(members, func(i, j int) bool { return members[i].id < members[j].id })
+	return members
+}
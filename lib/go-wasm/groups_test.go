@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSuspendGroupOfThree(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+
+	group := call(vo.CreateThreadGroup).Int()
+	members := []int{createThread(t, vo, 0x10000), createThread(t, vo, 0x20000), createThread(t, vo, 0x30000)}
+	for _, id := range members {
+		requireOK(t, call(vo.AddThreadToGroup, group, id))
+	}
+
+	if got := requireOK(t, call(vo.SuspendGroup, group)).Get("count").Int(); got != 3 {
+		t.Fatalf("suspendGroup suspended %d threads, want 3", got)
+	}
+	for _, id := range members {
+		if got := threadStatus(vo, id); got != "suspended" {
+			t.Errorf("member %d is %q, want suspended", id, got)
+		}
+	}
+	if got := threadStatus(vo, 1); got != "running" {
+		t.Errorf("the main thread outside the group is %q, want running", got)
+	}
+
+	if got := requireOK(t, call(vo.ResumeGroup, group)).Get("count").Int(); got != 3 {
+		t.Fatalf("resumeGroup resumed %d threads, want 3", got)
+	}
+	if got := requireOK(t, call(vo.TerminateGroup, group)).Get("count").Int(); got != 3 {
+		t.Fatalf("terminateGroup terminated %d threads, want 3", got)
+	}
+	for _, id := range members {
+		if got := threadStatus(vo, id); got != "terminated" && got != "" {
+			t.Errorf("member %d is %q after terminateGroup", id, got)
+		}
+	}
+	requireError(t, call(vo.SuspendGroup, group+1), errUnknownGroup)
+}
@@ -0,0 +1,116 @@
+// Guest Clock
+// A monotonic tick counter the bridge or host can feed to guest code
+//
+// GetGuestTicks advances at the rate set with SetTickRate (1000 Hz by
+// default). Without a guest clock frequency, guest time is the VM's
+// execution time, which stops while the VM is paused or stopped. With one,
+// guest time is cyclesExecuted divided by that frequency, so ticks follow
+// emulated work rather than the host's speed. Deterministic mode always
+// derives ticks from cycles (at defaultGuestCycleHz if no frequency is
+// set), so the same seed and program see the same ticks on every run.
+//
+// Changing the rate keeps the ticks already counted, and ticks never go
+// backwards: after Restore rolls the cycle count back they hold until
+// execution passes the point of the last rate change again. Reset starts
+// the count from zero.
+
+package main
+
+import (
+	"math"
+	"syscall/js"
+	"time"
+
+	"github.com/aquifer/vm-orchestrator/internal/engine"
+)
+
+const (
+	defaultTickRate     = 1000      // ticks per second of guest time
+	defaultGuestCycleHz = 1_000_000 // cycles per second of guest time in deterministic mode
+)
+
+// guestClock converts executed cycles or execution time into ticks
+type guestClock struct {
+	hz      float64 // ticks per second
+	cycleHz float64 // cycles per second of guest time, 0 = execution time
+
+	// Ticks counted before the last rate change, and the cycle count and
+	// execution time at that point
+	base       float64
+	baseCycles uint64
+	baseTime   time.Duration
+}
+
+// GetGuestTicks returns the current guest tick count
+func (vo *VMOrchestrator) GetGuestTicks(this js.Value, args []js.Value) interface{} {
+	deterministic := vo.isDeterministic()
+
+	vo.statsMutex.Lock()
+	defer vo.statsMutex.Unlock()
+
+	vo.stats.UpdateExecutionTime(time.Now())
+	return js.ValueOf(math.Floor(vo.guestClock.ticks(vo.stats, deterministic)))
+}
+
+// SetTickRate sets how many ticks make up one second of guest time.
+// Arguments: hz (number > 0), clockHz (optional, emulated cycles per second;
+// 0 or omitted measures guest time by execution time)
+func (vo *VMOrchestrator) SetTickRate(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber {
+		return js.ValueOf(false)
+	}
+	hz := args[0].Float()
+	if !(hz > 0) || math.IsInf(hz, 0) {
+		return js.ValueOf(false)
+	}
+
+	var cycleHz float64
+	if len(args) > 1 && !args[1].IsUndefined() && !args[1].IsNull() {
+		if args[1].Type() != js.TypeNumber {
+			return js.ValueOf(false)
+		}
+		cycleHz = args[1].Float()
+		if !(cycleHz >= 0) || math.IsInf(cycleHz, 0) {
+			return js.ValueOf(false)
+		}
+	}
+
+	deterministic := vo.isDeterministic()
+
+	vo.statsMutex.Lock()
+	defer vo.statsMutex.Unlock()
+
+	vo.stats.UpdateExecutionTime(time.Now())
+	vo.guestClock = guestClock{
+		hz:         hz,
+		cycleHz:    cycleHz,
+		base:       vo.guestClock.ticks(vo.stats, deterministic),
+		baseCycles: vo.stats.CyclesExecuted,
+		baseTime:   vo.stats.ExecutionTime,
+	}
+	return js.ValueOf(true)
+}
+
+// isDeterministic reports whether deterministic scheduling is on
+func (vo *VMOrchestrator) isDeterministic() bool {
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+	return vo.deterministic
+}
+
+// ticks returns the tick count for the given stats, whose execution time
+// must be up to date
+func (clock *guestClock) ticks(stats *engine.Stats, deterministic bool) float64 {
+	var elapsed float64 // seconds of guest time since the last rate change
+	if cycleHz := clock.cycleHz; cycleHz > 0 || deterministic {
+		if cycleHz == 0 {
+			cycleHz = defaultGuestCycleHz
+		}
+		if stats.CyclesExecuted > clock.baseCycles {
+			elapsed = float64(stats.CyclesExecuted-clock.baseCycles) / cycleHz
+		}
+	} else if stats.ExecutionTime > clock.baseTime {
+		elapsed = (stats.ExecutionTime - clock.baseTime).Seconds()
+	}
+	return clock.base + elapsed*clock.hz
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+func TestGuestTicksMonotonic(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+
+	first := call(vo.GetGuestTicks).Float()
+	last := first
+	for i := 0; i < 50; i++ {
+		time.Sleep(time.Millisecond)
+		ticks := call(vo.GetGuestTicks).Float()
+		if ticks < last {
+			t.Fatalf("ticks went back from %v to %v", last, ticks)
+		}
+		last = ticks
+	}
+	if last <= first {
+		t.Fatalf("ticks stayed at %v while running", first)
+	}
+
+	// Raising the rate keeps the ticks already counted
+	if !call(vo.SetTickRate, 1_000_000).Bool() {
+		t.Fatal("setTickRate failed")
+	}
+	if ticks := call(vo.GetGuestTicks).Float(); ticks < last {
+		t.Errorf("changing the rate moved ticks back from %v to %v", last, ticks)
+	}
+
+	requireOK(t, call(vo.Pause))
+	paused := call(vo.GetGuestTicks).Float()
+	time.Sleep(10 * time.Millisecond)
+	if ticks := call(vo.GetGuestTicks).Float(); ticks != paused {
+		t.Errorf("ticks moved from %v to %v while paused", paused, ticks)
+	}
+}
+
+// guestTickTrace runs three threads under seed, each instruction costing 700
+// cycles and reading the guest clock, and returns the ticks each read saw
+func guestTickTrace(t *testing.T, seed int) []float64 {
+	t.Helper()
+	vo := newTestOrchestrator(t)
+	var ticks []float64
+	call(vo.Initialize, newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func([]js.Value) interface{} {
+			ticks = append(ticks, call(vo.GetGuestTicks).Float())
+			return map[string]interface{}{"ok": true, "cycles": 700}
+		},
+	}))
+	call(vo.SetDeterministic, seed)
+	call(vo.SetTickMode, true)
+	for i := 1; i <= 2; i++ {
+		createThread(t, vo, uint64(0x10000*i), i)
+	}
+	requireOK(t, call(vo.Start))
+	for id := 1; id <= 3; id++ {
+		requireOK(t, call(vo.SetThreadInstructionLimit, id, 200))
+	}
+	call(vo.Tick, 1000)
+	eventually(t, "every thread to reach its limit", func() bool { return stat(vo, "activeThreads") == 0 })
+
+	// Deterministic ticks count cycles at defaultGuestCycleHz
+	want := math.Floor(3 * 200 * 700 * defaultTickRate / defaultGuestCycleHz)
+	if got := call(vo.GetGuestTicks).Float(); got != want {
+		t.Errorf("ticks after %d cycles = %v, want %v", 3*200*700, got, want)
+	}
+	return ticks
+}
+
+func TestGuestTicksDeterministic(t *testing.T) {
+	first := guestTickTrace(t, 42)
+	for i := 1; i < len(first); i++ {
+		if first[i] < first[i-1] {
+			t.Fatalf("read %d saw ticks go back from %v to %v", i, first[i-1], first[i])
+		}
+	}
+	time.Sleep(20 * time.Millisecond) // wall-clock time must not matter
+	if second := guestTickTrace(t, 42); !reflect.DeepEqual(first, second) {
+		t.Error("two runs with seed 42 saw different guest clocks")
+	}
+}
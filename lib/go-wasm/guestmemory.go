@@ -0,0 +1,112 @@
+// Guest Memory Access
+// Reads and writes guest memory for debuggers and memory inspectors
+//
+// ReadMemory and WriteMemory forward to the bridge's readMemory(address,
+// length) -> Uint8Array and writeMemory(address, bytes) methods after
+// checking the whole range against the address space size and the memory
+// map: every byte must lie in a region with read (or write) permission.
+// Rejected accesses return an error result and never reach the bridge, and
+// a bridge method that throws fails the call with bridge_exception.
+// These are host accesses, so they do not fault any thread.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// maxMemoryTransfer bounds a single ReadMemory or WriteMemory call
+const maxMemoryTransfer = 16 << 20
+
+// ReadMemory reads length bytes of guest memory starting at address.
+// Returns { bytes } with a Uint8Array, or null in legacy mode on failure.
+// Arguments: address, length
+func (vo *VMOrchestrator) ReadMemory(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(nil, errInvalidArgument, "readMemory requires an address and a length")
+	}
+
+	address, ok := jsToAddress(args[0])
+	if !ok {
+		return vo.fail(nil, errInvalidArgument, "invalid address")
+	}
+	if args[1].Type() != js.TypeNumber || args[1].Int() < 1 || args[1].Int() > maxMemoryTransfer {
+		return vo.fail(nil, errInvalidArgument, "length must be between 1 and %d", maxMemoryTransfer)
+	}
+	length := args[1].Int()
+
+	if result := vo.checkMemoryRange(nil, address, uint64(length), permRead, "read"); result != nil {
+		return result
+	}
+
+	emulator := vo.emulator()
+	if !emulator.Truthy() || emulator.Get("readMemory").Type() != js.TypeFunction {
+		return vo.fail(nil, errUnsupported, "the emulator bridge does not implement readMemory")
+	}
+
+	bytes, err := callJS(func() js.Value { return emulator.Call("readMemory", addressToJS(address), length) })
+	if err != nil {
+		return vo.fail(nil, errBridgeException, "readMemory failed: %v", err)
+	}
+	if !bytes.InstanceOf(js.Global().Get("Uint8Array")) || bytes.Length() != length {
+		return vo.fail(nil, errInvalidState, "readMemory did not return %d bytes", length)
+	}
+	return vo.succeed(bytes, map[string]interface{}{"bytes": bytes})
+}
+
+// WriteMemory writes bytes (a Uint8Array or an array of numbers) to guest
+// memory starting at address
+// Arguments: address, bytes
+func (vo *VMOrchestrator) WriteMemory(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[1].Type() != js.TypeObject {
+		return vo.fail(false, errInvalidArgument, "writeMemory requires an address and bytes")
+	}
+
+	address, ok := jsToAddress(args[0])
+	if !ok {
+		return vo.fail(false, errInvalidArgument, "invalid address")
+	}
+
+	uint8Array := js.Global().Get("Uint8Array")
+	bytes := args[1]
+	if !bytes.InstanceOf(uint8Array) {
+		var err error
+		if bytes, err = callJS(func() js.Value { return uint8Array.Call("from", bytes) }); err != nil {
+			return vo.fail(false, errInvalidArgument, "bytes must be a Uint8Array or an array of numbers")
+		}
+	}
+	length := bytes.Length()
+	if length < 1 || length > maxMemoryTransfer {
+		return vo.fail(false, errInvalidArgument, "length must be between 1 and %d", maxMemoryTransfer)
+	}
+
+	if result := vo.checkMemoryRange(false, address, uint64(length), permWrite, "write"); result != nil {
+		return result
+	}
+
+	emulator := vo.emulator()
+	if !emulator.Truthy() || emulator.Get("writeMemory").Type() != js.TypeFunction {
+		return vo.fail(false, errUnsupported, "the emulator bridge does not implement writeMemory")
+	}
+
+	if _, err := callJS(func() js.Value { return emulator.Call("writeMemory", addressToJS(address), bytes) }); err != nil {
+		return vo.fail(false, errBridgeException, "writeMemory failed: %v", err)
+	}
+	return vo.succeed(true, nil)
+}
+
+// checkMemoryRange validates a host access of length bytes at address.
+// Returns the failure result to hand back, or nil if the access may go ahead.
+func (vo *VMOrchestrator) checkMemoryRange(legacy interface{}, address, length uint64, perm int, access string) interface{} {
+	last := address + (length - 1)
+	if last < address {
+		return vo.fail(legacy, errOutOfRange, "access overflows the address space")
+	}
+	if space := vo.addressSpace.Load(); space != nil && space.size != 0 && last >= space.size {
+		return vo.fail(legacy, errOutOfRange, "%#x is outside the %#x-byte address space", last, space.size)
+	}
+	if denied, ok := vo.rangeAllowed(address, last, perm); !ok {
+		return vo.fail(legacy, errAccessDenied, "%s denied at %#x", access, denied)
+	}
+	return nil
+}
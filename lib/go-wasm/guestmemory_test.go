@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"syscall/js"
+	"testing"
+)
+
+// memoryBridge is a bridge over a sparse byte map that counts its calls
+type memoryBridge struct {
+	memory map[uint64]byte
+	calls  int
+}
+
+func (m *memoryBridge) bridge(t *testing.T) js.Value {
+	m.memory = make(map[uint64]byte)
+	return newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func([]js.Value) interface{} { return true },
+		"readMemory": func(args []js.Value) interface{} {
+			m.calls++
+			address, length := uint64(args[0].Float()), args[1].Int()
+			data := make([]byte, length)
+			for i := range data {
+				data[i] = m.memory[address+uint64(i)]
+			}
+			bytes := js.Global().Get("Uint8Array").New(length)
+			js.CopyBytesToJS(bytes, data)
+			return bytes
+		},
+		"writeMemory": func(args []js.Value) interface{} {
+			m.calls++
+			address := uint64(args[0].Float())
+			data := make([]byte, args[1].Length())
+			js.CopyBytesToGo(data, args[1])
+			for i, b := range data {
+				m.memory[address+uint64(i)] = b
+			}
+			return nil
+		},
+	})
+}
+
+// readBytes reads guest memory and returns it as a Go slice
+func readBytes(t *testing.T, vo *VMOrchestrator, address uint64, length int) []byte {
+	t.Helper()
+	bytes := requireOK(t, call(vo.ReadMemory, address, length)).Get("bytes")
+	data := make([]byte, bytes.Length())
+	js.CopyBytesToGo(data, bytes)
+	return data
+}
+
+func TestGuestMemoryInsideRegions(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	var m memoryBridge
+	call(vo.Initialize, m.bridge(t))
+	requireOK(t, call(vo.MapRegion, 0x1000, 0x1000, permRead|permWrite))
+	requireOK(t, call(vo.MapRegion, 0x2000, 0x1000, permRead))
+
+	// A range may span adjacent regions if each byte is permitted
+	requireOK(t, call(vo.WriteMemory, 0x1ffe, []interface{}{1, 2}))
+	m.memory[0x2000] = 3
+	if got := readBytes(t, vo, 0x1ffe, 3); !reflect.DeepEqual(got, []byte{1, 2, 3}) {
+		t.Errorf("read %v, want [1 2 3]", got)
+	}
+
+	bytes := js.Global().Get("Uint8Array").New(2)
+	js.CopyBytesToJS(bytes, []byte{0xab, 0xcd})
+	requireOK(t, call(vo.WriteMemory, 0x1800, bytes))
+	if got := readBytes(t, vo, 0x1800, 2); !reflect.DeepEqual(got, []byte{0xab, 0xcd}) {
+		t.Errorf("read %v after writing a Uint8Array, want [171 205]", got)
+	}
+}
+
+func TestGuestMemoryOutsideRegions(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	var m memoryBridge
+	call(vo.Initialize, m.bridge(t))
+	requireOK(t, call(vo.MapRegion, 0x1000, 0x1000, permRead|permWrite))
+	requireOK(t, call(vo.MapRegion, 0x3000, 0x1000, permRead))
+	if !call(vo.SetAddressSpaceSize, 0x10000).Bool() {
+		t.Fatal("setAddressSpaceSize failed")
+	}
+
+	requireError(t, call(vo.ReadMemory, 0x5000, 4), errAccessDenied)                   // unmapped
+	requireError(t, call(vo.ReadMemory, 0x1ffe, 4), errAccessDenied)                   // runs off a region
+	requireError(t, call(vo.WriteMemory, 0x3000, []interface{}{1}), errAccessDenied)   // read-only
+	requireError(t, call(vo.ReadMemory, 0xffff, 2), errOutOfRange)                     // past the address space
+	requireError(t, call(vo.WriteMemory, 0x1000, []interface{}{}), errInvalidArgument) // empty
+	requireError(t, call(vo.ReadMemory, 0x1000, 0), errInvalidArgument)
+	if m.calls != 0 {
+		t.Errorf("rejected accesses made %d bridge calls", m.calls)
+	}
+
+	// Host accesses never fault threads
+	requireOK(t, call(vo.MapRegion, 0x8000, 0x1000, permRead|permExecute))
+	id := createThread(t, vo, 0x8000, 1, "paused")
+	call(vo.ReadMemory, 0x5000, 4)
+	if got := threadStatus(vo, id); got != "paused" {
+		t.Errorf("thread is %q after a rejected host read, want paused", got)
+	}
+}
+
+func TestGuestMemoryNeedsBridgeSupport(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireError(t, call(vo.ReadMemory, 0x1000, 4), errUnsupported)
+	requireError(t, call(vo.WriteMemory, 0x1000, []interface{}{1}), errUnsupported)
+}
@@ -0,0 +1,116 @@
+// Stats Heartbeat
+// Pushes stats snapshots to JavaScript on a fixed interval
+//
+// OnStats replaces polling GetStats from a JS timer: while the VM runs, a
+// ticker goroutine posts the callback to the event queue (see events.go)
+// with the same object GetStats returns. The goroutine is tied to the current run and exits when the VM
+// stops, and a new one is started by the next Start, so Start/Stop cycles
+// never leave tickers behind. Replacing or stopping the heartbeat cancels
+// the running goroutine immediately.
+
+package main
+
+import (
+	"syscall/js"
+	"time"
+)
+
+// statsHeartbeat is a registered stats callback and its interval
+type statsHeartbeat struct {
+	interval time.Duration
+	callback js.Value
+	stop     chan struct{} // closed when the heartbeat is replaced or stopped
+}
+
+// OnStats registers callback(stats) to be invoked every intervalMs while the
+// VM is running, replacing any previous heartbeat
+// Arguments: intervalMs, callback
+func (vo *VMOrchestrator) OnStats(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[0].Type() != js.TypeNumber || args[1].Type() != js.TypeFunction {
+		return js.ValueOf(false)
+	}
+
+	ms := args[0].Int()
+	if ms < 1 {
+		return js.ValueOf(false)
+	}
+
+	heartbeat := &statsHeartbeat{
+		interval: time.Duration(ms) * time.Millisecond,
+		callback: args[1],
+		stop:     make(chan struct{}),
+	}
+
+	// Holding schedMutex keeps Start from launching the same heartbeat twice
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+
+	vo.replaceHeartbeat(heartbeat)
+	vo.startHeartbeat(vo.runDone)
+	return js.ValueOf(true)
+}
+
+// StopStatsHeartbeat cancels the heartbeat registered with OnStats
+func (vo *VMOrchestrator) StopStatsHeartbeat(this js.Value, args []js.Value) interface{} {
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+
+	return js.ValueOf(vo.replaceHeartbeat(nil))
+}
+
+// replaceHeartbeat installs a new heartbeat and cancels the old one.
+// Returns false if there was none. Caller must hold schedMutex.
+func (vo *VMOrchestrator) replaceHeartbeat(heartbeat *statsHeartbeat) bool {
+	vo.callbackMutex.Lock()
+	old := vo.heartbeat
+	vo.heartbeat = heartbeat
+	vo.callbackMutex.Unlock()
+
+	if old == nil {
+		return false
+	}
+	close(old.stop)
+	return true
+}
+
+// startHeartbeat launches the registered heartbeat, if any, for the run
+// that ends when done is closed. A nil done means the VM is not running.
+// Caller must hold schedMutex.
+func (vo *VMOrchestrator) startHeartbeat(done <-chan struct{}) {
+	vo.callbackMutex.RLock()
+	heartbeat := vo.heartbeat
+	vo.callbackMutex.RUnlock()
+
+	if heartbeat != nil && done != nil {
+		go vo.emitStats(heartbeat, done)
+	}
+}
+
+// emitStats invokes the heartbeat callback every interval until the run
+// ends or the heartbeat is cancelled
+func (vo *VMOrchestrator) emitStats(heartbeat *statsHeartbeat, done <-chan struct{}) {
+	ticker := time.NewTicker(heartbeat.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-heartbeat.stop:
+			return
+		case <-ticker.C:
+			// Stats are read when the event runs, so a backed-up queue
+			// coalesces ticks into one up-to-date snapshot. A tick still
+			// queued when the run ends or the heartbeat is cancelled is
+			// dropped.
+			vo.postEvent(eventNormal, "stats", func() {
+				select {
+				case <-heartbeat.stop:
+				case <-done:
+				default:
+					heartbeat.callback.Invoke(vo.cachedStats())
+				}
+			})
+		}
+	}
+}
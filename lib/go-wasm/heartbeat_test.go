@@ -0,0 +1,63 @@
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+func TestStatsHeartbeatCount(t *testing.T) {
+	// No bridge: workers park, so a busy worker cannot delay the ticker
+	vo := newTestOrchestrator(t)
+	var beats int64
+	if !call(vo.OnStats, 10, newCallback(t, func([]js.Value) { atomic.AddInt64(&beats, 1) })).Bool() {
+		t.Fatal("onStats failed")
+	}
+
+	// Start/Stop cycles must not leave extra tickers behind
+	for i := 0; i < 3; i++ {
+		requireOK(t, call(vo.Start))
+		requireOK(t, call(vo.Stop))
+	}
+	time.Sleep(30 * time.Millisecond)
+	atomic.StoreInt64(&beats, 0)
+
+	requireOK(t, call(vo.Start))
+	time.Sleep(200 * time.Millisecond)
+	requireOK(t, call(vo.Stop))
+	time.Sleep(30 * time.Millisecond) // let the last queued beat run
+
+	// 20 intervals; timers under Node and browsers are coarse, so allow
+	// a wide margin but not a second ticker's worth
+	got := atomic.LoadInt64(&beats)
+	if got < 8 || got > 30 {
+		t.Fatalf("%d heartbeats in 200ms at 10ms intervals, want about 20", got)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if after := atomic.LoadInt64(&beats); after != got {
+		t.Errorf("%d heartbeats while stopped", after-got)
+	}
+}
+
+func TestStopStatsHeartbeat(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	var beats int64
+	call(vo.OnStats, 5, newCallback(t, func([]js.Value) { atomic.AddInt64(&beats, 1) }))
+	requireOK(t, call(vo.Start))
+	eventually(t, "a heartbeat", func() bool { return atomic.LoadInt64(&beats) > 0 })
+
+	if !call(vo.StopStatsHeartbeat).Bool() {
+		t.Fatal("stopStatsHeartbeat found no heartbeat")
+	}
+	time.Sleep(20 * time.Millisecond)
+	stopped := atomic.LoadInt64(&beats)
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt64(&beats); got != stopped {
+		t.Errorf("%d heartbeats after stopStatsHeartbeat", got-stopped)
+	}
+	if call(vo.StopStatsHeartbeat).Bool() {
+		t.Error("a second stopStatsHeartbeat found a heartbeat")
+	}
+}
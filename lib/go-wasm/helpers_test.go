@@ -0,0 +1,162 @@
+// Shared test helpers
+//
+// The orchestrator tests run under GOOS=js GOARCH=wasm through the
+// go_js_wasm_exec wrapper (Node.js) and drive the orchestrator through the
+// same methods JavaScript calls, with emulator bridges built from Go
+// functions.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// testTimeout bounds how long a test waits for the VM to make progress
+const testTimeout = 5 * time.Second
+
+// newTestOrchestrator returns an orchestrator with the default register
+// file that is stopped when the test ends
+func newTestOrchestrator(t *testing.T) *VMOrchestrator {
+	t.Helper()
+	vo := newOrchestrator(defaultRegisterCount)
+	t.Cleanup(func() {
+		if atomic.LoadInt32(&vo.isRunning) != 0 {
+			vo.Stop(js.Undefined(), nil)
+		}
+	})
+	return vo
+}
+
+// jsArgs converts Go values to method arguments
+func jsArgs(values ...interface{}) []js.Value {
+	args := make([]js.Value, len(values))
+	for i, value := range values {
+		args[i] = js.ValueOf(value)
+	}
+	return args
+}
+
+// call invokes an orchestrator method as JavaScript would
+func call(method func(js.Value, []js.Value) interface{}, values ...interface{}) js.Value {
+	return js.ValueOf(method(js.Undefined(), jsArgs(values...)))
+}
+
+// requireOK fails the test unless result is a successful structured result
+func requireOK(t *testing.T, result js.Value) js.Value {
+	t.Helper()
+	if !result.Get("ok").Truthy() {
+		t.Fatalf("call failed: %s: %s", result.Get("error").String(), result.Get("message").String())
+	}
+	return result
+}
+
+// requireError fails the test unless result failed with code
+func requireError(t *testing.T, result js.Value, code string) {
+	t.Helper()
+	if result.Get("ok").Truthy() {
+		t.Fatalf("call succeeded, want error %q", code)
+	}
+	if got := result.Get("error").String(); got != code {
+		t.Fatalf("call failed with %q (%s), want %q", got, result.Get("message").String(), code)
+	}
+}
+
+// newBridge builds an emulator bridge whose methods are Go functions
+func newBridge(t *testing.T, methods map[string]func(args []js.Value) interface{}) js.Value {
+	t.Helper()
+	bridge := js.Global().Get("Object").New()
+	for name, method := range methods {
+		method := method
+		fn := js.FuncOf(func(this js.Value, args []js.Value) interface{} { return method(args) })
+		t.Cleanup(fn.Release)
+		bridge.Set(name, fn)
+	}
+	return bridge
+}
+
+// stepBridge returns a bridge whose executeInstruction always succeeds
+func stepBridge(t *testing.T) js.Value {
+	t.Helper()
+	return newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func([]js.Value) interface{} { return true },
+	})
+}
+
+// createThread creates a thread at pc, passing any further CreateThread
+// arguments, and returns its ID
+func createThread(t *testing.T, vo *VMOrchestrator, pc uint64, extra ...interface{}) int {
+	t.Helper()
+	result := requireOK(t, call(vo.CreateThread, append([]interface{}{pc}, extra...)...))
+	return result.Get("threadID").Int()
+}
+
+// threadPC returns a thread's next PC
+func threadPC(t *testing.T, vo *VMOrchestrator, threadID int) uint64 {
+	t.Helper()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		t.Fatalf("thread %d does not exist", threadID)
+	}
+	thread.mutex.RLock()
+	defer thread.mutex.RUnlock()
+	return thread.pc
+}
+
+// threadStatus returns a thread's status, or "" if it is gone
+func threadStatus(vo *VMOrchestrator, threadID int) string {
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return ""
+	}
+	return thread.ThreadStatus()
+}
+
+// stat returns a numeric GetStats field
+func stat(vo *VMOrchestrator, name string) float64 {
+	return call(vo.GetStats).Get(name).Float()
+}
+
+// statsThread returns a thread's GetStats threadStats entry, or undefined
+func statsThread(vo *VMOrchestrator, threadID int) js.Value {
+	threads := call(vo.GetStats).Get("threadStats")
+	for i := 0; i < threads.Length(); i++ {
+		if thread := threads.Index(i); thread.Get("id").Int() == threadID {
+			return thread
+		}
+	}
+	return js.Undefined()
+}
+
+// eventually polls cond until it holds, failing the test after testTimeout
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// newJSFunc wraps fn as a JS function that returns a value
+func newJSFunc(t *testing.T, fn func(args []js.Value) interface{}) js.Value {
+	t.Helper()
+	callback := js.FuncOf(func(this js.Value, args []js.Value) interface{} { return fn(args) })
+	t.Cleanup(callback.Release)
+	return callback.Value
+}
+
+// newCallback wraps fn as a JS function for the On* registration methods
+func newCallback(t *testing.T, fn func(args []js.Value)) js.Value {
+	t.Helper()
+	callback := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		fn(args)
+		return nil
+	})
+	t.Cleanup(callback.Release)
+	return callback.Value
+}
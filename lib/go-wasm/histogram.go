@@ -0,0 +1,91 @@
+// Latency Histogram
+// Buckets the wall-clock time of each bridge call by order of magnitude
+//
+// While enabled, every step through the emulator bridge (one instruction or
+// one batch) is timed and counted in a decade bucket, which shows the
+// distribution of bridge-call latency. Counters are atomic, so workers
+// record concurrently without a lock, and a disabled histogram costs one
+// atomic load per step. Browsers coarsen the clock (see chargeCPUTime), so
+// fast single steps may land in the lowest bucket as zero.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"time"
+)
+
+// latencyBuckets are the exclusive upper bounds of every bucket but the last
+var latencyBuckets = [...]time.Duration{
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+}
+
+// latencyBucketNames label the buckets in GetLatencyHistogram
+var latencyBucketNames = [len(latencyBuckets) + 1]string{
+	"<1us", "<10us", "<100us", "<1ms", "<10ms", "<100ms", ">=100ms",
+}
+
+// latencyHistogram counts bridge calls per bucket
+type latencyHistogram struct {
+	counts [len(latencyBuckets) + 1]uint64 // atomic
+}
+
+// EnableLatencyHistogram starts recording bridge-call latency. Enabling an
+// enabled histogram keeps its counts.
+func (vo *VMOrchestrator) EnableLatencyHistogram(this js.Value, args []js.Value) interface{} {
+	vo.latency.CompareAndSwap(nil, &latencyHistogram{})
+	return js.ValueOf(true)
+}
+
+// DisableLatencyHistogram stops recording and discards the counts
+func (vo *VMOrchestrator) DisableLatencyHistogram(this js.Value, args []js.Value) interface{} {
+	vo.latency.Store(nil)
+	return js.ValueOf(true)
+}
+
+// ResetHistogram zeroes the counts of an enabled histogram
+func (vo *VMOrchestrator) ResetHistogram(this js.Value, args []js.Value) interface{} {
+	histogram := vo.latency.Load()
+	if histogram == nil {
+		return js.ValueOf(false)
+	}
+
+	for i := range histogram.counts {
+		atomic.StoreUint64(&histogram.counts[i], 0)
+	}
+	return js.ValueOf(true)
+}
+
+// GetLatencyHistogram returns bucket name -> count, or null while disabled
+func (vo *VMOrchestrator) GetLatencyHistogram(this js.Value, args []js.Value) interface{} {
+	histogram := vo.latency.Load()
+	if histogram == nil {
+		return js.Null()
+	}
+
+	buckets := make(map[string]interface{}, len(histogram.counts))
+	for i := range histogram.counts {
+		buckets[latencyBucketNames[i]] = atomic.LoadUint64(&histogram.counts[i])
+	}
+	return js.ValueOf(buckets)
+}
+
+// observe counts the time since start; meant to be deferred
+func (histogram *latencyHistogram) observe(start time.Time) {
+	elapsed := time.Since(start)
+
+	bucket := len(latencyBuckets)
+	for i, bound := range latencyBuckets {
+		if elapsed < bound {
+			bucket = i
+			break
+		}
+	}
+	atomic.AddUint64(&histogram.counts[bucket], 1)
+}
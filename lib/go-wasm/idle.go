@@ -0,0 +1,45 @@
+// Idle Detection
+// Tells the host when the guest has finished all of its work
+//
+// The VM is idle once its last active thread terminates on its own (halt,
+// instruction limit, kill) or faults while the VM is still running; faulted
+// threads do not count as active. The check happens in terminateThread and
+// fault under threadMutex, in the same critical section that removes or
+// faults the thread, so exactly one of them observes the count reach zero.
+// Start creates no idle event before its main thread exists because the
+// count never went from above zero to zero, and creating threads again
+// re-arms detection for the next time the count drops to zero. Stop
+// terminates threads without firing.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// OnIdle registers a callback invoked as callback() when the last active
+// thread terminates while the VM is running. Passing null clears it.
+func (vo *VMOrchestrator) OnIdle(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	vo.idleCallback = args[0]
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// fireIdle queues a call to the registered idle callback
+func (vo *VMOrchestrator) fireIdle() {
+	vo.postEvent(eventCritical, "", func() {
+		vo.callbackMutex.RLock()
+		callback := vo.idleCallback
+		vo.callbackMutex.RUnlock()
+
+		if callback.Type() == js.TypeFunction {
+			callback.Invoke()
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// countIdle registers an idle callback and returns its call counter
+func countIdle(t *testing.T, vo *VMOrchestrator) *int32 {
+	t.Helper()
+	var fired int32
+	if !call(vo.OnIdle, newCallback(t, func([]js.Value) { atomic.AddInt32(&fired, 1) })).Bool() {
+		t.Fatal("onIdle rejected a function")
+	}
+	return &fired
+}
+
+// settle gives queued events time to be dispatched
+func settle() { time.Sleep(50 * time.Millisecond) }
+
+func TestIdleFiresOnceWhenLastThreadTerminates(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	fired := countIdle(t, vo)
+	requireOK(t, call(vo.Start))
+
+	a := createThread(t, vo, 0x40000000, 1, "paused")
+	b := createThread(t, vo, 0x40001000, 1, "paused")
+	requireOK(t, call(vo.KillThread, 1))
+	requireOK(t, call(vo.KillThread, a))
+	settle()
+	if got := atomic.LoadInt32(fired); got != 0 {
+		t.Fatalf("idle fired %d times with thread %d still active", got, b)
+	}
+
+	requireOK(t, call(vo.KillThread, b))
+	eventually(t, "the idle callback", func() bool { return atomic.LoadInt32(fired) > 0 })
+	settle()
+	if got := atomic.LoadInt32(fired); got != 1 {
+		t.Fatalf("idle fired %d times, want 1", got)
+	}
+
+	// New threads re-arm detection
+	c := createThread(t, vo, 0x40002000, 1, "paused")
+	requireOK(t, call(vo.KillThread, c))
+	eventually(t, "the re-armed idle callback", func() bool { return atomic.LoadInt32(fired) == 2 })
+}
+
+func TestIdleNotFiredByStop(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	fired := countIdle(t, vo)
+	requireOK(t, call(vo.Start))
+	createThread(t, vo, 0x40000000, 1, "paused")
+
+	call(vo.Stop)
+	settle()
+	if got := atomic.LoadInt32(fired); got != 0 {
+		t.Errorf("idle fired %d times on Stop", got)
+	}
+}
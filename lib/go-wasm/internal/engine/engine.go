@@ -0,0 +1,11 @@
+// Package engine holds the platform-independent core of the VM
+// orchestrator: execution statistics, the run queue that decides
+// scheduling order, and thread ID allocation and lifecycle accounting.
+//
+// Package main is the syscall/js binding layer: it owns the locks, calls
+// the emulator bridge and converts values to and from JavaScript, and
+// delegates the bookkeeping here. Nothing in this package imports
+// syscall/js, so it builds and is tested on the host with a plain go test.
+// Types here are not safe for concurrent use unless documented otherwise;
+// callers guard them with their own mutexes.
+package engine
@@ -0,0 +1,65 @@
+package engine
+
+// Quantum is the number of instructions a priority-1 thread executes per
+// scheduling window; a thread of priority p runs p quanta per dispatch
+const Quantum = 64
+
+// Schedulable is a thread as the run queue sees it
+type Schedulable interface {
+	// Affinity returns the index of the worker the thread is pinned to, or
+	// -1 if it may run on any worker
+	Affinity() int
+}
+
+// RunQueue holds runnable threads in FIFO order. A worker takes the first
+// queued thread it may run and the thread is pushed back at the tail after
+// its quantum, so threads are served round-robin in the order they became
+// runnable. Threads pinned to another worker are skipped but keep their
+// place.
+type RunQueue[T Schedulable] struct {
+	threads []T
+}
+
+// Len returns the number of queued threads
+func (queue *RunQueue[T]) Len() int {
+	return len(queue.threads)
+}
+
+// Push appends a thread at the tail of the queue
+func (queue *RunQueue[T]) Push(thread T) {
+	queue.threads = append(queue.threads, thread)
+}
+
+// Clear drops every queued thread
+func (queue *RunQueue[T]) Clear() {
+	queue.threads = nil
+}
+
+// Next returns the position of the first queued thread that worker may run
+// and that eligible accepts, or -1 if there is none. A nil eligible accepts
+// every thread.
+func (queue *RunQueue[T]) Next(worker int, eligible func(T) bool) int {
+	for i, thread := range queue.threads {
+		if affinity := thread.Affinity(); affinity >= 0 && affinity != worker {
+			continue
+		}
+		if eligible == nil || eligible(thread) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Take removes and returns the thread at position i, keeping the others in
+// order
+func (queue *RunQueue[T]) Take(i int) T {
+	thread := queue.threads[i]
+	if i == 0 {
+		var zero T
+		queue.threads[0] = zero
+		queue.threads = queue.threads[1:]
+	} else {
+		queue.threads = append(queue.threads[:i], queue.threads[i+1:]...)
+	}
+	return thread
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testThread is a schedulable thread with a fixed affinity
+type testThread struct {
+	id       int
+	affinity int
+}
+
+func (thread *testThread) Affinity() int {
+	return thread.affinity
+}
+
+func newTestThreads(n int) []*testThread {
+	threads := make([]*testThread, n)
+	for i := range threads {
+		threads[i] = &testThread{id: i + 1, affinity: -1}
+	}
+	return threads
+}
+
+// dispatch takes the next thread worker may run, or nil
+func dispatch(queue *RunQueue[*testThread], worker int) *testThread {
+	next := queue.Next(worker, nil)
+	if next < 0 {
+		return nil
+	}
+	return queue.Take(next)
+}
+
+func TestRunQueueServesThreadsRoundRobin(t *testing.T) {
+	var queue RunQueue[*testThread]
+	for _, thread := range newTestThreads(3) {
+		queue.Push(thread)
+	}
+
+	// Each dispatched thread is requeued at the tail after its quantum
+	var order []int
+	for i := 0; i < 7; i++ {
+		thread := dispatch(&queue, 0)
+		order = append(order, thread.id)
+		queue.Push(thread)
+	}
+
+	if want := []int{1, 2, 3, 1, 2, 3, 1}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("dispatch order is %v, want %v", order, want)
+	}
+}
+
+func TestRunQueueSkipsThreadsPinnedElsewhere(t *testing.T) {
+	threads := newTestThreads(3)
+	threads[0].affinity = 1
+	threads[2].affinity = 0
+
+	var queue RunQueue[*testThread]
+	for _, thread := range threads {
+		queue.Push(thread)
+	}
+
+	if thread := dispatch(&queue, 0); thread.id != 2 {
+		t.Fatalf("worker 0 took thread %d, want 2", thread.id)
+	}
+	if thread := dispatch(&queue, 0); thread.id != 3 {
+		t.Fatalf("worker 0 took thread %d, want 3", thread.id)
+	}
+	if thread := dispatch(&queue, 0); thread != nil {
+		t.Fatalf("worker 0 took thread %d pinned to worker 1", thread.id)
+	}
+	if thread := dispatch(&queue, 1); thread == nil || thread.id != 1 {
+		t.Fatal("worker 1 did not take its pinned thread")
+	}
+}
+
+func TestRunQueueNextHonorsEligibility(t *testing.T) {
+	var queue RunQueue[*testThread]
+	for _, thread := range newTestThreads(4) {
+		queue.Push(thread)
+	}
+
+	even := func(thread *testThread) bool { return thread.id%2 == 0 }
+	if next := queue.Next(0, even); next != 1 {
+		t.Fatalf("first eligible position is %d, want 1", next)
+	}
+	if next := queue.Next(0, func(*testThread) bool { return false }); next != -1 {
+		t.Fatalf("position with nothing eligible is %d, want -1", next)
+	}
+}
+
+func TestRunQueueTakeKeepsOrder(t *testing.T) {
+	var queue RunQueue[*testThread]
+	for _, thread := range newTestThreads(4) {
+		queue.Push(thread)
+	}
+
+	if thread := queue.Take(2); thread.id != 3 {
+		t.Fatalf("took thread %d, want 3", thread.id)
+	}
+	if queue.Len() != 3 {
+		t.Fatalf("queue length is %d, want 3", queue.Len())
+	}
+
+	var order []int
+	for queue.Len() > 0 {
+		order = append(order, dispatch(&queue, 0).id)
+	}
+	if want := []int{1, 2, 4}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("remaining order is %v, want %v", order, want)
+	}
+}
+
+func TestRunQueueClear(t *testing.T) {
+	var queue RunQueue[*testThread]
+	for _, thread := range newTestThreads(2) {
+		queue.Push(thread)
+	}
+
+	queue.Clear()
+	if queue.Len() != 0 || queue.Next(0, nil) != -1 {
+		t.Fatal("cleared queue still has work")
+	}
+}
@@ -0,0 +1,89 @@
+package engine
+
+import "time"
+
+// Stats tracks a VM's execution statistics
+type Stats struct {
+	InstructionsExecuted uint64
+	CyclesExecuted       uint64 // instruction cycle costs reported by the bridge, 1 per instruction by default
+	MemoryAllocated      uint64
+	PeakMemoryAllocated  uint64
+	AllocationErrors     uint64 // frees that exceeded the allocated total
+	Faults               uint64 // threads faulted by bridge failures or memory access violations
+	Yields               uint64 // quanta given up through cooperative yields
+	StackGrowths         uint64 // thread stack reallocations
+	RejectedThreads      uint64 // thread creations refused by the thread limit
+	PeakActiveThreads    uint64 // most threads active at once since the last reset
+	ThreadsCreated       uint64
+	ThreadsTerminated    uint64
+	ExecutionTime        time.Duration // wall-clock time spent running, up to LastUpdate
+	CPUTime              time.Duration // time threads spent executing, summed over threads
+	LastUpdate           time.Time
+	ClockRunning         bool // ExecutionTime accumulates while true
+}
+
+// NewStats returns zeroed stats with a stopped execution clock
+func NewStats(now time.Time) Stats {
+	return Stats{LastUpdate: now}
+}
+
+// UpdateExecutionTime brings ExecutionTime up to now while the clock runs
+func (stats *Stats) UpdateExecutionTime(now time.Time) {
+	if stats.ClockRunning {
+		stats.ExecutionTime += now.Sub(stats.LastUpdate)
+	}
+	stats.LastUpdate = now
+}
+
+// SetClock accounts execution time up to now, then starts or stops the
+// execution clock
+func (stats *Stats) SetClock(now time.Time, running bool) {
+	stats.UpdateExecutionTime(now)
+	stats.ClockRunning = running
+}
+
+// CountInstructions adds n executed instructions costing cycles. Returns
+// the instruction count before the addition, so callers can detect
+// thresholds crossed by exactly one increment.
+func (stats *Stats) CountInstructions(n, cycles uint64) (before uint64) {
+	before = stats.InstructionsExecuted
+	stats.InstructionsExecuted += n
+	stats.CyclesExecuted += cycles
+	return before
+}
+
+// CyclesPerInstruction returns the average cycle cost of an instruction, or
+// 0 before any instruction has executed
+func (stats *Stats) CyclesPerInstruction() float64 {
+	if stats.InstructionsExecuted == 0 {
+		return 0
+	}
+	return float64(stats.CyclesExecuted) / float64(stats.InstructionsExecuted)
+}
+
+// Allocate adds bytes to the allocated memory total and raises the peak
+func (stats *Stats) Allocate(bytes uint64) {
+	stats.MemoryAllocated += bytes
+	stats.PeakMemoryAllocated = max(stats.PeakMemoryAllocated, stats.MemoryAllocated)
+}
+
+// Free subtracts bytes from the allocated memory total. Freeing more than
+// is allocated clamps the total to zero, counts an allocation error and
+// returns false.
+func (stats *Stats) Free(bytes uint64) bool {
+	if bytes > stats.MemoryAllocated {
+		stats.MemoryAllocated = 0
+		stats.AllocationErrors++
+		return false
+	}
+	stats.MemoryAllocated -= bytes
+	return true
+}
+
+// ThreadsAdded counts n created threads that brought the active thread
+// count to active. Concurrent creations may report out of order, but the
+// peak only ever rises, so the highest count wins either way.
+func (stats *Stats) ThreadsAdded(n, active int) {
+	stats.ThreadsCreated += uint64(n)
+	stats.PeakActiveThreads = max(stats.PeakActiveThreads, uint64(active))
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCountInstructionsReturnsPreviousTotal(t *testing.T) {
+	stats := NewStats(time.Now())
+
+	if before := stats.CountInstructions(3, 5); before != 0 {
+		t.Fatalf("first count returned %d, want 0", before)
+	}
+	if before := stats.CountInstructions(2, 2); before != 3 {
+		t.Fatalf("second count returned %d, want 3", before)
+	}
+	if stats.InstructionsExecuted != 5 || stats.CyclesExecuted != 7 {
+		t.Fatalf("got %d instructions and %d cycles, want 5 and 7", stats.InstructionsExecuted, stats.CyclesExecuted)
+	}
+}
+
+func TestCyclesPerInstruction(t *testing.T) {
+	stats := NewStats(time.Now())
+	if cpi := stats.CyclesPerInstruction(); cpi != 0 {
+		t.Fatalf("CPI before any instruction is %v, want 0", cpi)
+	}
+
+	stats.CountInstructions(4, 10)
+	if cpi := stats.CyclesPerInstruction(); cpi != 2.5 {
+		t.Fatalf("CPI is %v, want 2.5", cpi)
+	}
+}
+
+func TestAllocateAndFree(t *testing.T) {
+	stats := NewStats(time.Now())
+
+	stats.Allocate(100)
+	stats.Allocate(50)
+	if !stats.Free(120) {
+		t.Fatal("freeing less than allocated failed")
+	}
+	stats.Allocate(10)
+
+	if stats.MemoryAllocated != 40 {
+		t.Errorf("allocated is %d, want 40", stats.MemoryAllocated)
+	}
+	if stats.PeakMemoryAllocated != 150 {
+		t.Errorf("peak is %d, want 150", stats.PeakMemoryAllocated)
+	}
+	if stats.AllocationErrors != 0 {
+		t.Errorf("allocation errors is %d, want 0", stats.AllocationErrors)
+	}
+}
+
+func TestOverFreeClampsAndCountsAnError(t *testing.T) {
+	stats := NewStats(time.Now())
+	stats.Allocate(8)
+
+	if stats.Free(9) {
+		t.Fatal("freeing more than allocated succeeded")
+	}
+	if stats.MemoryAllocated != 0 || stats.AllocationErrors != 1 {
+		t.Fatalf("got %d allocated and %d errors, want 0 and 1", stats.MemoryAllocated, stats.AllocationErrors)
+	}
+	if stats.PeakMemoryAllocated != 8 {
+		t.Fatalf("peak is %d, want 8", stats.PeakMemoryAllocated)
+	}
+}
+
+func TestExecutionClockOnlyRunsWhileStarted(t *testing.T) {
+	start := time.Unix(1000, 0)
+	stats := NewStats(start)
+
+	stats.UpdateExecutionTime(start.Add(time.Second))
+	if stats.ExecutionTime != 0 {
+		t.Fatalf("stopped clock accumulated %v", stats.ExecutionTime)
+	}
+
+	stats.SetClock(start.Add(2*time.Second), true)
+	stats.UpdateExecutionTime(start.Add(5 * time.Second))
+	if stats.ExecutionTime != 3*time.Second {
+		t.Fatalf("running clock accumulated %v, want 3s", stats.ExecutionTime)
+	}
+
+	stats.SetClock(start.Add(6*time.Second), false)
+	stats.UpdateExecutionTime(start.Add(60 * time.Second))
+	if stats.ExecutionTime != 4*time.Second {
+		t.Fatalf("clock accumulated %v after stopping, want 4s", stats.ExecutionTime)
+	}
+}
+
+func TestThreadsAddedKeepsTheHighestPeak(t *testing.T) {
+	stats := NewStats(time.Now())
+
+	stats.ThreadsAdded(2, 2)
+	stats.ThreadsAdded(3, 5)
+	stats.ThreadsAdded(1, 4) // reported late by a concurrent creation
+
+	if stats.ThreadsCreated != 6 {
+		t.Errorf("created is %d, want 6", stats.ThreadsCreated)
+	}
+	if stats.PeakActiveThreads != 5 {
+		t.Errorf("peak is %d, want 5", stats.PeakActiveThreads)
+	}
+}
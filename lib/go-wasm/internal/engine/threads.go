@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// Active reports whether a thread in status counts toward the active thread
+// count and the thread limit. Faulted threads are kept only for inspection
+// and terminated ones are gone, so neither is active.
+func Active(status string) bool {
+	return status != "faulted" && status != "terminated"
+}
+
+// Restorable reports whether a snapshot or exported state may hold a thread
+// in status. Terminated threads are never saved.
+func Restorable(status string) bool {
+	switch status {
+	case "ready", "running", "paused", "waiting", "suspended", "faulted":
+		return true
+	default:
+		return false
+	}
+}
+
+// Thread is a VM thread as the registry sees it
+type Thread interface {
+	// ThreadStatus returns the thread's lifecycle status. It may lock the
+	// thread, so callers must not hold the thread's own lock.
+	ThreadStatus() string
+}
+
+// Threads maps IDs to a VM's live threads. A thread is added once created
+// and deleted once it terminates; faulted threads stay until then.
+type Threads[T Thread] map[int]T
+
+// Active returns how many threads in the map are active
+func (threads Threads[T]) Active() int {
+	count := 0
+	for _, thread := range threads {
+		if Active(thread.ThreadStatus()) {
+			count++
+		}
+	}
+	return count
+}
+
+// Admit returns how many of n new threads fit under a limit on active
+// threads; a limit of 0 admits all of them
+func (threads Threads[T]) Admit(n, limit int) int {
+	if limit == 0 {
+		return n
+	}
+	return min(n, max(limit-threads.Active(), 0))
+}
+
+// Sorted returns the threads in ID order
+func (threads Threads[T]) Sorted() []T {
+	ids := make([]int, 0, len(threads))
+	for id := range threads {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	sorted := make([]T, len(ids))
+	for i, id := range ids {
+		sorted[i] = threads[id]
+	}
+	return sorted
+}
+
+// IDs allocates thread IDs, starting at 1. It is safe for concurrent use.
+type IDs struct {
+	last int32
+}
+
+// Next returns a fresh thread ID
+func (ids *IDs) Next() int {
+	return int(atomic.AddInt32(&ids.last, 1))
+}
+
+// Last returns the most recently allocated ID, or 0 if none has been
+func (ids *IDs) Last() int32 {
+	return atomic.LoadInt32(&ids.last)
+}
+
+// Reset makes last the most recently allocated ID, e.g. after restoring
+// threads, so that Next continues after it
+func (ids *IDs) Reset(last int32) {
+	atomic.StoreInt32(&ids.last, last)
+}
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+)
+
+// statusThread is a thread whose status the test sets directly
+type statusThread struct {
+	id     int
+	status string
+}
+
+func (thread *statusThread) ThreadStatus() string {
+	return thread.status
+}
+
+// create allocates a thread with a fresh ID and adds it to threads
+func create(threads Threads[*statusThread], ids *IDs, status string) *statusThread {
+	thread := &statusThread{id: ids.Next(), status: status}
+	threads[thread.id] = thread
+	return thread
+}
+
+func TestIDsStartAtOneAndIncrease(t *testing.T) {
+	var ids IDs
+	for want := 1; want <= 3; want++ {
+		if id := ids.Next(); id != want {
+			t.Fatalf("allocated %d, want %d", id, want)
+		}
+	}
+	if last := ids.Last(); last != 3 {
+		t.Fatalf("last is %d, want 3", last)
+	}
+
+	ids.Reset(10)
+	if id := ids.Next(); id != 11 {
+		t.Fatalf("allocated %d after reset to 10, want 11", id)
+	}
+}
+
+func TestIDsAreUniqueUnderConcurrency(t *testing.T) {
+	const workers, perWorker = 8, 100
+
+	var ids IDs
+	allocated := make(chan int, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				allocated <- ids.Next()
+			}
+		}()
+	}
+	wg.Wait()
+	close(allocated)
+
+	seen := make(map[int]bool)
+	for id := range allocated {
+		if seen[id] {
+			t.Fatalf("ID %d allocated twice", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != workers*perWorker {
+		t.Fatalf("allocated %d IDs, want %d", len(seen), workers*perWorker)
+	}
+}
+
+func TestThreadCreationAndTermination(t *testing.T) {
+	var ids IDs
+	threads := make(Threads[*statusThread])
+
+	first := create(threads, &ids, "running")
+	second := create(threads, &ids, "paused")
+	create(threads, &ids, "ready")
+	if active := threads.Active(); active != 3 {
+		t.Fatalf("%d threads active after creating 3", active)
+	}
+
+	// A faulted thread stays in the map but no longer counts
+	second.status = "faulted"
+	if active := threads.Active(); active != 2 {
+		t.Fatalf("%d threads active after a fault, want 2", active)
+	}
+
+	first.status = "terminated"
+	delete(threads, first.id)
+	if active := threads.Active(); active != 1 {
+		t.Fatalf("%d threads active after a termination, want 1", active)
+	}
+
+	// IDs are never reused
+	if thread := create(threads, &ids, "running"); thread.id != 4 {
+		t.Fatalf("new thread has ID %d, want 4", thread.id)
+	}
+}
+
+func TestAdmitEnforcesTheThreadLimit(t *testing.T) {
+	var ids IDs
+	threads := make(Threads[*statusThread])
+	create(threads, &ids, "running")
+	create(threads, &ids, "waiting")
+	create(threads, &ids, "faulted")
+
+	tests := []struct {
+		n, limit, want int
+	}{
+		{n: 5, limit: 0, want: 5}, // no limit
+		{n: 1, limit: 4, want: 1},
+		{n: 3, limit: 4, want: 2}, // the faulted thread does not count
+		{n: 1, limit: 2, want: 0},
+		{n: 1, limit: 1, want: 0}, // already over the limit
+	}
+	for _, tt := range tests {
+		if got := threads.Admit(tt.n, tt.limit); got != tt.want {
+			t.Errorf("Admit(%d, %d) = %d, want %d", tt.n, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestSortedOrdersByID(t *testing.T) {
+	threads := Threads[*statusThread]{
+		7: {id: 7, status: "running"},
+		2: {id: 2, status: "running"},
+		5: {id: 5, status: "faulted"},
+	}
+
+	sorted := threads.Sorted()
+	if len(sorted) != 3 {
+		t.Fatalf("sorted %d threads, want 3", len(sorted))
+	}
+	for i, want := range []int{2, 5, 7} {
+		if sorted[i].id != want {
+			t.Fatalf("position %d holds thread %d, want %d", i, sorted[i].id, want)
+		}
+	}
+}
+
+func TestStatusClassification(t *testing.T) {
+	tests := []struct {
+		status             string
+		active, restorable bool
+	}{
+		{"ready", true, true},
+		{"running", true, true},
+		{"paused", true, true},
+		{"waiting", true, true},
+		{"suspended", true, true},
+		{"faulted", false, true},
+		{"terminated", false, false},
+	}
+	for _, tt := range tests {
+		if got := Active(tt.status); got != tt.active {
+			t.Errorf("Active(%q) = %v, want %v", tt.status, got, tt.active)
+		}
+		if got := Restorable(tt.status); got != tt.restorable {
+			t.Errorf("Restorable(%q) = %v, want %v", tt.status, got, tt.restorable)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Package ring provides the fixed-capacity ring buffer behind the
+// orchestrator's instruction trace, schedule trace, fault log and
+// automatic snapshots.
+//
+// It is the first piece of VM logic moved out of package main: it has no
+// syscall/js dependency, so it builds and can be tested on the host with a
+// plain go test, unlike the WASM-only orchestrator.
+package ring
+
+import "sync"
+
+// Buffer keeps the most recent entries up to a fixed capacity, overwriting
+// the oldest once full. It is safe for concurrent use.
+type Buffer[T any] struct {
+	mutex   sync.Mutex
+	entries []T
+	next    int
+	count   int
+}
+
+// New returns an empty buffer holding up to capacity entries. capacity must
+// be at least 1.
+func New[T any](capacity int) *Buffer[T] {
+	return &Buffer[T]{entries: make([]T, capacity)}
+}
+
+// Cap returns the buffer's capacity
+func (b *Buffer[T]) Cap() int {
+	return len(b.entries)
+}
+
+// Push appends an entry, overwriting the oldest once the buffer is full
+func (b *Buffer[T]) Push(entry T) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.count < len(b.entries) {
+		b.count++
+	}
+}
+
+// Snapshot copies the entries, oldest first
+func (b *Buffer[T]) Snapshot() []T {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entries := make([]T, b.count)
+	start := b.next - b.count
+	if start < 0 {
+		start += len(b.entries)
+	}
+	for i := range entries {
+		entries[i] = b.entries[(start+i)%len(b.entries)]
+	}
+	return entries
+}
+
+// Clear discards every entry, keeping the capacity
+func (b *Buffer[T]) Clear() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	clear(b.entries)
+	b.next, b.count = 0, 0
+}
@@ -0,0 +1,81 @@
+package ring
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPushKeepsEntriesInOrder(t *testing.T) {
+	buffer := New[int](4)
+	if got := buffer.Snapshot(); len(got) != 0 {
+		t.Fatalf("new buffer holds %v, want nothing", got)
+	}
+
+	for i := 1; i <= 3; i++ {
+		buffer.Push(i)
+	}
+	if got, want := buffer.Snapshot(), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("snapshot is %v, want %v", got, want)
+	}
+	if got := buffer.Cap(); got != 4 {
+		t.Errorf("Cap() = %d, want 4", got)
+	}
+}
+
+func TestPushOverwritesOldestOnceFull(t *testing.T) {
+	buffer := New[int](3)
+
+	// Every wrap position: the snapshot is always the last three, oldest first
+	for i := 1; i <= 10; i++ {
+		buffer.Push(i)
+		want := []int{}
+		for entry := max(1, i-2); entry <= i; entry++ {
+			want = append(want, entry)
+		}
+		if got := buffer.Snapshot(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("after pushing 1..%d the snapshot is %v, want %v", i, got, want)
+		}
+	}
+	if got := buffer.Cap(); got != 3 {
+		t.Errorf("Cap() = %d after wrapping, want 3", got)
+	}
+}
+
+func TestSnapshotIsACopy(t *testing.T) {
+	buffer := New[string](2)
+	buffer.Push("a")
+	buffer.Push("b")
+
+	snapshot := buffer.Snapshot()
+	snapshot[0] = "changed"
+	buffer.Push("c")
+
+	if got, want := buffer.Snapshot(), []string{"b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot is %v, want %v", got, want)
+	}
+	if want := []string{"changed", "b"}; !reflect.DeepEqual(snapshot, want) {
+		t.Errorf("earlier snapshot changed to %v, want %v", snapshot, want)
+	}
+}
+
+func TestClear(t *testing.T) {
+	buffer := New[int](3)
+	for i := 1; i <= 5; i++ {
+		buffer.Push(i)
+	}
+
+	buffer.Clear()
+	if got := buffer.Snapshot(); len(got) != 0 {
+		t.Fatalf("cleared buffer holds %v, want nothing", got)
+	}
+	if got := buffer.Cap(); got != 3 {
+		t.Errorf("Cap() = %d after Clear, want 3", got)
+	}
+
+	// Filling restarts from empty rather than the old wrap position
+	buffer.Push(6)
+	buffer.Push(7)
+	if got, want := buffer.Snapshot(), []int{6, 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot after Clear is %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+// Interrupts
+// Asynchronous signal delivery to guest threads
+//
+// SendInterrupt queues a vector on a thread. Queued vectors are delivered
+// by the scheduler between instructions, one at a time in FIFO order: the
+// interrupted PC is pushed onto the thread stack as a return frame (two
+// words, high half first) and execution continues at the handler registered
+// for the vector. ReturnFromInterrupt pops the frame and resumes the
+// interrupted code. A vector with no handler faults the thread.
+
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"syscall/js"
+)
+
+// SetInterruptHandler sets the handler address for an interrupt vector
+// Arguments: vector, address (null removes the handler)
+func (vo *VMOrchestrator) SetInterruptHandler(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return js.ValueOf(false)
+	}
+
+	vector := args[0].Int()
+	if vector < 0 {
+		return js.ValueOf(false)
+	}
+
+	vo.interruptMutex.Lock()
+	defer vo.interruptMutex.Unlock()
+
+	if args[1].IsNull() {
+		delete(vo.interruptHandlers, vector)
+		return js.ValueOf(true)
+	}
+
+	address, ok := jsToAddress(args[1])
+	if !ok {
+		return js.ValueOf(false)
+	}
+	vo.interruptHandlers[vector] = address
+	return js.ValueOf(true)
+}
+
+// SendInterrupt queues an interrupt vector on a thread
+// Arguments: threadID, vector
+func (vo *VMOrchestrator) SendInterrupt(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(false, errInvalidArgument, "sendInterrupt requires a thread ID and vector")
+	}
+
+	vector := args[1].Int()
+	if vector < 0 {
+		return vo.fail(false, errInvalidArgument, "vector must not be negative")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+	if !thread.queueInterrupt(vector) {
+		return vo.fail(false, errInvalidState, "thread %d has terminated", threadID)
+	}
+	return vo.succeed(true, nil)
+}
+
+// SendInterruptAll queues an interrupt vector on every active thread.
+// Returns the number of threads it was queued on, or -1 for a bad vector.
+func (vo *VMOrchestrator) SendInterruptAll(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(-1)
+	}
+
+	vector := args[0].Int()
+	if vector < 0 {
+		return js.ValueOf(-1)
+	}
+
+	vo.threadMutex.RLock()
+	threads := make([]*VMThread, 0, len(vo.threads))
+	for _, thread := range vo.threads {
+		threads = append(threads, thread)
+	}
+	vo.threadMutex.RUnlock()
+
+	queued := 0
+	for _, thread := range threads {
+		if thread.queueInterrupt(vector) {
+			queued++
+		}
+	}
+	return js.ValueOf(queued)
+}
+
+// ReturnFromInterrupt pops the return frame pushed when an interrupt was
+// delivered and resumes the thread at the interrupted PC
+func (vo *VMOrchestrator) ReturnFromInterrupt(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(false, errInvalidArgument, "returnFromInterrupt requires a thread ID")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.Lock()
+	defer thread.mutex.Unlock()
+
+	if thread.status == "terminated" {
+		return vo.fail(false, errInvalidState, "thread %d has terminated", threadID)
+	}
+	depth := len(thread.stack)
+	if depth < 2 {
+		return vo.fail(false, errStackEmpty, "thread %d has no interrupt return frame", threadID)
+	}
+	thread.pc = uint64(thread.stack[depth-2])<<32 | uint64(thread.stack[depth-1])
+	thread.stack = thread.stack[:depth-2]
+	thread.bypassBreakpoint = false
+	return vo.succeed(true, nil)
+}
+
+// queueInterrupt appends a vector to the thread's pending interrupts.
+// Returns false if the thread has already terminated.
+func (thread *VMThread) queueInterrupt(vector int) bool {
+	thread.mutex.Lock()
+	defer thread.mutex.Unlock()
+
+	if thread.status == "terminated" {
+		return false
+	}
+	thread.pendingInterrupts = append(thread.pendingInterrupts, vector)
+	return true
+}
+
+// deliverInterrupt redirects a thread to the handler of its oldest pending
+// interrupt. Returns false if the thread faulted instead.
+func (vo *VMOrchestrator) deliverInterrupt(thread *VMThread) bool {
+	thread.mutex.Lock()
+	if len(thread.pendingInterrupts) == 0 {
+		thread.mutex.Unlock()
+		return true
+	}
+	vector := thread.pendingInterrupts[0]
+	thread.pendingInterrupts = thread.pendingInterrupts[1:]
+	thread.mutex.Unlock()
+
+	vo.interruptMutex.RLock()
+	handler, ok := vo.interruptHandlers[vector]
+	vo.interruptMutex.RUnlock()
+
+	if !ok {
+		vo.faultThread(thread, fmt.Sprintf("unhandled interrupt %d", vector))
+		return false
+	}
+
+	limit := int(atomic.LoadInt32(&vo.maxStackDepth))
+
+	thread.mutex.Lock()
+	if depth := len(thread.stack); depth+2 > limit {
+		thread.mutex.Unlock()
+		vo.stackOverflow(thread, depth)
+		return false
+	}
+	grew := vo.pushStack(thread, limit, uint32(thread.pc>>32), uint32(thread.pc))
+	thread.pc = handler
+	thread.bypassBreakpoint = false
+	thread.mutex.Unlock()
+
+	if grew {
+		vo.countStackGrowth()
+	}
+	return true
+}
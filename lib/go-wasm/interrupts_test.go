@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"testing"
+)
+
+func TestInterruptRedirectsAndReturns(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	const handler = 0x40000000
+	var inHandler int32
+	call(vo.Initialize, newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func(args []js.Value) interface{} {
+			if args[0].Float() == handler {
+				atomic.StoreInt32(&inHandler, 1)
+			}
+			return true
+		},
+	}))
+	call(vo.SetInterruptHandler, 3, handler)
+	requireOK(t, call(vo.Start))
+	requireOK(t, call(vo.KillThread, 1)) // so waitForQuanta only waits on the test thread
+
+	id := createThread(t, vo, 0x2000)
+	eventually(t, "the thread to run", func() bool { return threadPC(t, vo, id) > 0x2000 })
+	requireOK(t, call(vo.SuspendThread, id))
+	if !vo.waitForQuanta(testTimeout) {
+		t.Fatal("the thread's quantum did not end")
+	}
+	interrupted := threadPC(t, vo, id)
+
+	requireOK(t, call(vo.SendInterrupt, id, 3))
+	requireOK(t, call(vo.ResumeThread, id))
+	eventually(t, "the handler to run", func() bool { return atomic.LoadInt32(&inHandler) == 1 })
+	requireOK(t, call(vo.SuspendThread, id))
+
+	if pc := threadPC(t, vo, id); pc < handler {
+		t.Fatalf("pc = %#x, want it inside the handler at %#x", pc, handler)
+	}
+	if depth := call(vo.GetThread, id).Get("stackDepth").Int(); depth != 2 {
+		t.Fatalf("stack depth = %d, want a two-word return frame", depth)
+	}
+
+	requireOK(t, call(vo.ReturnFromInterrupt, id))
+	if pc := threadPC(t, vo, id); pc != interrupted {
+		t.Errorf("returned to %#x, want the interrupted pc %#x", pc, interrupted)
+	}
+	if depth := call(vo.GetThread, id).Get("stackDepth").Int(); depth != 0 {
+		t.Errorf("stack depth = %d after return, want 0", depth)
+	}
+	requireError(t, call(vo.ReturnFromInterrupt, id), errStackEmpty)
+}
+
+func TestUnhandledInterruptFaults(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+	id := createThread(t, vo, 0x2000)
+
+	if got := call(vo.SendInterruptAll, 7).Int(); got != 2 {
+		t.Fatalf("sendInterruptAll queued on %d threads, want 2", got)
+	}
+	eventually(t, "both threads to fault", func() bool {
+		return threadStatus(vo, 1) == "faulted" && threadStatus(vo, id) == "faulted"
+	})
+}
@@ -0,0 +1,147 @@
+// JavaScript interop helpers shared by the orchestrator's exported methods
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"syscall/js"
+)
+
+// newPromise creates a JS Promise whose executor runs in its own goroutine,
+// so it may block without stalling the JavaScript event loop
+func newPromise(executor func(resolve, reject js.Value)) js.Value {
+	handler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		resolve, reject := args[0], args[1]
+		go executor(resolve, reject)
+		return nil
+	})
+	defer handler.Release()
+
+	return js.Global().Get("Promise").New(handler)
+}
+
+// rejectedPromise returns a Promise already rejected with an Error
+func rejectedPromise(message string) js.Value {
+	return js.Global().Get("Promise").Call("reject", js.Global().Get("Error").New(message))
+}
+
+// callJS runs fn, which calls into JavaScript, and returns a thrown exception
+// as an error instead of letting it unwind through the caller
+func callJS(fn func() js.Value) (result js.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = js.Undefined(), fmt.Errorf("%v", r)
+		}
+	}()
+	return fn(), nil
+}
+
+// uint32sToJS converts a register file or stack to a JS-compatible array
+func uint32sToJS(registers []uint32) []interface{} {
+	values := make([]interface{}, len(registers))
+	for i, value := range registers {
+		values[i] = value
+	}
+	return values
+}
+
+// isCallbackArg reports whether v can be registered as a callback: a function,
+// or null/undefined to clear the registration
+func isCallbackArg(v js.Value) bool {
+	return v.Type() == js.TypeFunction || v.IsNull() || v.IsUndefined()
+}
+
+// intsToJS converts a list of IDs to a JS-compatible array
+func intsToJS(ids []int) []interface{} {
+	values := make([]interface{}, len(ids))
+	for i, id := range ids {
+		values[i] = id
+	}
+	return values
+}
+
+// jsToUint32s converts a JS array of numbers to a uint32 slice
+func jsToUint32s(v js.Value) []uint32 {
+	if v.Type() != js.TypeObject {
+		return nil
+	}
+
+	values := make([]uint32, v.Length())
+	for i := range values {
+		values[i] = uint32(v.Index(i).Int())
+	}
+	return values
+}
+
+// jsToPrimitive converts a JS number, string or boolean to its Go equivalent
+func jsToPrimitive(v js.Value) (interface{}, bool) {
+	switch v.Type() {
+	case js.TypeNumber:
+		return v.Float(), true
+	case js.TypeString:
+		return v.String(), true
+	case js.TypeBoolean:
+		return v.Bool(), true
+	default:
+		return nil, false
+	}
+}
+
+// jsToPrimitiveMap converts a plain JS object of primitive values to a Go
+// map, skipping entries that are not primitives
+func jsToPrimitiveMap(v js.Value) map[string]interface{} {
+	if v.Type() != js.TypeObject {
+		return nil
+	}
+
+	keys := js.Global().Get("Object").Call("keys", v)
+	values := make(map[string]interface{}, keys.Length())
+	for i := 0; i < keys.Length(); i++ {
+		key := keys.Index(i).String()
+		if value, ok := jsToPrimitive(v.Get(key)); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// maxSafeInteger is the largest integer a JS number represents exactly (2^53-1)
+const maxSafeInteger = 1<<53 - 1
+
+// jsToAddress parses a guest address given as a JS number or as a decimal
+// or 0x-prefixed string. Strings carry addresses above 2^53 without
+// precision loss. Returns false for negative, fractional or malformed values.
+func jsToAddress(v js.Value) (uint64, bool) {
+	switch v.Type() {
+	case js.TypeNumber:
+		f := v.Float()
+		if f < 0 || f > maxSafeInteger || f != math.Trunc(f) {
+			return 0, false
+		}
+		return uint64(f), true
+	case js.TypeString:
+		address, err := strconv.ParseUint(v.String(), 0, 64)
+		return address, err == nil
+	}
+	return 0, false
+}
+
+// addressToJS converts a guest address to a JS number when it is exactly
+// representable, or to a decimal string otherwise
+func addressToJS(address uint64) interface{} {
+	if address > maxSafeInteger {
+		return strconv.FormatUint(address, 10)
+	}
+	return address
+}
+
+// addressesToJS converts an address list to a JS-compatible array
+func addressesToJS(addresses []uint64) []interface{} {
+	values := make([]interface{}, len(addresses))
+	for i, address := range addresses {
+		values[i] = addressToJS(address)
+	}
+	return values
+}
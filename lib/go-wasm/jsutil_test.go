@@ -0,0 +1,42 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestAddressConversion(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  uint64
+		ok    bool
+	}{
+		{0x1000, 0x1000, true},
+		{float64(0x100000000), 0x100000000, true},
+		{"0x100000004", 0x100000004, true},
+		{"18446744073709551615", 1<<64 - 1, true},
+		{float64(maxSafeInteger), maxSafeInteger, true},
+		{-4, 0, false},
+		{1.5, 0, false},
+		{"0x1g", 0, false},
+		{true, 0, false},
+	}
+	for _, test := range tests {
+		got, ok := jsToAddress(js.ValueOf(test.value))
+		if ok != test.ok || got != test.want {
+			t.Errorf("jsToAddress(%v) = %#x, %v, want %#x, %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestAddressRoundTripAboveSafeIntegers(t *testing.T) {
+	for _, address := range []uint64{0x1000, 0xffffffff, 0x100000000, maxSafeInteger, maxSafeInteger + 1, 1<<64 - 4} {
+		got, ok := jsToAddress(js.ValueOf(addressToJS(address)))
+		if !ok || got != address {
+			t.Errorf("%#x round-tripped to %#x, %v", address, got, ok)
+		}
+	}
+	if kind := js.ValueOf(addressToJS(maxSafeInteger + 1)).Type(); kind != js.TypeString {
+		t.Errorf("an address above 2^53 converts to a JS %v, want a string", kind)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// threadPCs returns a thread's pc and lastPC from getThread
+func threadPCs(vo *VMOrchestrator, threadID int) (pc, lastPC uint64) {
+	thread := call(vo.GetThread, threadID)
+	return uint64(thread.Get("pc").Float()), uint64(thread.Get("lastPC").Float())
+}
+
+func TestLastPCTrailsPC(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	id := createThread(t, vo, 0x2000, 1, "paused")
+
+	for i := 1; i <= 3; i++ {
+		requireOK(t, call(vo.StepThread, id))
+		pc, lastPC := threadPCs(vo, id)
+		if pc != 0x2000+uint64(4*i) || lastPC != pc-4 {
+			t.Fatalf("after %d steps pc = %#x, lastPC = %#x; want lastPC one instruction behind", i, pc, lastPC)
+		}
+	}
+
+	// The same holds for a thread stopped mid-run
+	requireOK(t, call(vo.Start))
+	running := createThread(t, vo, 0x3000)
+	eventually(t, "the thread to run", func() bool { return threadPC(t, vo, running) > 0x3100 })
+	requireOK(t, call(vo.SuspendThread, running))
+	eventually(t, "the quantum to end", func() bool {
+		pc, lastPC := threadPCs(vo, running)
+		return lastPC == pc-4
+	})
+}
+
+func TestFaultReportsLastPC(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, faultAtBridge(t, 0x40000008))
+	lastPCs := make(chan uint64, 1)
+	call(vo.OnFault, newCallback(t, func(args []js.Value) {
+		lastPCs <- uint64(args[2].Float())
+	}))
+	requireOK(t, call(vo.Start))
+	id := createThread(t, vo, 0x40000000)
+
+	select {
+	case got := <-lastPCs:
+		if got != 0x40000008 {
+			t.Errorf("fault callback lastPC = %#x, want the faulting instruction 0x40000008", got)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the fault callback")
+	}
+	if _, lastPC := threadPCs(vo, id); lastPC != 0x40000008 {
+		t.Errorf("getThread lastPC = %#x, want 0x40000008", lastPC)
+	}
+}
@@ -0,0 +1,92 @@
+// Execution Log
+// Optional diagnostics routed to a host callback instead of console.log
+//
+// SetLogLevel chooses how much is reported: "error" (faults), "info"
+// (thread creation and termination, VM start and stop) or "debug" (every
+// scheduling decision). Messages are delivered through the event queue as
+// callback(level, message). The level is checked before anything is
+// formatted, and hot paths check it before building their arguments, so the
+// default level "off" costs a single atomic load.
+
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"syscall/js"
+)
+
+// Log levels, in increasing verbosity
+const (
+	logOff = iota
+	logError
+	logInfo
+	logDebug
+)
+
+// logLevelNames maps log levels to the names used by SetLogLevel
+var logLevelNames = [...]string{
+	logOff:   "off",
+	logError: "error",
+	logInfo:  "info",
+	logDebug: "debug",
+}
+
+// SetLogLevel sets the most verbose level that is logged: "off", "error",
+// "info" or "debug"
+func (vo *VMOrchestrator) SetLogLevel(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return js.ValueOf(false)
+	}
+
+	for level, name := range logLevelNames {
+		if name == args[0].String() {
+			atomic.StoreInt32(&vo.logLevel, int32(level))
+			return js.ValueOf(true)
+		}
+	}
+	return js.ValueOf(false)
+}
+
+// OnLog registers a callback invoked as callback(level, message) for each
+// logged event. Passing null clears it.
+func (vo *VMOrchestrator) OnLog(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	vo.logCallback = args[0]
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// logging reports whether messages at level are logged
+func (vo *VMOrchestrator) logging(level int32) bool {
+	return level <= atomic.LoadInt32(&vo.logLevel)
+}
+
+// logf formats and queues a log message if its level is enabled. Errors are
+// queued at normal priority so they outlive a flood of debug messages.
+func (vo *VMOrchestrator) logf(level int32, format string, args ...interface{}) {
+	if !vo.logging(level) {
+		return
+	}
+
+	priority := eventLow
+	if level == logError {
+		priority = eventNormal
+	}
+	name, message := logLevelNames[level], fmt.Sprintf(format, args...)
+
+	vo.postEvent(priority, "", func() {
+		vo.callbackMutex.RLock()
+		callback := vo.logCallback
+		vo.callbackMutex.RUnlock()
+
+		if callback.Type() == js.TypeFunction {
+			callback.Invoke(name, message)
+		}
+	})
+}
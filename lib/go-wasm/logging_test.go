@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// logLevelsSeen runs a short session logging at level and returns which
+// message levels reached the callback
+func logLevelsSeen(t *testing.T, level string) map[string]bool {
+	t.Helper()
+	vo := newTestOrchestrator(t)
+	var mutex sync.Mutex
+	seen := map[string]bool{}
+	call(vo.OnLog, newCallback(t, func(args []js.Value) {
+		mutex.Lock()
+		seen[args[0].String()] = true
+		mutex.Unlock()
+	}))
+	if !call(vo.SetLogLevel, level).Bool() {
+		t.Fatalf("setLogLevel(%q) failed", level)
+	}
+
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start)) // info, then debug scheduling decisions
+	id := createThread(t, vo, 0x40000000, 1, "paused")
+	call(vo.SetMaxStackDepth, 1)
+	requireOK(t, call(vo.PushStack, id, 1))
+	requireError(t, call(vo.PushStack, id, 2), errStackOverflow) // error
+	time.Sleep(20 * time.Millisecond)
+	call(vo.Stop)
+	settle()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	return seen
+}
+
+func TestLogLevelFiltering(t *testing.T) {
+	tests := []struct {
+		level string
+		want  map[string]bool
+	}{
+		{"off", map[string]bool{}},
+		{"error", map[string]bool{"error": true}},
+		{"info", map[string]bool{"error": true, "info": true}},
+		{"debug", map[string]bool{"error": true, "info": true, "debug": true}},
+	}
+	for _, test := range tests {
+		if got := logLevelsSeen(t, test.level); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("at level %q logged %v, want %v", test.level, got, test.want)
+		}
+	}
+}
+
+func TestSetLogLevelRejectsUnknownLevels(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	for _, level := range []interface{}{"verbose", "", 2} {
+		if call(vo.SetLogLevel, level).Bool() {
+			t.Errorf("setLogLevel(%v) succeeded", level)
+		}
+	}
+}
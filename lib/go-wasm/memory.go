@@ -0,0 +1,45 @@
+// Guest Memory Accounting
+// Tracks heap allocations reported by the emulator bridge
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// RecordAllocation adds bytes to the guest's allocated memory total
+func (vo *VMOrchestrator) RecordAllocation(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(false)
+	}
+
+	bytes := args[0].Int()
+	if bytes < 0 {
+		return js.ValueOf(false)
+	}
+
+	vo.statsMutex.Lock()
+	defer vo.statsMutex.Unlock()
+
+	vo.stats.Allocate(uint64(bytes))
+	return js.ValueOf(true)
+}
+
+// RecordFree subtracts bytes from the guest's allocated memory total.
+// Freeing more than is allocated clamps the total to zero, counts an
+// allocation error and returns false.
+func (vo *VMOrchestrator) RecordFree(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(false)
+	}
+
+	bytes := args[0].Int()
+	if bytes < 0 {
+		return js.ValueOf(false)
+	}
+
+	vo.statsMutex.Lock()
+	defer vo.statsMutex.Unlock()
+
+	return js.ValueOf(vo.stats.Free(uint64(bytes)))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRecordAllocationAndFree(t *testing.T) {
+	vo := newTestOrchestrator(t)
+
+	for _, bytes := range []int{100, 400} {
+		if !call(vo.RecordAllocation, bytes).Bool() {
+			t.Fatalf("recordAllocation(%d) failed", bytes)
+		}
+	}
+	if got := stat(vo, "memoryAllocated"); got != 500 {
+		t.Fatalf("memoryAllocated = %v, want 500", got)
+	}
+
+	if !call(vo.RecordFree, 300).Bool() {
+		t.Fatal("recordFree(300) failed")
+	}
+	if got := stat(vo, "memoryAllocated"); got != 200 {
+		t.Errorf("memoryAllocated = %v after free, want 200", got)
+	}
+	if got := stat(vo, "peakMemoryAllocated"); got != 500 {
+		t.Errorf("peakMemoryAllocated = %v, want 500", got)
+	}
+	if got := stat(vo, "allocationErrors"); got != 0 {
+		t.Errorf("allocationErrors = %v, want 0", got)
+	}
+}
+
+func TestRecordFreeClampsOverFree(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.RecordAllocation, 64)
+
+	if call(vo.RecordFree, 100).Bool() {
+		t.Fatal("recordFree of more than is allocated succeeded")
+	}
+	if got := stat(vo, "memoryAllocated"); got != 0 {
+		t.Errorf("memoryAllocated = %v after over-free, want 0", got)
+	}
+	if got := stat(vo, "allocationErrors"); got != 1 {
+		t.Errorf("allocationErrors = %v, want 1", got)
+	}
+	if got := stat(vo, "peakMemoryAllocated"); got != 64 {
+		t.Errorf("peakMemoryAllocated = %v, want 64", got)
+	}
+}
+
+func TestRecordAllocationRejectsNegativeSizes(t *testing.T) {
+	vo := newTestOrchestrator(t)
+
+	if call(vo.RecordAllocation, -1).Bool() || call(vo.RecordFree, -1).Bool() {
+		t.Error("negative sizes were accepted")
+	}
+	if got := stat(vo, "memoryAllocated"); got != 0 {
+		t.Errorf("memoryAllocated = %v, want 0", got)
+	}
+}
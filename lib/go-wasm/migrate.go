@@ -0,0 +1,117 @@
+// Thread Migration
+// Moves a thread from one orchestrator to another, e.g. to balance load
+//
+// ExportThread brings the VM to a safe point so the thread is not
+// mid-instruction, serializes it in the ExportState thread format and
+// terminates it on the source with reason "migrated" (joiners resolve and
+// termination listeners fire as for any exit). ImportThread recreates it
+// under a fresh local ID and schedules it if it was running.
+//
+// Only the thread's own state travels: registers, PC, stack, name,
+// priority, instruction counters, watches, pending interrupts and TLS.
+// Relationships with other threads do not, so threads blocked in "waiting"
+// cannot be migrated, worker affinity and group membership are dropped, and
+// guest mutexes the thread holds are released on the source and handed to
+// their waiters, as when a thread is killed.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"syscall/js"
+)
+
+// ExportThread removes a thread from this orchestrator. Returns a Promise
+// resolving to its serialized state (a JSON string for ImportThread), or
+// rejecting if the thread is unknown, terminated or waiting.
+func (vo *VMOrchestrator) ExportThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return rejectedPromise("exportThread requires a thread ID")
+	}
+
+	thread := vo.getThread(args[0].Int())
+	if thread == nil {
+		return rejectedPromise("unknown thread")
+	}
+
+	token := vo.holdSafepoint()
+
+	return newPromise(func(resolve, reject js.Value) {
+		defer vo.releaseSafepoint(token)
+		vo.awaitSafepoint()
+
+		thread.mutex.Lock()
+		status := thread.status
+		exported := thread.export()
+		thread.mutex.Unlock()
+
+		if status == "terminated" || status == "waiting" {
+			reject.Invoke(js.Global().Get("Error").New(fmt.Sprintf("thread %d is %s", thread.id, status)))
+			return
+		}
+
+		data, err := json.Marshal(exported)
+		if err != nil {
+			reject.Invoke(js.Global().Get("Error").New(err.Error()))
+			return
+		}
+
+		vo.terminateThread(thread, "migrated")
+		vo.releaseOwnedMutexes(thread.id)
+		resolve.Invoke(string(data))
+	})
+}
+
+// ImportThread recreates a thread exported by ExportThread on this
+// orchestrator and returns its new ID
+func (vo *VMOrchestrator) ImportThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return vo.fail(-1, errInvalidArgument, "importThread requires a JSON string")
+	}
+
+	var exported exportedThread
+	if err := json.Unmarshal([]byte(args[0].String()), &exported); err != nil {
+		return vo.fail(-1, errInvalidArgument, "invalid thread JSON: %v", err)
+	}
+	if err := vo.validateMigrant(&exported); err != nil {
+		return vo.fail(-1, errInvalidArgument, "invalid thread: %v", err)
+	}
+
+	if vo.admitThreads(1) == 0 {
+		return vo.fail(-1, errThreadLimit, "the thread limit has been reached")
+	}
+
+	exported.ID = vo.threadIDs.Next()
+	exported.Affinity = -1
+	exported.WaitingOn = nil
+	thread := exported.restore()
+	vo.addThreads(thread)
+	return vo.succeed(thread.id, map[string]interface{}{"threadID": thread.id})
+}
+
+// validateMigrant checks that an exported thread fits this orchestrator
+func (vo *VMOrchestrator) validateMigrant(exported *exportedThread) error {
+	registers := int(atomic.LoadInt32(&vo.registerCount))
+	if len(exported.Registers) != registers {
+		return fmt.Errorf("thread has %d registers, want %d", len(exported.Registers), registers)
+	}
+	if limit := int(atomic.LoadInt32(&vo.maxStackDepth)); len(exported.Stack) > limit {
+		return fmt.Errorf("stack depth %d exceeds %d", len(exported.Stack), limit)
+	}
+	if exported.Priority < 1 {
+		return fmt.Errorf("priority %d", exported.Priority)
+	}
+	switch exported.Status {
+	case "ready", "running", "paused", "suspended", "faulted":
+	default:
+		return fmt.Errorf("status %q", exported.Status)
+	}
+	for _, index := range exported.Watches {
+		if index < 0 || index >= registers {
+			return fmt.Errorf("watch on register %d", index)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"syscall/js"
+	"testing"
+)
+
+func TestMigrationRoundTrip(t *testing.T) {
+	// Two running VMs with Go bridges would nest their bridge calls, so the
+	// source stays stopped and its thread is advanced by stepping
+	source, destination := newTestOrchestrator(t), newTestOrchestrator(t)
+	call(source.Initialize, stepBridge(t))
+	call(destination.Initialize, stepBridge(t))
+	destination.threadIDs.Next() // so the migrant cannot keep its source ID by accident
+
+	id := createThread(t, source, 0x40000000, 3, "paused", "mover")
+	for i := 0; i < 3; i++ {
+		requireOK(t, call(source.StepThread, id))
+	}
+	requireOK(t, call(source.SetRegister, id, 2, 0xbeef))
+	requireOK(t, call(source.PushStack, id, 11))
+	requireOK(t, call(source.PushStack, id, 22))
+
+	state := awaitPromise(t, call(source.ExportThread, id))
+	if got := threadStatus(source, id); got != "" && got != "terminated" {
+		t.Fatalf("exported thread is still %q on the source", got)
+	}
+	var exported exportedThread
+	if err := json.Unmarshal([]byte(state.String()), &exported); err != nil {
+		t.Fatalf("export is not thread JSON: %v", err)
+	}
+
+	result := requireOK(t, call(destination.ImportThread, state))
+	imported := result.Get("threadID").Int()
+	if imported == id {
+		t.Errorf("imported thread kept its source ID %d", id)
+	}
+
+	info := call(destination.GetThread, imported)
+	if got := info.Get("name").String(); got != "mover" {
+		t.Errorf("name = %q, want mover", got)
+	}
+	if got := info.Get("priority").Int(); got != 3 {
+		t.Errorf("priority = %d, want 3", got)
+	}
+	if got := info.Get("registers").Index(2).Int(); got != 0xbeef {
+		t.Errorf("register 2 = %#x, want 0xbeef", got)
+	}
+	thread := destination.getThread(imported)
+	thread.mutex.RLock()
+	stack := append([]uint32(nil), thread.stack...)
+	thread.mutex.RUnlock()
+	if !reflect.DeepEqual(stack, []uint32{11, 22}) {
+		t.Errorf("stack = %v, want [11 22]", stack)
+	}
+
+	// The thread carries on from the exported PC on the destination
+	if exported.PC != 0x4000000c {
+		t.Errorf("exported PC = %#x, want 0x4000000c", exported.PC)
+	}
+	if got := call(destination.StepThread, imported).Get("pc").Float(); got != 0x40000010 {
+		t.Errorf("imported thread stepped to %#x, want 0x40000010", uint64(got))
+	}
+
+	// A running thread keeps running once imported into a started VM
+	runner := createThread(t, source, 0x50000000)
+	state = awaitPromise(t, call(source.ExportThread, runner))
+	requireOK(t, call(destination.Start))
+	moved := requireOK(t, call(destination.ImportThread, state)).Get("threadID").Int()
+	eventually(t, "the imported thread to run", func() bool { return threadPC(t, destination, moved) > 0x50000000 })
+}
+
+func TestExportThreadRejections(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	rejected := make(chan string, 1)
+	call(vo.ExportThread, 99).Call("catch", newCallback(t, func(args []js.Value) {
+		rejected <- args[0].Get("message").String()
+	}))
+	if got := <-rejected; got != "unknown thread" {
+		t.Errorf("export of an unknown thread rejected with %q", got)
+	}
+
+	requireError(t, call(vo.ImportThread, "{"), errInvalidArgument)
+	requireError(t, call(vo.ImportThread, `{"registers":[1],"priority":1,"status":"running"}`), errInvalidArgument)
+}
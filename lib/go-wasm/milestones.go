@@ -0,0 +1,64 @@
+// Instruction Milestones
+// Notifies JavaScript every N instructions executed VM-wide
+//
+// OnInstructionMilestone fires its callback each time the global
+// instructionsExecuted counter crosses a multiple of the interval. The
+// counter only advances under statsMutex, so every increment sees a unique
+// before/after pair: each milestone is detected by exactly one increment
+// even when several workers cross it at the same moment, and a batch that
+// jumps over several milestones reports each of them. Callbacks go through
+// the event queue (see events.go) and are subject to its bound.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// instructionMilestone is a registered milestone callback
+type instructionMilestone struct {
+	interval uint64
+	callback js.Value
+}
+
+// OnInstructionMilestone registers callback(count) to be invoked every
+// interval instructions, replacing any previous registration. Passing a
+// null callback clears it.
+// Arguments: interval, callback
+func (vo *VMOrchestrator) OnInstructionMilestone(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || !isCallbackArg(args[1]) {
+		return js.ValueOf(false)
+	}
+	if args[1].Type() != js.TypeFunction {
+		vo.milestone.Store(nil)
+		return js.ValueOf(true)
+	}
+
+	interval := args[0].Int()
+	if interval < 1 {
+		return js.ValueOf(false)
+	}
+
+	vo.milestone.Store(&instructionMilestone{interval: uint64(interval), callback: args[1]})
+	return js.ValueOf(true)
+}
+
+// countInstructions adds n instructions costing cycles to the global
+// counters and queues a callback for every milestone crossed and any
+// automatic snapshot due
+func (vo *VMOrchestrator) countInstructions(n, cycles uint64) {
+	vo.statsMutex.Lock()
+	before := vo.stats.CountInstructions(n, cycles)
+	vo.statsMutex.Unlock()
+
+	vo.queueAutoSnapshot(before, n)
+
+	milestone := vo.milestone.Load()
+	if milestone == nil {
+		return
+	}
+	for count := (before/milestone.interval + 1) * milestone.interval; count <= before+n; count += milestone.interval {
+		reached := count
+		vo.postEvent(eventNormal, "", func() { milestone.callback.Invoke(reached) })
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"syscall/js"
+	"testing"
+)
+
+// runToMilestones runs four workers' worth of threads to a fixed total of
+// instructions and returns the milestone counts reported
+func runToMilestones(t *testing.T, bridge func(*VMOrchestrator) js.Value, interval, total int) []int {
+	t.Helper()
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, bridge(vo))
+	call(vo.SetMaxWorkers, 4)
+
+	var mutex sync.Mutex
+	var counts []int
+	call(vo.OnInstructionMilestone, interval, newCallback(t, func(args []js.Value) {
+		mutex.Lock()
+		counts = append(counts, args[0].Int())
+		mutex.Unlock()
+	}))
+
+	// Hold dispatch until every thread has its limit
+	call(vo.SetTickMode, true)
+	requireOK(t, call(vo.Start))
+	for i := 0; i < 3; i++ {
+		createThread(t, vo, uint64(0x10000000*(i+1)))
+	}
+	for id := 1; id <= 4; id++ {
+		requireOK(t, call(vo.SetThreadInstructionLimit, id, total/4))
+	}
+	call(vo.SetTickMode, false)
+
+	eventually(t, "every thread to reach its limit", func() bool { return stat(vo, "activeThreads") == 0 })
+	eventually(t, "the milestone callbacks", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(counts) >= total/interval
+	})
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	sort.Ints(counts)
+	return counts
+}
+
+// checkMilestones fails unless counts are exactly interval, 2*interval, ..., total
+func checkMilestones(t *testing.T, counts []int, interval, total int) {
+	t.Helper()
+	if len(counts) != total/interval {
+		t.Fatalf("%d milestone callbacks, want %d", len(counts), total/interval)
+	}
+	for i, count := range counts {
+		if want := (i + 1) * interval; count != want {
+			t.Fatalf("milestone %d reported %d, want %d", i, count, want)
+		}
+	}
+}
+
+func TestMilestonesUnderConcurrentLoad(t *testing.T) {
+	const interval, total = 10, 2000
+	counts := runToMilestones(t, func(*VMOrchestrator) js.Value { return stepBridge(t) }, interval, total)
+	checkMilestones(t, counts, interval, total)
+}
+
+func TestMilestonesCrossedByBatches(t *testing.T) {
+	const interval, total = 10, 2000
+	var instructions, calls int64
+	counts := runToMilestones(t, func(vo *VMOrchestrator) js.Value {
+		call(vo.SetBatchSize, 16)
+		return batchBridge(t, &instructions, &calls)
+	}, interval, total)
+	checkMilestones(t, counts, interval, total)
+}
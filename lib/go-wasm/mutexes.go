@@ -0,0 +1,257 @@
+// Guest Mutexes
+// Models guest locks so blocked threads stop consuming quanta
+//
+// Each mutex has an owner and a FIFO queue of waiting threads. Locking a
+// held mutex moves the thread to "waiting" with a wait-for edge on the
+// owner, so lock cycles show up in deadlock detection. Unlocking hands
+// ownership directly to the longest waiter and makes it runnable again;
+// a later locker can never overtake a queued one.
+//
+// Owners inherit priority to avoid priority inversion: a thread holding a
+// mutex runs at the highest priority of the threads blocked on it, if that
+// is above its own, so a medium-priority thread cannot starve it while a
+// high-priority thread waits. Inheritance is transitive along chains of
+// blocked owners and is recomputed from the whole mutex graph whenever it
+// changes, so a thread holding several contended mutexes keeps the boost of
+// the highest remaining waiter and drops back to its own priority once it
+// releases the last of them.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// guestMutex is a lock owned by at most one guest thread
+type guestMutex struct {
+	owner   int   // owning thread ID, 0 when free
+	waiters []int // blocked thread IDs, oldest first
+}
+
+// CreateMutex registers a new unlocked mutex and returns its ID
+func (vo *VMOrchestrator) CreateMutex(this js.Value, args []js.Value) interface{} {
+	vo.guestSyncMutex.Lock()
+	defer vo.guestSyncMutex.Unlock()
+
+	vo.guestMutexCounter++
+	vo.guestMutexes[vo.guestMutexCounter] = &guestMutex{}
+	return js.ValueOf(vo.guestMutexCounter)
+}
+
+// LockMutex acquires a mutex for a thread, or blocks the thread in
+// "waiting" until the mutex is handed to it. The result reports
+// acquired: false when the thread was queued.
+// Arguments: threadID, mutexID
+func (vo *VMOrchestrator) LockMutex(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(false, errInvalidArgument, "lockMutex requires a thread ID and mutex ID")
+	}
+
+	threadID, mutexID := args[0].Int(), args[1].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	vo.guestSyncMutex.Lock()
+	mutex := vo.guestMutexes[mutexID]
+	if mutex == nil {
+		vo.guestSyncMutex.Unlock()
+		return vo.fail(false, errUnknownMutex, "mutex %d does not exist", mutexID)
+	}
+	if mutex.owner == threadID {
+		vo.guestSyncMutex.Unlock()
+		return vo.fail(false, errInvalidState, "thread %d already owns mutex %d", threadID, mutexID)
+	}
+
+	thread.mutex.Lock()
+	if status := thread.status; status != "running" && status != "paused" && !thread.yielding {
+		thread.mutex.Unlock()
+		vo.guestSyncMutex.Unlock()
+		return vo.fail(false, errInvalidState, "thread %d is %s", threadID, status)
+	}
+
+	acquired := vo.acquireOrQueue(mutex, thread)
+	thread.mutex.Unlock()
+	if !acquired {
+		vo.inheritPriorities()
+	}
+	vo.guestSyncMutex.Unlock()
+
+	if acquired {
+		return vo.succeed(true, map[string]interface{}{"acquired": true})
+	}
+
+	if cycle := vo.findDeadlock(); cycle != nil {
+		vo.fireDeadlock(cycle)
+	}
+	return vo.succeed(true, map[string]interface{}{"acquired": false})
+}
+
+// UnlockMutex releases a mutex held by the thread. If threads are waiting,
+// ownership passes to the oldest one, which becomes runnable.
+// Arguments: threadID, mutexID
+func (vo *VMOrchestrator) UnlockMutex(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(false, errInvalidArgument, "unlockMutex requires a thread ID and mutex ID")
+	}
+
+	threadID, mutexID := args[0].Int(), args[1].Int()
+
+	vo.guestSyncMutex.Lock()
+	mutex := vo.guestMutexes[mutexID]
+	if mutex == nil {
+		vo.guestSyncMutex.Unlock()
+		return vo.fail(false, errUnknownMutex, "mutex %d does not exist", mutexID)
+	}
+	if mutex.owner != threadID {
+		owner := mutex.owner
+		vo.guestSyncMutex.Unlock()
+		return vo.fail(false, errNotOwner, "mutex %d is owned by thread %d, not %d", mutexID, owner, threadID)
+	}
+
+	next := vo.handOff(mutex)
+	vo.inheritPriorities()
+	vo.guestSyncMutex.Unlock()
+
+	if next != nil {
+		vo.enqueueThread(next)
+	}
+	return vo.succeed(true, nil)
+}
+
+// acquireOrQueue makes the thread the owner of a free mutex, or queues it
+// as a waiter with a wait-for edge on the owner. Returns true if acquired.
+// Caller must hold guestSyncMutex and thread.mutex.
+func (vo *VMOrchestrator) acquireOrQueue(mutex *guestMutex, thread *VMThread) bool {
+	if mutex.owner == 0 {
+		mutex.owner = thread.id
+		return true
+	}
+
+	vo.beginWait(thread)
+	thread.yielding = false
+	thread.waitingOn = []int{mutex.owner}
+	mutex.waiters = append(mutex.waiters, thread.id)
+	return false
+}
+
+// handOff passes a mutex to its oldest live waiter, retargets the other
+// waiters' wait-for edges at the new owner and returns the woken thread, or
+// nil if the mutex became free. Caller must hold guestSyncMutex.
+func (vo *VMOrchestrator) handOff(mutex *guestMutex) *VMThread {
+	for len(mutex.waiters) > 0 {
+		next := vo.getThread(mutex.waiters[0])
+		mutex.waiters = mutex.waiters[1:]
+		if next == nil {
+			continue // terminated while waiting
+		}
+
+		next.mutex.Lock()
+		if next.status != "waiting" {
+			next.mutex.Unlock()
+			continue
+		}
+		vo.resumeFromWait(next)
+		next.waitingOn = nil
+		next.mutex.Unlock()
+
+		mutex.owner = next.id
+		for _, waiterID := range mutex.waiters {
+			if waiter := vo.getThread(waiterID); waiter != nil {
+				waiter.mutex.Lock()
+				waiter.waitingOn = []int{next.id}
+				waiter.mutex.Unlock()
+			}
+		}
+		return next
+	}
+
+	mutex.owner = 0
+	return nil
+}
+
+// releaseOwnedMutexes hands every mutex owned by a dead thread to its
+// oldest waiter, or frees it
+func (vo *VMOrchestrator) releaseOwnedMutexes(threadID int) {
+	var woken []*VMThread
+
+	vo.guestSyncMutex.Lock()
+	for _, mutex := range vo.guestMutexes {
+		if mutex.owner != threadID {
+			continue
+		}
+		if next := vo.handOff(mutex); next != nil {
+			woken = append(woken, next)
+		}
+	}
+	vo.inheritPriorities()
+	vo.guestSyncMutex.Unlock()
+
+	for _, thread := range woken {
+		vo.enqueueThread(thread)
+	}
+}
+
+// inheritPriorities recomputes every mutex owner's inherited priority from
+// the threads blocked on it, and clears boosts that no longer apply.
+// Caller must hold guestSyncMutex.
+func (vo *VMOrchestrator) inheritPriorities() {
+	blockedOn := make(map[int][]int) // owner -> threads waiting for its mutexes
+	for _, mutex := range vo.guestMutexes {
+		if mutex.owner != 0 {
+			blockedOn[mutex.owner] = append(blockedOn[mutex.owner], mutex.waiters...)
+		}
+	}
+
+	// A thread's effective priority is its own or that of its highest
+	// waiter, whichever is higher; memoizing the own priority first stops
+	// the recursion on lock cycles
+	effective := make(map[int]int)
+	var priorityOf func(threadID int) int
+	priorityOf = func(threadID int) int {
+		if priority, ok := effective[threadID]; ok {
+			return priority
+		}
+		thread := vo.getThread(threadID)
+		if thread == nil {
+			return 0
+		}
+		thread.mutex.RLock()
+		priority := thread.priority
+		thread.mutex.RUnlock()
+
+		effective[threadID] = priority
+		for _, waiterID := range blockedOn[threadID] {
+			priority = max(priority, priorityOf(waiterID))
+		}
+		effective[threadID] = priority
+		return priority
+	}
+
+	for ownerID := range blockedOn {
+		priorityOf(ownerID)
+	}
+
+	vo.threadMutex.RLock()
+	threads := make([]*VMThread, 0, len(vo.threads))
+	for _, thread := range vo.threads {
+		threads = append(threads, thread)
+	}
+	vo.threadMutex.RUnlock()
+
+	for _, thread := range threads {
+		thread.mutex.Lock()
+		thread.inheritedPriority = 0
+		if priority := effective[thread.id]; priority > thread.priority {
+			thread.inheritedPriority = priority
+		}
+		thread.mutex.Unlock()
+	}
+}
+
+// effectivePriority is the priority the scheduler uses, including any
+// inherited boost. Caller must hold thread.mutex.
+func (thread *VMThread) effectivePriority() int {
+	return max(thread.priority, thread.inheritedPriority)
+}
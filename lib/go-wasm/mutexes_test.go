@@ -0,0 +1,201 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+// pausedThreads creates n threads in "paused" mode so the scheduler leaves
+// them alone, returning their IDs
+func pausedThreads(t *testing.T, vo *VMOrchestrator, n int) []int {
+	t.Helper()
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = createThread(t, vo, uint64(0x1000*(i+1)), 1, "paused")
+	}
+	return ids
+}
+
+func TestContendedMutexIsFair(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	ids := pausedThreads(t, vo, 5)
+	mutex := call(vo.CreateMutex).Int()
+
+	if !requireOK(t, call(vo.LockMutex, ids[0], mutex)).Get("acquired").Bool() {
+		t.Fatal("first locker did not acquire the free mutex")
+	}
+	for _, id := range ids[1:4] {
+		if requireOK(t, call(vo.LockMutex, id, mutex)).Get("acquired").Bool() {
+			t.Fatalf("thread %d acquired a held mutex", id)
+		}
+		if got := threadStatus(vo, id); got != "waiting" {
+			t.Fatalf("blocked thread %d is %q, want waiting", id, got)
+		}
+	}
+
+	// Ownership passes in lock order, and a late locker queues behind the
+	// earlier waiters instead of overtaking them
+	requireOK(t, call(vo.UnlockMutex, ids[0], mutex))
+	if requireOK(t, call(vo.LockMutex, ids[4], mutex)).Get("acquired").Bool() {
+		t.Fatal("a late locker overtook the queued waiters")
+	}
+	for i, id := range ids[1:] {
+		if got := threadStatus(vo, id); got == "waiting" {
+			t.Fatalf("thread %d is still waiting when it should own the mutex", id)
+		}
+		for _, waiter := range ids[i+2:] {
+			if got := threadStatus(vo, waiter); got != "waiting" {
+				t.Fatalf("thread %d is %q before its turn", waiter, got)
+			}
+		}
+		requireOK(t, call(vo.UnlockMutex, id, mutex))
+	}
+}
+
+func TestUnlockByNonOwnerFails(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	ids := pausedThreads(t, vo, 2)
+	mutex := call(vo.CreateMutex).Int()
+
+	requireError(t, call(vo.UnlockMutex, ids[0], mutex), errNotOwner)
+	requireOK(t, call(vo.LockMutex, ids[0], mutex))
+	requireError(t, call(vo.UnlockMutex, ids[1], mutex), errNotOwner)
+	requireError(t, call(vo.LockMutex, ids[0], mutex), errInvalidState)
+	requireError(t, call(vo.LockMutex, ids[0], 99), errUnknownMutex)
+}
+
+// effectivePriority returns a thread's priority including inheritance
+func effectivePriority(t *testing.T, vo *VMOrchestrator, threadID int) int {
+	t.Helper()
+	return call(vo.GetThread, threadID).Get("effectivePriority").Int()
+}
+
+// requirePriorities checks the effective priority of each thread
+func requirePriorities(t *testing.T, vo *VMOrchestrator, when string, want map[int]int) {
+	t.Helper()
+	for id, priority := range want {
+		if got := effectivePriority(t, vo, id); got != priority {
+			t.Errorf("%s: thread %d runs at priority %d, want %d", when, id, got, priority)
+		}
+	}
+}
+
+func TestPriorityInversion(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	low := createThread(t, vo, 0x1000, 1, "paused")
+	medium := createThread(t, vo, 0x2000, 5, "paused")
+	high := createThread(t, vo, 0x3000, 9, "paused")
+	mutex := call(vo.CreateMutex).Int()
+
+	requireOK(t, call(vo.LockMutex, low, mutex))
+	requirePriorities(t, vo, "uncontended", map[int]int{low: 1, medium: 5, high: 9})
+
+	// The high-priority waiter lends its priority to the holder, so the
+	// medium-priority thread can no longer starve it
+	requireOK(t, call(vo.LockMutex, high, mutex))
+	requirePriorities(t, vo, "high blocked on low", map[int]int{low: 9, medium: 5, high: 9})
+	if got := call(vo.GetThread, low).Get("priority").Int(); got != 1 {
+		t.Errorf("boost changed the holder's own priority to %d", got)
+	}
+
+	requireOK(t, call(vo.UnlockMutex, low, mutex))
+	requirePriorities(t, vo, "after unlock", map[int]int{low: 1, medium: 5, high: 9})
+}
+
+func TestNestedPriorityBoostsUnwind(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	holder := createThread(t, vo, 0x1000, 1, "paused")
+	medium := createThread(t, vo, 0x2000, 5, "paused")
+	high := createThread(t, vo, 0x3000, 9, "paused")
+	first, second := call(vo.CreateMutex).Int(), call(vo.CreateMutex).Int()
+
+	requireOK(t, call(vo.LockMutex, holder, first))
+	requireOK(t, call(vo.LockMutex, holder, second))
+	requireOK(t, call(vo.LockMutex, medium, second))
+	requireOK(t, call(vo.LockMutex, high, first))
+	requirePriorities(t, vo, "both mutexes contended", map[int]int{holder: 9})
+
+	requireOK(t, call(vo.UnlockMutex, holder, first))
+	requirePriorities(t, vo, "high waiter served", map[int]int{holder: 5})
+	requireOK(t, call(vo.UnlockMutex, holder, second))
+	requirePriorities(t, vo, "all released", map[int]int{holder: 1})
+}
+
+func TestPriorityInheritanceIsTransitive(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	a := createThread(t, vo, 0x1000, 1, "paused")
+	b := createThread(t, vo, 0x2000, 3, "paused")
+	c := createThread(t, vo, 0x3000, 9, "paused")
+	first, second := call(vo.CreateMutex).Int(), call(vo.CreateMutex).Int()
+
+	requireOK(t, call(vo.LockMutex, a, first))
+	requireOK(t, call(vo.LockMutex, b, second))
+	requireOK(t, call(vo.LockMutex, b, first))  // b waits on a
+	requireOK(t, call(vo.LockMutex, c, second)) // c waits on b
+	requirePriorities(t, vo, "chain c -> b -> a", map[int]int{a: 9, b: 9, c: 9})
+}
+
+func TestKillingHolderReleasesMutex(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	reasons := make(chan string, 4)
+	call(vo.OnThreadTerminated, newCallback(t, func(args []js.Value) {
+		reasons <- args[0].Get("reason").String()
+	}))
+	ids := pausedThreads(t, vo, 3)
+	holder, waiters := ids[0], ids[1:]
+	mutex := call(vo.CreateMutex).Int()
+
+	requireOK(t, call(vo.LockMutex, holder, mutex))
+	for _, id := range waiters {
+		requireOK(t, call(vo.LockMutex, id, mutex))
+	}
+
+	requireOK(t, call(vo.KillThread, holder))
+	var reason string
+	eventually(t, "the termination callback", func() bool {
+		select {
+		case reason = <-reasons:
+			return true
+		default:
+			return false
+		}
+	})
+	if reason != "killed" {
+		t.Errorf("exit reason = %q, want killed", reason)
+	}
+
+	// The mutex passes to the oldest waiter rather than staying with the
+	// dead thread, and on to the next when that one unlocks
+	if got := threadStatus(vo, waiters[0]); got == "waiting" {
+		t.Fatal("the first waiter is still blocked after the holder was killed")
+	}
+	if got := threadStatus(vo, waiters[1]); got != "waiting" {
+		t.Fatalf("the second waiter is %q before its turn", got)
+	}
+	requireOK(t, call(vo.UnlockMutex, waiters[0], mutex))
+	if got := threadStatus(vo, waiters[1]); got == "waiting" {
+		t.Fatal("the second waiter is still blocked")
+	}
+	requireOK(t, call(vo.UnlockMutex, waiters[1], mutex))
+}
+
+func TestHandOffRestoresWaiterMode(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	ids := pausedThreads(t, vo, 2)
+	running := createThread(t, vo, 0x8000) // never runs: the VM is not started
+	mutex := call(vo.CreateMutex).Int()
+
+	requireOK(t, call(vo.LockMutex, ids[0], mutex))
+	requireOK(t, call(vo.LockMutex, ids[1], mutex))
+	requireOK(t, call(vo.LockMutex, running, mutex))
+
+	// Each waiter gets the mode it blocked in back with the mutex
+	requireOK(t, call(vo.UnlockMutex, ids[0], mutex))
+	if got := threadStatus(vo, ids[1]); got != "paused" {
+		t.Errorf("paused waiter is %q after the hand-off, want paused", got)
+	}
+	requireOK(t, call(vo.UnlockMutex, ids[1], mutex))
+	if got := threadStatus(vo, running); got != "running" {
+		t.Errorf("running waiter is %q after the hand-off, want running", got)
+	}
+}
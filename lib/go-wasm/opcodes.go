@@ -0,0 +1,68 @@
+// Opcode Profiling
+// Counts how often each opcode executes, to find the hottest instructions
+//
+// While profiling is enabled the executeInstruction bridge must report the
+// opcode it executed as { ok, opcode, ... }; results without a numeric
+// opcode (including legacy boolean results) are not counted. Batches cannot
+// report per-instruction opcodes, so batching is disabled while profiling.
+// A disabled profile costs one atomic load per instruction.
+
+package main
+
+import (
+	"strconv"
+	"sync"
+	"syscall/js"
+)
+
+// opcodeProfile counts executed instructions per opcode
+type opcodeProfile struct {
+	mutex  sync.Mutex
+	counts map[uint32]uint64
+}
+
+// EnableOpcodeProfiling starts counting opcodes. Enabling an enabled profile
+// keeps its counts.
+func (vo *VMOrchestrator) EnableOpcodeProfiling(this js.Value, args []js.Value) interface{} {
+	vo.opcodes.CompareAndSwap(nil, &opcodeProfile{counts: make(map[uint32]uint64)})
+	return js.ValueOf(true)
+}
+
+// DisableOpcodeProfiling stops counting and discards the counts
+func (vo *VMOrchestrator) DisableOpcodeProfiling(this js.Value, args []js.Value) interface{} {
+	vo.opcodes.Store(nil)
+	return js.ValueOf(true)
+}
+
+// GetOpcodeProfile returns opcode -> count (keys are decimal opcode
+// strings), or null while profiling is disabled
+func (vo *VMOrchestrator) GetOpcodeProfile(this js.Value, args []js.Value) interface{} {
+	profile := vo.opcodes.Load()
+	if profile == nil {
+		return js.Null()
+	}
+
+	profile.mutex.Lock()
+	defer profile.mutex.Unlock()
+
+	counts := make(map[string]interface{}, len(profile.counts))
+	for opcode, count := range profile.counts {
+		counts[strconv.FormatUint(uint64(opcode), 10)] = count
+	}
+	return js.ValueOf(counts)
+}
+
+// record counts the opcode reported by an executeInstruction result
+func (profile *opcodeProfile) record(result js.Value) {
+	if result.Type() != js.TypeObject {
+		return
+	}
+	opcode := result.Get("opcode")
+	if opcode.Type() != js.TypeNumber {
+		return
+	}
+
+	profile.mutex.Lock()
+	profile.counts[uint32(opcode.Int())]++
+	profile.mutex.Unlock()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+// opcodeBridge reports the opcode for each PC from a repeating pattern of
+// 4-byte instructions starting at base; PCs outside it report no opcode
+func opcodeBridge(t *testing.T, base uint64, pattern []int) js.Value {
+	return newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func(args []js.Value) interface{} {
+			pc := uint64(args[0].Float())
+			if pc < base {
+				return true
+			}
+			return map[string]interface{}{"ok": true, "opcode": pattern[(pc-base)/4%uint64(len(pattern))]}
+		},
+	})
+}
+
+func TestOpcodeProfileCounts(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, opcodeBridge(t, 0x40000000, []int{0x13, 0x13, 0x13, 0x33, 0x33, 0x6f}))
+	profiled := createThread(t, vo, 0x40000000, 1, "paused")
+	legacy := createThread(t, vo, 0x1000, 1, "paused")
+
+	if !call(vo.GetOpcodeProfile).IsNull() {
+		t.Fatal("profile is not null before profiling is enabled")
+	}
+	requireOK(t, call(vo.StepThread, profiled)) // not counted
+	call(vo.EnableOpcodeProfiling)
+	for i := 0; i < 59; i++ {
+		requireOK(t, call(vo.StepThread, profiled))
+	}
+	for i := 0; i < 10; i++ {
+		requireOK(t, call(vo.StepThread, legacy)) // boolean results carry no opcode
+	}
+
+	profile := call(vo.GetOpcodeProfile)
+	want := map[string]int{"19": 29, "51": 20, "111": 10}
+	keys := js.Global().Get("Object").Call("keys", profile)
+	if keys.Length() != len(want) {
+		t.Errorf("profile has %d opcodes, want %d", keys.Length(), len(want))
+	}
+	for opcode, count := range want {
+		if got := profile.Get(opcode); got.IsUndefined() || got.Int() != count {
+			t.Errorf("opcode %s counted %v times, want %d", opcode, got, count)
+		}
+	}
+
+	call(vo.DisableOpcodeProfiling)
+	if !call(vo.GetOpcodeProfile).IsNull() {
+		t.Error("profile is not null after profiling is disabled")
+	}
+	call(vo.EnableOpcodeProfiling)
+	if n := js.Global().Get("Object").Call("keys", call(vo.GetOpcodeProfile)).Length(); n != 0 {
+		t.Errorf("re-enabled profile kept %d opcodes", n)
+	}
+}
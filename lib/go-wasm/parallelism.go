@@ -0,0 +1,45 @@
+// Parallelism
+// Controls how many OS threads may run Go code at once
+//
+// The worker pool (SetMaxWorkers) decides how many quanta can be in flight;
+// GOMAXPROCS decides how many of them actually execute simultaneously.
+// Extra workers beyond the parallelism still help when quanta block (on
+// the speed limit or a bridge call that yields), but they time-slice
+// rather than run in parallel.
+//
+// The js/wasm runtime runs every goroutine on the single JS thread, so
+// parallelism is always 1 there: the runtime keeps GOMAXPROCS at 1 and
+// SetParallelism reports that effective value honestly. Hosts that move the module into a
+// worker still get one thread per module instance; scale out with several
+// orchestrators instead.
+
+package main
+
+import (
+	"runtime"
+	"syscall/js"
+)
+
+// singleThreaded reports whether the runtime schedules every goroutine on
+// one OS thread regardless of GOMAXPROCS
+const singleThreaded = runtime.GOARCH == "wasm"
+
+// SetParallelism sets runtime.GOMAXPROCS. Returns the parallelism now in
+// effect, which is 1 on single-threaded builds whatever n was, or -1 if n
+// is not a positive integer.
+func (vo *VMOrchestrator) SetParallelism(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber || args[0].Int() < 1 {
+		return js.ValueOf(-1)
+	}
+
+	runtime.GOMAXPROCS(args[0].Int())
+	return js.ValueOf(parallelism())
+}
+
+// parallelism returns the number of goroutines that can execute at once
+func parallelism() int {
+	if singleThreaded {
+		return 1
+	}
+	return runtime.GOMAXPROCS(0)
+}
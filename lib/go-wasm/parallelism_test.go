@@ -0,0 +1,35 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestParallelismRoundTrips(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	previous := runtime.GOMAXPROCS(0)
+	t.Cleanup(func() { runtime.GOMAXPROCS(previous) })
+
+	for _, n := range []int{3, 1, 2} {
+		got := call(vo.SetParallelism, n).Int()
+		want := n
+		if singleThreaded {
+			want = 1
+		}
+		if got != want {
+			t.Errorf("setParallelism(%d) = %d, want %d", n, got, want)
+		}
+		if procs := runtime.GOMAXPROCS(0); !singleThreaded && procs != n {
+			t.Errorf("setParallelism(%d) left GOMAXPROCS at %d", n, procs)
+		}
+		if reported := stat(vo, "parallelism"); reported != float64(got) {
+			t.Errorf("GetStats reports parallelism %v after setParallelism returned %d", reported, got)
+		}
+	}
+
+	for _, bad := range []interface{}{0, -2, "4"} {
+		if got := call(vo.SetParallelism, bad).Int(); got != -1 {
+			t.Errorf("setParallelism(%v) = %d, want -1", bad, got)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// VM Pause
+// Freezes execution without tearing down threads
+//
+// Stop terminates every thread. Pause instead parks the worker pool: no new
+// quanta are dispatched, and a quantum in progress blocks on schedCond at
+// its next instruction boundary, so thread state is preserved exactly and
+// Resume continues where execution left off. The VM still counts as running
+// while paused (IsRunning is true); IsPaused tells the two apart. The
+// execution clock stops while paused.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"time"
+)
+
+// Pause freezes execution of every thread
+func (vo *VMOrchestrator) Pause(this js.Value, args []js.Value) interface{} {
+	switch vo.pause() {
+	case errNotRunning:
+		return vo.fail(false, errNotRunning, "the VM is not running")
+	case errInvalidState:
+		return vo.fail(false, errInvalidState, "the VM is already paused")
+	}
+	return vo.succeed(true, nil)
+}
+
+// pause freezes execution and stops the execution clock. Returns "" on
+// success, errNotRunning or errInvalidState (already paused).
+func (vo *VMOrchestrator) pause() string {
+	vo.schedMutex.Lock()
+	if atomic.LoadInt32(&vo.isRunning) != 1 {
+		vo.schedMutex.Unlock()
+		return errNotRunning
+	}
+	if !atomic.CompareAndSwapInt32(&vo.paused, 0, 1) {
+		vo.schedMutex.Unlock()
+		return errInvalidState
+	}
+	vo.schedMutex.Unlock()
+
+	vo.statsMutex.Lock()
+	vo.stats.SetClock(time.Now(), false)
+	vo.statsMutex.Unlock()
+
+	return ""
+}
+
+// Resume continues execution after Pause
+func (vo *VMOrchestrator) Resume(this js.Value, args []js.Value) interface{} {
+	vo.schedMutex.Lock()
+	if !atomic.CompareAndSwapInt32(&vo.paused, 1, 0) {
+		vo.schedMutex.Unlock()
+		return vo.fail(false, errInvalidState, "the VM is not paused")
+	}
+
+	// Restart the clock before any worker can run
+	vo.statsMutex.Lock()
+	vo.stats.SetClock(time.Now(), atomic.LoadInt32(&vo.isRunning) == 1)
+	vo.statsMutex.Unlock()
+	vo.schedMutex.Unlock()
+	vo.schedCond.Broadcast()
+
+	return vo.succeed(true, nil)
+}
+
+// IsPaused returns whether execution is frozen by Pause
+func (vo *VMOrchestrator) IsPaused(this js.Value, args []js.Value) interface{} {
+	return js.ValueOf(atomic.LoadInt32(&vo.paused) == 1)
+}
+
+// clearPause lifts a pause when the VM stops so the next Start runs freely
+func (vo *VMOrchestrator) clearPause() {
+	vo.schedMutex.Lock()
+	atomic.StoreInt32(&vo.paused, 0)
+	vo.schedMutex.Unlock()
+}
+
+// mustPark reports whether a quantum in progress should park at the current
+// instruction boundary
+func (vo *VMOrchestrator) mustPark() bool {
+	return atomic.LoadInt32(&vo.paused) == 1 || atomic.LoadInt32(&vo.safepoint) == 1
+}
+
+// parkAtBoundary blocks a quantum in progress until the VM is resumed and no
+// safe point is held. Parked quanta are counted so RequestSafepoint knows
+// when every thread has stopped. Returns false if the VM stopped instead.
+func (vo *VMOrchestrator) parkAtBoundary() bool {
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+
+	vo.parked++
+	vo.schedCond.Broadcast()
+	for vo.mustPark() && atomic.LoadInt32(&vo.isRunning) == 1 {
+		vo.schedCond.Wait()
+	}
+	vo.parked--
+	return atomic.LoadInt32(&vo.isRunning) == 1
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// threadState is the state Pause must leave untouched
+type threadState struct {
+	pc        uint64
+	registers []uint32
+}
+
+// captureThreads records every thread's PC and registers
+func captureThreads(vo *VMOrchestrator) map[int]threadState {
+	vo.threadMutex.RLock()
+	threads := vo.threads.Sorted()
+	vo.threadMutex.RUnlock()
+
+	states := make(map[int]threadState, len(threads))
+	for _, thread := range threads {
+		thread.mutex.RLock()
+		states[thread.id] = threadState{thread.pc, append([]uint32(nil), thread.registers...)}
+		thread.mutex.RUnlock()
+	}
+	return states
+}
+
+func TestPauseResumePreservesState(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+	id := createThread(t, vo, 0x40000000)
+	requireOK(t, call(vo.SetRegister, id, 5, 0xabcd))
+	eventually(t, "both threads to run", func() bool { return threadPC(t, vo, 1) > 0x1000 && threadPC(t, vo, id) > 0x40000000 })
+
+	requireOK(t, call(vo.Pause))
+	if !call(vo.IsRunning).Bool() || !call(vo.IsPaused).Bool() {
+		t.Fatal("a paused VM must report running and paused")
+	}
+	// An in-flight instruction may still land before the worker parks
+	time.Sleep(10 * time.Millisecond)
+	paused, clock := captureThreads(vo), stat(vo, "executionTime")
+
+	time.Sleep(100 * time.Millisecond)
+	if got := captureThreads(vo); !reflect.DeepEqual(got, paused) {
+		t.Fatalf("thread state changed while paused:\n%v\n%v", paused, got)
+	}
+	if got := stat(vo, "executionTime"); got-clock > 5 {
+		t.Errorf("executionTime advanced %vms while paused", got-clock)
+	}
+
+	requireOK(t, call(vo.Resume))
+	if call(vo.IsPaused).Bool() {
+		t.Error("IsPaused is true after Resume")
+	}
+	// Execution continues from exactly where it stopped
+	for threadID, state := range paused {
+		if got := threadPC(t, vo, threadID); got < state.pc {
+			t.Errorf("thread %d resumed at %#x, before %#x", threadID, got, state.pc)
+		}
+	}
+	eventually(t, "execution to continue", func() bool { return threadPC(t, vo, id) > paused[id].pc })
+	if got := call(vo.GetRegisters, id).Index(5).Int(); got != 0xabcd {
+		t.Errorf("r5 = %#x after resume, want 0xabcd", got)
+	}
+}
@@ -0,0 +1,144 @@
+// Group CPU Quotas
+// Caps the CPU share of a thread group so one guest app cannot starve others
+//
+// SetGroupCpuQuota(groupID, percent) limits a group's members to percent of
+// the CPU time available in each 100ms window (window length times the
+// effective parallelism). Quantum CPU time is charged to the group when the
+// quantum ends; once the group has used its share, workers skip its threads
+// until the window rolls over. Groups without a quota run unconstrained.
+//
+// A quantum is never cut short, so a group can overshoot its share by up to
+// one quantum per worker. Quotas are ignored in deterministic mode, whose
+// thread order must not depend on wall-clock time.
+
+package main
+
+import (
+	"math"
+	"syscall/js"
+	"time"
+)
+
+// cpuQuotaWindow is the period over which group CPU time is measured
+const cpuQuotaWindow = 100 * time.Millisecond
+
+// groupQuota is a group's CPU share and its use in the current window
+type groupQuota struct {
+	share       float64 // fraction of the window's CPU time, 0..1
+	used        time.Duration
+	windowStart time.Time
+	wake        *time.Timer // wakes workers when the window rolls over, nil if not armed
+}
+
+// SetGroupCpuQuota limits a thread group's CPU share, or removes the limit
+// when passed null
+// Arguments: groupID, percent (0 < percent <= 100) or null
+func (vo *VMOrchestrator) SetGroupCpuQuota(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(false, errInvalidArgument, "setGroupCpuQuota requires a group ID and a percentage")
+	}
+
+	groupID := args[0].Int()
+	remove := args[1].IsNull() || args[1].IsUndefined()
+	var percent float64
+	if !remove {
+		if args[1].Type() != js.TypeNumber {
+			return vo.fail(false, errInvalidArgument, "percent must be a number or null")
+		}
+		percent = args[1].Float()
+		if !(percent > 0 && percent <= 100) {
+			return vo.fail(false, errOutOfRange, "percent %v is outside (0, 100]", percent)
+		}
+	}
+
+	vo.groupMutex.Lock()
+	_, ok := vo.threadGroups[groupID]
+	vo.groupMutex.Unlock()
+	if !ok {
+		return vo.fail(false, errUnknownGroup, "thread group %d does not exist", groupID)
+	}
+
+	vo.schedMutex.Lock()
+	if quota := vo.groupQuotas[groupID]; quota != nil && quota.wake != nil {
+		quota.wake.Stop()
+	}
+	if remove {
+		delete(vo.groupQuotas, groupID)
+	} else {
+		vo.groupQuotas[groupID] = &groupQuota{share: percent / 100, windowStart: time.Now()}
+	}
+	vo.schedMutex.Unlock()
+
+	// Threads skipped under the old quota may be dispatchable now
+	vo.schedCond.Broadcast()
+	return vo.succeed(true, nil)
+}
+
+// clearGroupQuotas drops every quota when the thread groups are reset
+func (vo *VMOrchestrator) clearGroupQuotas() {
+	vo.schedMutex.Lock()
+	for _, quota := range vo.groupQuotas {
+		if quota.wake != nil {
+			quota.wake.Stop()
+		}
+	}
+	vo.groupQuotas = make(map[int]*groupQuota)
+	vo.schedMutex.Unlock()
+}
+
+// overQuota reports whether the thread's group has used its CPU share for
+// the current window, arming a wakeup for when the window rolls over.
+// Caller must hold schedMutex.
+func (vo *VMOrchestrator) overQuota(thread *VMThread, now time.Time) bool {
+	if len(vo.groupQuotas) == 0 {
+		return false
+	}
+
+	thread.mutex.RLock()
+	groupID := thread.groupID
+	thread.mutex.RUnlock()
+
+	quota := vo.groupQuotas[groupID]
+	if quota == nil {
+		return false
+	}
+	quota.roll(now)
+
+	budget := time.Duration(math.Round(quota.share * float64(cpuQuotaWindow) * float64(parallelism())))
+	if quota.used < budget {
+		return false
+	}
+
+	if quota.wake == nil {
+		quota.wake = time.AfterFunc(quota.windowStart.Add(cpuQuotaWindow).Sub(now), func() {
+			vo.schedMutex.Lock()
+			quota.wake = nil
+			vo.schedMutex.Unlock()
+			vo.schedCond.Broadcast()
+		})
+	}
+	return true
+}
+
+// chargeGroup adds a quantum's CPU time to its group's quota, if any
+func (vo *VMOrchestrator) chargeGroup(groupID int, elapsed time.Duration) {
+	if groupID == 0 {
+		return
+	}
+
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+
+	if quota := vo.groupQuotas[groupID]; quota != nil {
+		quota.roll(time.Now())
+		quota.used += elapsed
+	}
+}
+
+// roll starts a new window if the current one has ended
+func (quota *groupQuota) roll(now time.Time) {
+	if now.Sub(quota.windowStart) >= cpuQuotaWindow {
+		quota.windowStart = now
+		quota.used = 0
+	}
+}
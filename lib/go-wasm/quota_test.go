@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+func TestGroupCpuQuotas(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	var counts [2]int64 // instructions run by the 25% and 75% groups
+	call(vo.Initialize, newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func(args []js.Value) interface{} {
+			// Burn CPU so quanta are long enough for the quotas to bite
+			for start := time.Now(); time.Since(start) < 20*time.Microsecond; {
+			}
+			if args[0].Float() >= 0x50000000 {
+				atomic.AddInt64(&counts[1], 1)
+			} else {
+				atomic.AddInt64(&counts[0], 1)
+			}
+			return true
+		},
+	}))
+	requireOK(t, call(vo.Start))
+	requireOK(t, call(vo.KillThread, 1)) // only the two groups compete
+
+	noStart := map[string]interface{}{"autostart": false}
+	small, large := call(vo.CreateThreadGroup).Int(), call(vo.CreateThreadGroup).Int()
+	a := createThread(t, vo, 0x40000000, 1, "running", "", noStart)
+	b := createThread(t, vo, 0x50000000, 1, "running", "", noStart)
+	requireOK(t, call(vo.AddThreadToGroup, small, a))
+	requireOK(t, call(vo.AddThreadToGroup, large, b))
+	requireOK(t, call(vo.SetGroupCpuQuota, small, 25))
+	requireOK(t, call(vo.SetGroupCpuQuota, large, 75))
+	requireOK(t, call(vo.StartThread, a))
+	requireOK(t, call(vo.StartThread, b))
+
+	time.Sleep(50 * time.Millisecond)
+	before := [2]int64{atomic.LoadInt64(&counts[0]), atomic.LoadInt64(&counts[1])}
+	time.Sleep(4 * cpuQuotaWindow)
+	smallRan, largeRan := atomic.LoadInt64(&counts[0])-before[0], atomic.LoadInt64(&counts[1])-before[1]
+
+	// Quanta are never cut short, so each group may overshoot its budget by a
+	// quantum per window; the split still has to be well short of even
+	share := float64(smallRan) / float64(smallRan+largeRan)
+	if share < 0.15 || share > 0.38 {
+		t.Errorf("the 25%% group ran %d of %d instructions (%.0f%%)", smallRan, smallRan+largeRan, share*100)
+	}
+}
+
+func TestSetGroupCpuQuotaValidates(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	group := call(vo.CreateThreadGroup).Int()
+	requireError(t, call(vo.SetGroupCpuQuota, group, 0), errOutOfRange)
+	requireError(t, call(vo.SetGroupCpuQuota, group, 101), errOutOfRange)
+	requireError(t, call(vo.SetGroupCpuQuota, group, "50"), errInvalidArgument)
+	requireError(t, call(vo.SetGroupCpuQuota, 99, 50), errUnknownGroup)
+	requireOK(t, call(vo.SetGroupCpuQuota, group, 50))
+	requireOK(t, call(vo.SetGroupCpuQuota, group, js.Null()))
+}
@@ -0,0 +1,280 @@
+// Memory Regions
+// Emulates mmap'd guest memory with read/write/execute protection
+//
+// MapRegion registers [base, base+size) with a permission bitmask. Once any
+// region is mapped, the orchestrator enforces the map: an instruction fetch
+// from memory that is not executable, or a memory access reported by the
+// bridge that is not permitted, faults the thread segfault-style with the
+// offending address. With no regions mapped, memory is unrestricted.
+//
+// Bridges report accesses in the executeInstruction result as
+// accesses: [{ address, type }], where type is "read" or "write". Batches
+// cannot report per-instruction accesses, so batching is disabled while
+// regions are mapped. Bridges that would rather refuse an access than
+// perform it can call checkAccess themselves first.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"syscall/js"
+)
+
+// Region permission bits, matching PROT_READ, PROT_WRITE and PROT_EXEC
+const (
+	permRead    = 1
+	permWrite   = 2
+	permExecute = 4
+	permAll     = permRead | permWrite | permExecute
+)
+
+// memoryRegion is a mapped address range; last is inclusive so a region may
+// end at the top of the address space
+type memoryRegion struct {
+	base  uint64
+	last  uint64
+	perms int
+}
+
+// accessPerms maps an access type name to its permission bit
+var accessPerms = map[string]int{
+	"read":    permRead,
+	"write":   permWrite,
+	"execute": permExecute,
+}
+
+// MapRegion maps a guest memory region
+// Arguments: base, size, perms (bitmask: 1 read, 2 write, 4 execute)
+func (vo *VMOrchestrator) MapRegion(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return vo.fail(false, errInvalidArgument, "mapRegion requires a base, a size and permissions")
+	}
+
+	base, ok := jsToAddress(args[0])
+	if !ok {
+		return vo.fail(false, errInvalidArgument, "invalid base address")
+	}
+	size, ok := jsToAddress(args[1])
+	if !ok || size == 0 {
+		return vo.fail(false, errInvalidArgument, "size must be a positive integer")
+	}
+	if base+(size-1) < base {
+		return vo.fail(false, errOutOfRange, "region overflows the address space")
+	}
+	perms := args[2].Int()
+	if perms&^permAll != 0 {
+		return vo.fail(false, errInvalidArgument, "unknown permission bits %#x", perms&^permAll)
+	}
+
+	region := memoryRegion{base: base, last: base + (size - 1), perms: perms}
+
+	vo.regionMutex.Lock()
+	defer vo.regionMutex.Unlock()
+
+	// Neighbours in base order are the only candidates for overlap
+	i := sort.Search(len(vo.regions), func(i int) bool { return vo.regions[i].base > base })
+	if i > 0 && vo.regions[i-1].last >= base {
+		return vo.fail(false, errRegionOverlap, "region overlaps the region at %#x", vo.regions[i-1].base)
+	}
+	if i < len(vo.regions) && vo.regions[i].base <= region.last {
+		return vo.fail(false, errRegionOverlap, "region overlaps the region at %#x", vo.regions[i].base)
+	}
+
+	vo.regions = append(vo.regions, memoryRegion{})
+	copy(vo.regions[i+1:], vo.regions[i:])
+	vo.regions[i] = region
+	return vo.succeed(true, nil)
+}
+
+// UnmapRegion removes the region mapped at exactly base
+func (vo *VMOrchestrator) UnmapRegion(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(false, errInvalidArgument, "unmapRegion requires a base address")
+	}
+
+	base, ok := jsToAddress(args[0])
+	if !ok {
+		return vo.fail(false, errInvalidArgument, "invalid base address")
+	}
+
+	vo.regionMutex.Lock()
+	defer vo.regionMutex.Unlock()
+
+	i := sort.Search(len(vo.regions), func(i int) bool { return vo.regions[i].base >= base })
+	if i == len(vo.regions) || vo.regions[i].base != base {
+		return vo.fail(false, errUnknownRegion, "no region is mapped at %#x", base)
+	}
+	vo.regions = append(vo.regions[:i], vo.regions[i+1:]...)
+	return vo.succeed(true, nil)
+}
+
+// CheckAccess reports whether an access is permitted by the memory map
+// Arguments: address, accessType ("read", "write" or "execute")
+func (vo *VMOrchestrator) CheckAccess(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[1].Type() != js.TypeString {
+		return js.ValueOf(false)
+	}
+
+	address, ok := jsToAddress(args[0])
+	perm := accessPerms[args[1].String()]
+	if !ok || perm == 0 {
+		return js.ValueOf(false)
+	}
+	return js.ValueOf(vo.accessAllowed(address, perm))
+}
+
+// ValidateAddress checks an address against the memory map without
+// performing an access: it must lie in a region granting the access, and an
+// "execute" address must also be aligned to the instruction width. With no
+// regions mapped every address is valid.
+// Arguments: address, accessType ("read", "write" or "execute")
+func (vo *VMOrchestrator) ValidateAddress(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[1].Type() != js.TypeString {
+		return vo.fail(false, errInvalidArgument, "validateAddress requires an address and an access type")
+	}
+
+	address, ok := jsToAddress(args[0])
+	if !ok {
+		return vo.fail(false, errInvalidArgument, "invalid address")
+	}
+	perm := accessPerms[args[1].String()]
+	if perm == 0 {
+		return vo.fail(false, errInvalidArgument, "unknown access type %q", args[1].String())
+	}
+
+	if code, message := vo.validateAddress(address, perm); code != "" {
+		return vo.fail(false, code, "%s", message)
+	}
+	return vo.succeed(true, nil)
+}
+
+// ListRegions returns the mapped regions as { base, size, perms } in
+// address order
+func (vo *VMOrchestrator) ListRegions(this js.Value, args []js.Value) interface{} {
+	vo.regionMutex.RLock()
+	defer vo.regionMutex.RUnlock()
+
+	regions := make([]interface{}, len(vo.regions))
+	for i, region := range vo.regions {
+		regions[i] = map[string]interface{}{
+			"base":  addressToJS(region.base),
+			"size":  addressToJS(region.last - region.base + 1),
+			"perms": region.perms,
+		}
+	}
+	return js.ValueOf(regions)
+}
+
+// regionsMapped reports whether the memory map is being enforced
+func (vo *VMOrchestrator) regionsMapped() bool {
+	vo.regionMutex.RLock()
+	defer vo.regionMutex.RUnlock()
+	return len(vo.regions) > 0
+}
+
+// accessAllowed reports whether the region containing address grants perm.
+// An empty map allows everything.
+func (vo *VMOrchestrator) accessAllowed(address uint64, perm int) bool {
+	vo.regionMutex.RLock()
+	defer vo.regionMutex.RUnlock()
+
+	if len(vo.regions) == 0 {
+		return true
+	}
+
+	// The first region ending at or after address is the only one that
+	// can contain it
+	i := sort.Search(len(vo.regions), func(i int) bool { return vo.regions[i].last >= address })
+	return i < len(vo.regions) && vo.regions[i].base <= address && vo.regions[i].perms&perm != 0
+}
+
+// validateAddress is ValidateAddress for a single permission bit. Returns an
+// error code and message, or "" if the address is valid.
+func (vo *VMOrchestrator) validateAddress(address uint64, perm int) (code, message string) {
+	if !vo.regionsMapped() {
+		return "", ""
+	}
+	if !vo.accessAllowed(address, perm) {
+		return errAccessDenied, fmt.Sprintf("%#x is not in a region with %s permission", address, permName(perm))
+	}
+	if perm == permExecute && address%defaultInstructionLength != 0 {
+		return errInvalidArgument, fmt.Sprintf("%#x is not aligned to the %d-byte instruction width", address, defaultInstructionLength)
+	}
+	return "", ""
+}
+
+// permName returns the access type name of a single permission bit
+func permName(perm int) string {
+	for name, bit := range accessPerms {
+		if bit == perm {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// checkAccesses validates the accesses an executeInstruction result reports
+// and faults the thread on the first one the memory map does not permit.
+// Returns false if the thread faulted.
+func (vo *VMOrchestrator) checkAccesses(thread *VMThread, result js.Value) bool {
+	if result.Type() != js.TypeObject {
+		return true
+	}
+	accesses := result.Get("accesses")
+	if accesses.Type() != js.TypeObject {
+		return true
+	}
+
+	for i := 0; i < accesses.Length(); i++ {
+		access := accesses.Index(i)
+		kind := access.Get("type")
+		address, ok := jsToAddress(access.Get("address"))
+		if !ok || kind.Type() != js.TypeString || accessPerms[kind.String()] == 0 {
+			vo.faultThread(thread, "malformed memory access report")
+			return false
+		}
+		if !vo.accessAllowed(address, accessPerms[kind.String()]) {
+			vo.segfault(thread, address, kind.String())
+			return false
+		}
+	}
+	return true
+}
+
+// faultAddressToJS converts a thread's fault address to JS, or null if the
+// thread did not fault on a memory access
+func faultAddressToJS(address *uint64) interface{} {
+	if address == nil {
+		return nil
+	}
+	return addressToJS(*address)
+}
+
+// segfault faults a thread for an access the memory map does not permit
+func (vo *VMOrchestrator) segfault(thread *VMThread, address uint64, access string) {
+	vo.faultThreadAt(thread, fmt.Sprintf("segmentation fault: %s at %#x", access, address), &address)
+}
+
+// rangeAllowed reports whether every byte of [address, last] lies in regions
+// granting perm, or else the first address that does not. An empty map
+// allows everything.
+func (vo *VMOrchestrator) rangeAllowed(address, last uint64, perm int) (uint64, bool) {
+	vo.regionMutex.RLock()
+	defer vo.regionMutex.RUnlock()
+
+	if len(vo.regions) == 0 {
+		return 0, true
+	}
+
+	for {
+		i := sort.Search(len(vo.regions), func(i int) bool { return vo.regions[i].last >= address })
+		if i == len(vo.regions) || vo.regions[i].base > address || vo.regions[i].perms&perm == 0 {
+			return address, false
+		}
+		if vo.regions[i].last >= last {
+			return 0, true
+		}
+		address = vo.regions[i].last + 1 // continues in the adjacent region, if any
+	}
+}
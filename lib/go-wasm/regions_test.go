@@ -0,0 +1,111 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestMapRegionRejectsOverlaps(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	requireOK(t, call(vo.MapRegion, 0x1000, 0x1000, permRead))
+	requireOK(t, call(vo.MapRegion, 0x3000, 0x1000, permRead))
+
+	for _, region := range [][2]int{
+		{0x1000, 0x1000}, // identical
+		{0x0800, 0x1000}, // runs into the first region
+		{0x1fff, 0x10},   // starts on the first region's last byte
+		{0x2800, 0x1000}, // runs into the second region
+		{0x0000, 0x8000}, // covers both
+	} {
+		requireError(t, call(vo.MapRegion, region[0], region[1], permRead), errRegionOverlap)
+	}
+
+	// Adjacent regions do not overlap
+	requireOK(t, call(vo.MapRegion, 0x2000, 0x1000, permRead))
+	if got := call(vo.ListRegions).Length(); got != 3 {
+		t.Errorf("%d regions mapped, want 3", got)
+	}
+
+	requireOK(t, call(vo.UnmapRegion, 0x2000))
+	requireError(t, call(vo.UnmapRegion, 0x2000), errUnknownRegion)
+}
+
+func TestCheckAccessHonoursPermissions(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	if !call(vo.CheckAccess, 0x1000, "write").Bool() {
+		t.Fatal("access was denied with no regions mapped")
+	}
+
+	requireOK(t, call(vo.MapRegion, 0x1000, 0x1000, permRead|permExecute))
+	requireOK(t, call(vo.MapRegion, 0x8000, 0x1000, permRead|permWrite))
+
+	tests := []struct {
+		address int
+		access  string
+		want    bool
+	}{
+		{0x1000, "read", true},
+		{0x1ffc, "execute", true},
+		{0x1000, "write", false},
+		{0x8000, "write", true},
+		{0x8000, "execute", false},
+		{0x4000, "read", false}, // unmapped
+		{0x1000, "delete", false},
+	}
+	for _, test := range tests {
+		if got := call(vo.CheckAccess, test.address, test.access).Bool(); got != test.want {
+			t.Errorf("checkAccess(%#x, %s) = %v, want %v", test.address, test.access, got, test.want)
+		}
+	}
+}
+
+func TestWriteToReadOnlyRegionSegfaults(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	const target = 0x1010
+	call(vo.Initialize, newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func([]js.Value) interface{} {
+			return map[string]interface{}{
+				"ok":       true,
+				"accesses": []interface{}{map[string]interface{}{"address": target, "type": "write"}},
+			}
+		},
+	}))
+	requireOK(t, call(vo.MapRegion, 0x1000, 0x1000, permRead|permExecute))
+
+	id := createThread(t, vo, 0x1000, 1, "paused")
+	call(vo.StepThread, id)
+
+	if got := threadStatus(vo, id); got != "faulted" {
+		t.Fatalf("thread is %q after a write to read-only memory, want faulted", got)
+	}
+	address, ok := jsToAddress(statsThread(vo, id).Get("faultAddress"))
+	if !ok || address != target {
+		t.Errorf("fault address = %#x, want %#x", address, target)
+	}
+}
+
+func TestCreateThreadValidatesStartPC(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	// With no regions mapped any start address is accepted
+	createThread(t, vo, 0x9002, 1, "paused")
+
+	requireOK(t, call(vo.MapRegion, 0x1000, 0x1000, permRead|permExecute))
+	requireOK(t, call(vo.MapRegion, 0x8000, 0x1000, permRead|permWrite))
+	createThread(t, vo, 0x1000, 1, "paused")
+	requireError(t, call(vo.CreateThread, 0x1002, 1, "paused"), errInvalidArgument)
+	requireError(t, call(vo.CreateThread, 0x4000, 1, "paused"), errAccessDenied)
+	requireError(t, call(vo.CreateThread, 0x8000, 1, "paused"), errAccessDenied)
+
+	// Breakpoints go through the same check
+	if !call(vo.SetBreakpoint, 0x1004).Bool() {
+		t.Error("setBreakpoint refused an executable address")
+	}
+	for _, address := range []uint64{0x1002, 0x4000, 0x8000} {
+		if call(vo.SetBreakpoint, address).Bool() {
+			t.Errorf("setBreakpoint accepted %#x", address)
+		}
+	}
+	requireOK(t, call(vo.ValidateAddress, 0x8004, "write"))
+	requireError(t, call(vo.ValidateAddress, 0x8004, "execute"), errAccessDenied)
+	requireError(t, call(vo.ValidateAddress, 0x1000, "jump"), errInvalidArgument)
+}
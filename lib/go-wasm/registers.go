@@ -0,0 +1,101 @@
+// Register access for debuggers and test harnesses
+//
+// Reads and writes take the thread mutex, so they are safe while the thread
+// is running. A write may still race with the next executed instruction:
+// the emulator can overwrite the value before the caller observes it.
+// Suspend the thread first for deterministic results.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+const (
+	// defaultRegisterCount is the register file size when CreateOrchestrator
+	// is given no registerCount option (32-bit ARM's r0-r15)
+	defaultRegisterCount = 16
+	// maxRegisterCount bounds the registerCount option
+	maxRegisterCount = 256
+)
+
+// GetRegisters returns a thread's register file as a JS array, or null if
+// the thread does not exist
+func (vo *VMOrchestrator) GetRegisters(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.Null()
+	}
+
+	thread := vo.getThread(args[0].Int())
+	if thread == nil {
+		return js.Null()
+	}
+
+	thread.mutex.RLock()
+	defer thread.mutex.RUnlock()
+	return js.ValueOf(uint32sToJS(thread.registers))
+}
+
+// SetRegister writes a single register
+// Arguments: threadID, index (0 to registerCount-1), value
+func (vo *VMOrchestrator) SetRegister(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return vo.fail(false, errInvalidArgument, "setRegister requires a thread ID, register index and value")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	index := args[1].Int()
+	if index < 0 || index >= len(thread.registers) {
+		return vo.fail(false, errOutOfRange, "register %d is outside 0-%d", index, len(thread.registers)-1)
+	}
+
+	thread.mutex.Lock()
+	thread.registers[index] = uint32(args[2].Int())
+	thread.mutex.Unlock()
+
+	return vo.succeed(true, nil)
+}
+
+// GetErrno returns a thread's errno, or null if the thread does not exist.
+// errno is kept apart from the register file, so guest code clobbering
+// registers between a failed call and its check does not lose it.
+func (vo *VMOrchestrator) GetErrno(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.Null()
+	}
+
+	thread := vo.getThread(args[0].Int())
+	if thread == nil {
+		return js.Null()
+	}
+
+	thread.mutex.RLock()
+	defer thread.mutex.RUnlock()
+	return js.ValueOf(thread.errno)
+}
+
+// SetErrno sets a thread's errno, e.g. from the syscall bridge after a
+// failed operation
+// Arguments: threadID, value
+func (vo *VMOrchestrator) SetErrno(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[1].Type() != js.TypeNumber {
+		return vo.fail(false, errInvalidArgument, "setErrno requires a thread ID and a value")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.Lock()
+	thread.errno = uint32(args[1].Int())
+	thread.mutex.Unlock()
+
+	return vo.succeed(true, nil)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRegisterRoundTripOnSuspendedThread(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+
+	threadID := createThread(t, vo, 0x2000)
+	requireOK(t, call(vo.SuspendThread, threadID))
+
+	want := map[int]uint32{0: 1, 3: 0xdeadbeef, 15: 0xffffffff}
+	for index, value := range want {
+		requireOK(t, call(vo.SetRegister, threadID, index, value))
+	}
+
+	registers := call(vo.GetRegisters, threadID)
+	if registers.Length() != defaultRegisterCount {
+		t.Fatalf("got %d registers, want %d", registers.Length(), defaultRegisterCount)
+	}
+	for index := 0; index < registers.Length(); index++ {
+		if got := uint32(registers.Index(index).Int()); got != want[index] {
+			t.Errorf("r%d = %#x, want %#x", index, got, want[index])
+		}
+	}
+}
+
+func TestSetRegisterRejectsBadTargets(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	threadID := createThread(t, vo, 0x2000)
+
+	requireError(t, call(vo.SetRegister, threadID, defaultRegisterCount, 1), errOutOfRange)
+	requireError(t, call(vo.SetRegister, threadID, -1, 1), errOutOfRange)
+	requireError(t, call(vo.SetRegister, threadID+1, 0, 1), errUnknownThread)
+	if registers := call(vo.GetRegisters, threadID+1); !registers.IsNull() {
+		t.Errorf("registers of an unknown thread are %v, want null", registers)
+	}
+}
+
+func TestErrnoIsPerThread(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+
+	a, b := createThread(t, vo, 0x2000), createThread(t, vo, 0x3000)
+	requireOK(t, call(vo.SetErrno, a, 2))
+	requireOK(t, call(vo.SetErrno, b, 13))
+	// Clobbering the register file leaves errno alone
+	requireOK(t, call(vo.SuspendThread, a))
+	requireOK(t, call(vo.SetRegister, a, 0, 0xffffffff))
+	requireOK(t, call(vo.ResumeThread, a))
+
+	for id, want := range map[int]uint32{a: 2, b: 13} {
+		if got := uint32(call(vo.GetErrno, id).Int()); got != want {
+			t.Errorf("thread %d errno = %d, want %d", id, got, want)
+		}
+		if got := uint32(call(vo.GetThread, id).Get("errno").Int()); got != want {
+			t.Errorf("getThread(%d).errno = %d, want %d", id, got, want)
+		}
+	}
+	if got := call(vo.GetErrno, 1).Int(); got != 0 {
+		t.Errorf("untouched main thread errno = %d, want 0", got)
+	}
+	if errno := call(vo.GetErrno, 99); !errno.IsNull() {
+		t.Errorf("errno of an unknown thread is %v, want null", errno)
+	}
+	requireError(t, call(vo.SetErrno, 99, 1), errUnknownThread)
+}
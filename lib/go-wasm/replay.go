@@ -0,0 +1,170 @@
+// Trace Replay
+// Re-executes a captured instruction trace on a thread for analysis
+//
+// ReplayTrace takes the array GetTrace returns, possibly captured in
+// another environment, and drives one thread through the recorded PCs with
+// the VM stopped: before each entry the thread's PC is set to the recorded
+// one and the instruction is executed through the bridge. Entries of other
+// recorded threads are skipped, so a trace interleaving several threads
+// replays the first entry's thread only. Breakpoints, interrupts and the
+// scheduler are bypassed; memory permissions, watches and faults apply as
+// in normal execution.
+//
+// The replay reports where the thread would have left the recorded path
+// (the PC after an instruction differs from the next recorded PC) and, when
+// given the original final state, which registers and whether the PC match.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+
+	"github.com/aquifer/vm-orchestrator/internal/engine"
+)
+
+// replayEntry is one recorded instruction to re-execute
+type replayEntry struct {
+	threadID int // -1 if the entry does not name a thread
+	pc       uint64
+}
+
+// ReplayTrace re-executes a trace on a thread while the VM is stopped.
+// Arguments: threadID, trace ([{ threadID, pc }] as from GetTrace), and
+// optionally the expected final state { pc, registers }.
+// Returns { executed, divergedAt, pc, registers, pcMatches, mismatches }:
+// divergedAt is the index of the first entry whose successor PC was not the
+// recorded one (-1 if none), and pcMatches and mismatches ([{ index,
+// expected, actual }]) are null without an expected state. The replay stops
+// early if the thread faults or terminates. Legacy mode returns executed.
+func (vo *VMOrchestrator) ReplayTrace(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || !args[1].InstanceOf(js.Global().Get("Array")) {
+		return vo.fail(-1, errInvalidArgument, "replayTrace requires a thread ID and a trace array")
+	}
+	if !vo.isStopped() {
+		return vo.fail(-1, errAlreadyRunning, "traces can only be replayed while the VM is stopped")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(-1, threadID)
+	}
+
+	entries, message := replayEntriesFromJS(args[1])
+	if message != "" {
+		return vo.fail(-1, errInvalidArgument, "%s", message)
+	}
+
+	executed, divergedAt := 0, -1
+	for i, entry := range entries {
+		thread.mutex.Lock()
+		if !engine.Active(thread.status) {
+			thread.mutex.Unlock()
+			break
+		}
+		thread.pc = entry.pc
+		thread.mutex.Unlock()
+
+		// One instruction at a time, through step so a throwing bridge
+		// faults the thread instead of escaping
+		_, ok := vo.step(thread, entry.pc, 1)
+		executed++
+		if !ok {
+			break
+		}
+
+		if divergedAt < 0 && i+1 < len(entries) {
+			thread.mutex.RLock()
+			next := thread.pc
+			thread.mutex.RUnlock()
+			if next != entries[i+1].pc {
+				divergedAt = i
+			}
+		}
+	}
+
+	thread.mutex.RLock()
+	pc := thread.pc
+	registers := append([]uint32(nil), thread.registers...)
+	thread.mutex.RUnlock()
+
+	var pcMatches, mismatches interface{}
+	if len(args) > 2 && args[2].Type() == js.TypeObject {
+		pcMatches, mismatches = compareReplay(args[2], pc, registers)
+	}
+
+	return vo.succeed(executed, map[string]interface{}{
+		"executed":   executed,
+		"divergedAt": divergedAt,
+		"pc":         addressToJS(pc),
+		"registers":  uint32sToJS(registers),
+		"pcMatches":  pcMatches,
+		"mismatches": mismatches,
+	})
+}
+
+// isStopped reports whether the VM is neither running nor stopping
+func (vo *VMOrchestrator) isStopped() bool {
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+	return vo.inFlight == 0 && atomic.LoadInt32(&vo.isRunning) == 0
+}
+
+// replayEntriesFromJS reads a trace array, keeping only the entries of the
+// thread named by the first entry. Returns an error message, or "" if the
+// trace is valid.
+func replayEntriesFromJS(trace js.Value) ([]replayEntry, string) {
+	entries := make([]replayEntry, 0, trace.Length())
+	for i := 0; i < trace.Length(); i++ {
+		v := trace.Index(i)
+		if v.Type() != js.TypeObject {
+			return nil, "trace entries must be objects"
+		}
+
+		entry := replayEntry{threadID: -1}
+		var ok bool
+		if entry.pc, ok = jsToAddress(v.Get("pc")); !ok {
+			return nil, "invalid pc in trace entry"
+		}
+		if id := v.Get("threadID"); id.Type() == js.TypeNumber {
+			entry.threadID = id.Int()
+		}
+
+		if len(entries) > 0 && entry.threadID != entries[0].threadID {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, ""
+}
+
+// compareReplay compares the replayed state with the expected final state.
+// A missing expected PC or register array is not compared (null).
+func compareReplay(expected js.Value, pc uint64, registers []uint32) (pcMatches, mismatches interface{}) {
+	if want, ok := jsToAddress(expected.Get("pc")); ok {
+		pcMatches = want == pc
+	}
+
+	wantRegisters := expected.Get("registers")
+	if wantRegisters.Type() != js.TypeObject {
+		return pcMatches, nil
+	}
+
+	differences := []interface{}{}
+	for i, want := range jsToUint32s(wantRegisters) {
+		var actual interface{}
+		if i < len(registers) {
+			if registers[i] == want {
+				continue
+			}
+			actual = registers[i]
+		}
+		differences = append(differences, map[string]interface{}{
+			"index":    i,
+			"expected": want,
+			"actual":   actual,
+		})
+	}
+	return pcMatches, differences
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+// loopBridge runs a three-instruction loop for the thread *id:
+//
+//	0x40000000  r1 += 1
+//	0x40000004  r2 += r1
+//	0x40000008  if r1 < 3 goto 0x40000000
+func loopBridge(t *testing.T, vo *VMOrchestrator, id *int) js.Value {
+	return newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func(args []js.Value) interface{} {
+			registers := call(vo.GetThread, *id).Get("registers")
+			r1, r2 := registers.Index(1).Int(), registers.Index(2).Int()
+			switch uint64(args[0].Float()) {
+			case 0x40000000:
+				call(vo.SetRegister, *id, 1, r1+1)
+			case 0x40000004:
+				call(vo.SetRegister, *id, 2, r2+r1)
+			case 0x40000008:
+				if r1 < 3 {
+					return map[string]interface{}{"status": statusBranch, "pc": 0x40000000}
+				}
+			}
+			return true
+		},
+	})
+}
+
+// recordLoop runs the loop to completion on a fresh VM, returning its trace
+// and final state
+func recordLoop(t *testing.T) (trace, final js.Value) {
+	t.Helper()
+	vo := newTestOrchestrator(t)
+	var id int
+	call(vo.Initialize, loopBridge(t, vo, &id))
+	call(vo.EnableTrace, 64)
+	id = createThread(t, vo, 0x40000000, 1, "paused")
+	for i := 0; i < 9; i++ {
+		requireOK(t, call(vo.StepThread, id))
+	}
+
+	info := call(vo.GetThread, id)
+	final = js.ValueOf(map[string]interface{}{"pc": info.Get("pc"), "registers": info.Get("registers")})
+	return call(vo.GetTrace), final
+}
+
+func TestReplayTraceReachesRecordedState(t *testing.T) {
+	trace, final := recordLoop(t)
+	if trace.Length() != 9 || final.Get("pc").Float() != 0x4000000c || final.Get("registers").Index(2).Int() != 6 {
+		t.Fatalf("recorded %d entries ending at %v with r2 = %v; the loop did not run as intended",
+			trace.Length(), final.Get("pc"), final.Get("registers").Index(2))
+	}
+
+	// Replay in another VM, on a thread with a different ID and start PC
+	vo := newTestOrchestrator(t)
+	var id int
+	call(vo.Initialize, loopBridge(t, vo, &id))
+	createThread(t, vo, 0x1000, 1, "paused")
+	id = createThread(t, vo, 0x2000, 1, "paused")
+
+	result := requireOK(t, call(vo.ReplayTrace, id, trace, final))
+	if got := result.Get("executed").Int(); got != 9 {
+		t.Errorf("replayed %d instructions, want 9", got)
+	}
+	if got := result.Get("divergedAt").Int(); got != -1 {
+		t.Errorf("replay diverged at entry %d", got)
+	}
+	if !result.Get("pcMatches").Bool() || result.Get("mismatches").Length() != 0 {
+		t.Errorf("replay ended at %v with %d register mismatches, want the recorded state",
+			result.Get("pc"), result.Get("mismatches").Length())
+	}
+}
+
+func TestReplayTraceReportsDifferences(t *testing.T) {
+	trace, final := recordLoop(t)
+
+	vo := newTestOrchestrator(t)
+	var id int
+	call(vo.Initialize, loopBridge(t, vo, &id))
+	id = createThread(t, vo, 0x40000000, 1, "paused")
+	requireOK(t, call(vo.SetRegister, id, 1, 1)) // one iteration ahead
+
+	result := requireOK(t, call(vo.ReplayTrace, id, trace, final))
+	// r1 reaches 3 on the second pass, so the branch back is not taken
+	if got := result.Get("divergedAt").Int(); got != 5 {
+		t.Errorf("divergedAt = %d, want 5", got)
+	}
+	mismatch := result.Get("mismatches")
+	if mismatch.Length() != 2 || mismatch.Index(0).Get("index").Int() != 1 || mismatch.Index(0).Get("actual").Int() != 4 {
+		t.Errorf("mismatches = %v, want r1 and r2", js.Global().Get("JSON").Call("stringify", mismatch))
+	}
+
+	requireOK(t, call(vo.Start))
+	requireError(t, call(vo.ReplayTrace, id, trace), errAlreadyRunning)
+}
+
+func TestReplayTraceAgainstThrowingBridge(t *testing.T) {
+	trace, _ := recordLoop(t)
+
+	vo := newTestOrchestrator(t)
+	bridge := js.Global().Get("Object").New()
+	bridge.Set("executeInstruction", jsFunction("pc", `if (pc === 0x40000004) throw new Error("boom"); return true;`))
+	call(vo.Initialize, bridge)
+	id := createThread(t, vo, 0x40000000, 1, "paused")
+
+	// The exception faults the thread and ends the replay instead of
+	// escaping the call
+	result := requireOK(t, call(vo.ReplayTrace, id, trace))
+	if got := result.Get("executed").Int(); got != 2 {
+		t.Errorf("executed = %d, want 2 (stopping at the throwing instruction)", got)
+	}
+	if got := threadStatus(vo, id); got != "faulted" {
+		t.Errorf("thread is %q after the bridge threw, want faulted", got)
+	}
+}
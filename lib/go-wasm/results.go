@@ -0,0 +1,72 @@
+// Structured Results
+// Error codes for methods that can fail for more than one reason
+//
+// Lifecycle and thread-targeting methods return { ok: true, ... } on success
+// and { ok: false, error, message } on failure, where error is one of the
+// codes below so callers can branch on the cause. Orchestrators created with
+// { legacyResults: true } return the historical bare values instead (true,
+// false, a thread ID or -1).
+
+package main
+
+import (
+	"fmt"
+	"syscall/js"
+)
+
+// Error codes reported in failed results
+const (
+	errInvalidArgument = "invalid_argument" // missing or malformed argument
+	errAlreadyRunning  = "already_running"  // the VM is running or stopping
+	errNotRunning      = "not_running"      // the VM is not running
+	errUnknownThread   = "unknown_thread"   // no active thread has that ID
+	errInvalidState    = "invalid_state"    // the thread's status does not allow the operation
+	errOutOfRange      = "out_of_range"     // an index is outside the configured bounds
+	errStackOverflow   = "stack_overflow"   // a push exceeded the maximum stack depth
+	errStackEmpty      = "stack_empty"      // a pop found the stack empty
+	errHalted          = "halted"           // the emulator halted or faulted the thread
+	errUnknownMutex    = "unknown_mutex"    // no guest mutex has that ID
+	errNotOwner        = "not_owner"        // the thread does not own the guest mutex
+	errUnknownCondVar  = "unknown_condvar"  // no guest condition variable has that ID
+	errRegionOverlap   = "region_overlap"   // a memory region overlaps one already mapped
+	errUnknownRegion   = "unknown_region"   // no memory region is mapped at that base
+	errUnknownGroup    = "unknown_group"    // no thread group has that ID
+	errAccessDenied    = "access_denied"    // the memory map does not permit the access
+	errUnsupported     = "unsupported"      // the emulator bridge does not implement the operation
+	errThreadLimit     = "thread_limit"     // creating the thread would exceed the thread limit
+	errUnknownContext  = "unknown_context"  // the thread has no context saved under that label
+	errBridgeException = "bridge_exception" // the emulator bridge or a callback threw
+)
+
+// succeed returns a successful result: legacy in legacy mode, otherwise
+// { ok: true } merged with fields
+func (vo *VMOrchestrator) succeed(legacy interface{}, fields map[string]interface{}) interface{} {
+	if vo.legacyResults {
+		return js.ValueOf(legacy)
+	}
+
+	result := map[string]interface{}{"ok": true}
+	for key, value := range fields {
+		result[key] = value
+	}
+	return js.ValueOf(result)
+}
+
+// fail returns a failed result: legacy in legacy mode, otherwise
+// { ok: false, error: code, message }
+func (vo *VMOrchestrator) fail(legacy interface{}, code string, format string, args ...interface{}) interface{} {
+	if vo.legacyResults {
+		return js.ValueOf(legacy)
+	}
+
+	return js.ValueOf(map[string]interface{}{
+		"ok":      false,
+		"error":   code,
+		"message": fmt.Sprintf(format, args...),
+	})
+}
+
+// unknownThread is the failure for a thread ID with no active thread
+func (vo *VMOrchestrator) unknownThread(legacy interface{}, threadID int) interface{} {
+	return vo.fail(legacy, errUnknownThread, "thread %d does not exist", threadID)
+}
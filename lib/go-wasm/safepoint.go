@@ -0,0 +1,106 @@
+// Safe Points
+// Brings every thread to an instruction boundary for coordinated operations
+//
+// RequestSafepoint stops dispatching quanta and makes each quantum in
+// progress park at its next instruction boundary, like Pause. The returned
+// Promise resolves to a token once every in-flight quantum has parked (a
+// quantum already parked by Pause counts), so the host can inspect or edit
+// thread state knowing nothing is mid-instruction. ReleaseSafepoint(token)
+// lets execution continue once no other safe point is held.
+//
+// Snapshot holds a safe point internally for the duration of the copy. A
+// synchronous call cannot wait for acknowledgements (a quantum sleeping for
+// the speed limit needs the JS event loop to wake), so Snapshot instead
+// locks every thread at once: a quantum that reaches its next boundary
+// parks, and one still mid-instruction blocks until the copy is complete.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+)
+
+// RequestSafepoint stops every thread at its next instruction boundary.
+// Returns a Promise resolving to a token for ReleaseSafepoint once all
+// in-flight quanta have parked.
+func (vo *VMOrchestrator) RequestSafepoint(this js.Value, args []js.Value) interface{} {
+	token := vo.holdSafepoint()
+
+	return newPromise(func(resolve, reject js.Value) {
+		vo.awaitSafepoint()
+		resolve.Invoke(token)
+	})
+}
+
+// ReleaseSafepoint releases a safe point taken with RequestSafepoint.
+// Returns false if the token is unknown or already released.
+func (vo *VMOrchestrator) ReleaseSafepoint(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber {
+		return js.ValueOf(false)
+	}
+	return js.ValueOf(vo.releaseSafepoint(args[0].Int()))
+}
+
+// holdSafepoint stops dispatch and asks running quanta to park at their next
+// instruction boundary. Returns the token that releases the hold.
+func (vo *VMOrchestrator) holdSafepoint() int {
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+
+	vo.safepointCounter++
+	token := vo.safepointCounter
+	vo.safepointHolds[token] = struct{}{}
+	atomic.StoreInt32(&vo.safepoint, 1)
+	return token
+}
+
+// releaseSafepoint drops a hold and wakes parked quanta once none remain
+func (vo *VMOrchestrator) releaseSafepoint(token int) bool {
+	vo.schedMutex.Lock()
+	if _, ok := vo.safepointHolds[token]; !ok {
+		vo.schedMutex.Unlock()
+		return false
+	}
+	delete(vo.safepointHolds, token)
+	if len(vo.safepointHolds) == 0 {
+		atomic.StoreInt32(&vo.safepoint, 0)
+	}
+	vo.schedMutex.Unlock()
+	vo.schedCond.Broadcast()
+	return true
+}
+
+// clearSafepoints drops every hold when the VM stops so the next Start runs
+// freely. Outstanding tokens become invalid.
+func (vo *VMOrchestrator) clearSafepoints() {
+	vo.schedMutex.Lock()
+	vo.safepointHolds = make(map[int]struct{})
+	atomic.StoreInt32(&vo.safepoint, 0)
+	vo.schedMutex.Unlock()
+}
+
+// awaitSafepoint blocks until every in-flight quantum is parked at an
+// instruction boundary, the hold is released or the VM stops
+func (vo *VMOrchestrator) awaitSafepoint() {
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+
+	for vo.parked < vo.inFlight && atomic.LoadInt32(&vo.safepoint) == 1 && atomic.LoadInt32(&vo.isRunning) == 1 {
+		vo.schedCond.Wait()
+	}
+}
+
+// lockAllThreads read-locks every active thread in ID order and returns
+// them, so their state can be copied as one consistent cut. The caller
+// unlocks each thread when done.
+func (vo *VMOrchestrator) lockAllThreads() []*VMThread {
+	vo.threadMutex.RLock()
+	threads := vo.threads.Sorted()
+	vo.threadMutex.RUnlock()
+
+	for _, thread := range threads {
+		thread.mutex.RLock()
+	}
+	return threads
+}
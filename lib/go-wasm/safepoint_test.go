@@ -0,0 +1,94 @@
+package main
+
+import (
+	"reflect"
+	"runtime"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// loadedVM starts a VM running n threads besides the main thread on a bridge
+// where every instruction is 4 bytes, and waits until all of them have run
+func loadedVM(t *testing.T, n int) (*VMOrchestrator, map[int]uint64) {
+	t.Helper()
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	call(vo.SetYieldStrategy, "gosched")
+	requireOK(t, call(vo.Start))
+
+	starts := map[int]uint64{1: 0x1000}
+	for i := 0; i < n; i++ {
+		pc := uint64(0x40000000 + i*0x100000)
+		starts[createThread(t, vo, pc)] = pc
+	}
+	eventually(t, "every thread to run", func() bool {
+		for id, pc := range starts {
+			if threadPC(t, vo, id) == pc {
+				return false
+			}
+		}
+		return true
+	})
+	return vo, starts
+}
+
+// awaitPromise blocks until promise settles and returns its value
+func awaitPromise(t *testing.T, promise js.Value) js.Value {
+	t.Helper()
+	settled := make(chan js.Value, 1)
+	promise.Call("then", newCallback(t, func(args []js.Value) { settled <- args[0] }))
+	select {
+	case value := <-settled:
+		return value
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the promise")
+		return js.Undefined()
+	}
+}
+
+func TestSafepointHoldsEveryThread(t *testing.T) {
+	vo, starts := loadedVM(t, 4)
+
+	token := awaitPromise(t, call(vo.RequestSafepoint))
+	before := captureThreads(vo)
+	snapshot := call(vo.Snapshot)
+	time.Sleep(30 * time.Millisecond)
+	if after := captureThreads(vo); !reflect.DeepEqual(before, after) {
+		t.Fatalf("threads advanced during the safe point:\nbefore %v\nafter  %v", before, after)
+	}
+	for id, state := range before {
+		if pc := uint64(snapshotThread(t, snapshot, id).Get("pc").Float()); pc != state.pc {
+			t.Errorf("snapshot has thread %d at %#x, want %#x", id, pc, state.pc)
+		}
+	}
+
+	if !call(vo.ReleaseSafepoint, token).Bool() {
+		t.Fatal("releaseSafepoint rejected its token")
+	}
+	if call(vo.ReleaseSafepoint, token).Bool() {
+		t.Error("releaseSafepoint accepted a released token")
+	}
+	for id := range starts {
+		eventually(t, "threads to run after release", func() bool { return threadPC(t, vo, id) != before[id].pc })
+	}
+}
+
+func TestSnapshotUnderLoadIsConsistent(t *testing.T) {
+	vo, starts := loadedVM(t, 4)
+
+	// Every instruction is 4 bytes, so a thread copied at an instruction
+	// boundary has a PC exactly 4 bytes per executed instruction past its start
+	for i := 0; i < 50; i++ {
+		snapshot := call(vo.Snapshot)
+		for id, start := range starts {
+			thread := snapshotThread(t, snapshot, id)
+			pc := uint64(thread.Get("pc").Float())
+			executed := uint64(thread.Get("instructionsExecuted").Float())
+			if pc != start+4*executed {
+				t.Fatalf("snapshot %d: thread %d at %#x after %d instructions from %#x", i, id, pc, executed, start)
+			}
+		}
+		runtime.Gosched()
+	}
+}
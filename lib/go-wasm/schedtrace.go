@@ -0,0 +1,89 @@
+// Schedule Trace
+// Ring buffer of scheduling events for timeline visualizers
+//
+// Where the instruction trace (trace.go) records every PC, the schedule
+// trace records when threads get and lose the CPU: "dispatch" when a worker
+// takes a thread off the run queue, "preempt" when its quantum ends and it
+// goes back on the queue, "block" when a running thread becomes waiting,
+// suspended or paused, and "wake" when a thread becomes running again.
+// A cooperative yield shows as a block followed by a wake at the end of the
+// quantum. Like the instruction trace, the ring buffer is published through
+// an atomic pointer, so recording costs a nil check while it is off.
+
+package main
+
+import (
+	"syscall/js"
+	"time"
+
+	"github.com/aquifer/vm-orchestrator/internal/ring"
+)
+
+// defaultScheduleTraceSize is the buffer capacity when none is given
+const defaultScheduleTraceSize = 4096
+
+// scheduleEvent is one recorded scheduling event
+type scheduleEvent struct {
+	at       time.Time
+	threadID int
+	event    string
+}
+
+// EnableScheduleTrace starts recording the last capacity scheduling events
+// (4096 by default). Any previously recorded events are discarded.
+func (vo *VMOrchestrator) EnableScheduleTrace(this js.Value, args []js.Value) interface{} {
+	capacity := defaultScheduleTraceSize
+	if len(args) > 0 && !args[0].IsUndefined() {
+		if args[0].Type() != js.TypeNumber || args[0].Int() < 1 {
+			return js.ValueOf(false)
+		}
+		capacity = args[0].Int()
+	}
+
+	vo.schedTrace.Store(ring.New[scheduleEvent](capacity))
+	return js.ValueOf(true)
+}
+
+// DisableScheduleTrace stops recording and drops the recorded events
+func (vo *VMOrchestrator) DisableScheduleTrace(this js.Value, args []js.Value) interface{} {
+	vo.schedTrace.Store(nil)
+	return js.ValueOf(true)
+}
+
+// GetScheduleTrace returns the recorded events, oldest first, as
+// [{ timestamp, threadID, event }] with timestamp in fractional
+// milliseconds since the Unix epoch. Empty when the trace is off.
+func (vo *VMOrchestrator) GetScheduleTrace(this js.Value, args []js.Value) interface{} {
+	trace := vo.schedTrace.Load()
+	if trace == nil {
+		return js.ValueOf([]interface{}{})
+	}
+
+	events := trace.Snapshot()
+	result := make([]interface{}, len(events))
+	for i, event := range events {
+		result[i] = map[string]interface{}{
+			"timestamp": float64(event.at.UnixMicro()) / 1000,
+			"threadID":  event.threadID,
+			"event":     event.event,
+		}
+	}
+	return js.ValueOf(result)
+}
+
+// traceSchedule records a scheduling event if the schedule trace is on
+func (vo *VMOrchestrator) traceSchedule(threadID int, event string) {
+	if trace := vo.schedTrace.Load(); trace != nil {
+		trace.Push(scheduleEvent{at: time.Now(), threadID: threadID, event: event})
+	}
+}
+
+// traceStatusChange records the block or wake a status change amounts to
+func (vo *VMOrchestrator) traceStatusChange(threadID int, old, status string) {
+	switch {
+	case status == "running":
+		vo.traceSchedule(threadID, "wake")
+	case old == "running" && (status == "waiting" || status == "suspended" || status == "paused"):
+		vo.traceSchedule(threadID, "block")
+	}
+}
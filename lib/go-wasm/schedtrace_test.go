@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// threadSchedule returns one thread's events from the schedule trace,
+// failing if the timestamps go backwards
+func threadSchedule(t *testing.T, vo *VMOrchestrator, threadID int) []string {
+	t.Helper()
+	trace := call(vo.GetScheduleTrace)
+	var events []string
+	last := 0.0
+	for i := 0; i < trace.Length(); i++ {
+		entry := trace.Index(i)
+		if at := entry.Get("timestamp").Float(); at < last {
+			t.Fatalf("event %d at %v precedes the one before it at %v", i, at, last)
+		} else {
+			last = at
+		}
+		if entry.Get("threadID").Int() == threadID {
+			events = append(events, entry.Get("event").String())
+		}
+	}
+	return events
+}
+
+func TestScheduleTraceOrdering(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+	id := createThread(t, vo, 0x2000)
+	eventually(t, "the thread to run", func() bool { return threadPC(t, vo, id) != 0x2000 })
+
+	if !call(vo.EnableScheduleTrace).Bool() {
+		t.Fatal("enableScheduleTrace failed")
+	}
+	for i := 1; i <= 2; i++ {
+		requireOK(t, call(vo.SuspendThread, id))
+		requireOK(t, call(vo.ResumeThread, id))
+		eventually(t, "the resumed thread to be dispatched", func() bool {
+			events := strings.Join(threadSchedule(t, vo, id), " ")
+			wake := strings.LastIndex(events, "wake")
+			return strings.Count(events, "wake") == i && strings.Contains(events[wake:], "dispatch")
+		})
+	}
+
+	// Whatever the thread was doing when tracing started, each suspension
+	// blocks it and the resume wakes it, and it is dispatched after every
+	// wake. A dispatch may also fall between block and wake, when the
+	// thread was still queued as it was suspended.
+	var order []string
+	dispatched := true
+	for _, event := range threadSchedule(t, vo, id) {
+		switch event {
+		case "block", "wake":
+			if event == "block" && !dispatched {
+				t.Fatalf("thread %d blocked again before being dispatched after its wake", id)
+			}
+			order = append(order, event)
+			dispatched = event == "block"
+		case "dispatch":
+			dispatched = true
+		}
+	}
+	if got := strings.Join(order, " "); got != "block wake block wake" || !dispatched {
+		t.Fatalf("thread %d blocked and woke as %q, dispatched at the end: %v; want block, wake twice", id, got, dispatched)
+	}
+
+	call(vo.DisableScheduleTrace)
+	if got := call(vo.GetScheduleTrace).Length(); got != 0 {
+		t.Errorf("disabled schedule trace returned %d events", got)
+	}
+	if call(vo.EnableScheduleTrace, 0).Bool() {
+		t.Error("enableScheduleTrace(0) succeeded")
+	}
+}
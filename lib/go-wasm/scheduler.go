@@ -0,0 +1,321 @@
+// Thread Scheduler
+// Dispatches instruction quanta to VM threads in weighted round-robin order
+//
+// Runnable threads wait in a FIFO run queue (engine.RunQueue) served by a
+// fixed-size pool of worker goroutines. A worker pops the head of the
+// queue, lets it execute a quantum proportional to its priority, and
+// requeues it at the tail. VM threads are not tied to goroutines, so
+// thousands of guest threads share the same few workers. With a single
+// worker (the default) and equal priorities every thread receives the same
+// quantum in creation order, so interleaving is deterministic.
+//
+// Threads that are not "running" (suspended, waiting, terminated) are dropped
+// from the queue when their quantum ends and never occupy a worker. Workers
+// park on schedCond instead of spinning whenever there is no work they can
+// do: the queue is empty, no emulator bridge is attached yet, the VM is
+// paused, or (in tick mode) the host has not granted any quanta. Every event
+// that creates work (resume, Initialize, Tick) signals the condition
+// variable.
+//
+// A thread may be pinned to one worker with SetThreadAffinity. Workers skip
+// queued threads pinned elsewhere, so a pinned thread waits for its worker
+// even when others are idle; unpinned threads run on any worker.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"time"
+
+	"github.com/aquifer/vm-orchestrator/internal/engine"
+)
+
+// schedulingPolicy is reported through GetStats
+const schedulingPolicy = "weighted-round-robin"
+
+const (
+	// defaultThreadPriority is used when CreateThread is given no priority
+	defaultThreadPriority = 1
+	// defaultMaxWorkers is the initial size of the worker pool
+	defaultMaxWorkers = 1
+)
+
+// SetMaxWorkers resizes the worker pool. If the VM is running the pool is
+// restarted with the new size; in-flight quanta finish on their old worker.
+func (vo *VMOrchestrator) SetMaxWorkers(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(false)
+	}
+
+	workers := args[0].Int()
+	if workers < 1 {
+		return js.ValueOf(false)
+	}
+
+	vo.schedMutex.Lock()
+	vo.maxWorkers = workers
+	vo.schedMutex.Unlock()
+
+	// Threads pinned to workers that no longer exist would never run again
+	vo.threadMutex.RLock()
+	for _, thread := range vo.threads {
+		if atomic.LoadInt32(&thread.affinity) >= int32(workers) {
+			atomic.StoreInt32(&thread.affinity, -1)
+		}
+	}
+	vo.threadMutex.RUnlock()
+
+	if atomic.LoadInt32(&vo.isRunning) == 1 {
+		vo.startScheduler()
+	}
+	return js.ValueOf(true)
+}
+
+// startScheduler launches the worker pool for a new run epoch. Workers from
+// a previous epoch exit once they finish their current quantum.
+func (vo *VMOrchestrator) startScheduler() {
+	vo.schedMutex.Lock()
+	vo.schedEpoch++
+	epoch := vo.schedEpoch
+	workers := vo.poolSize()
+	vo.schedMutex.Unlock()
+	vo.schedCond.Broadcast()
+
+	for i := 0; i < workers; i++ {
+		go vo.worker(epoch, i)
+	}
+}
+
+// SetThreadAffinity pins a thread to the worker with the given index, or
+// lets it run on any worker again when workerIndex is -1
+func (vo *VMOrchestrator) SetThreadAffinity(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(false, errInvalidArgument, "setThreadAffinity requires a thread ID and worker index")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	index := args[1].Int()
+
+	vo.schedMutex.Lock()
+	if workers := vo.maxWorkers; index < -1 || index >= workers {
+		vo.schedMutex.Unlock()
+		return vo.fail(false, errOutOfRange, "worker %d is outside 0-%d", index, workers-1)
+	}
+	atomic.StoreInt32(&thread.affinity, int32(index))
+	vo.schedMutex.Unlock()
+
+	// The thread may be queued behind a worker that can no longer take it
+	vo.schedCond.Broadcast()
+	return vo.succeed(true, nil)
+}
+
+// SetTickMode switches between free-running execution and host-driven
+// execution where each Tick grants a number of scheduling quanta
+func (vo *VMOrchestrator) SetTickMode(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeBoolean {
+		return js.ValueOf(false)
+	}
+
+	vo.schedMutex.Lock()
+	vo.tickMode = args[0].Bool()
+	vo.tickBudget = 0
+	vo.schedMutex.Unlock()
+	vo.schedCond.Broadcast()
+
+	return js.ValueOf(true)
+}
+
+// Tick grants scheduling quanta in tick mode (default 1) and wakes workers.
+// Returns false when the orchestrator is free-running.
+func (vo *VMOrchestrator) Tick(this js.Value, args []js.Value) interface{} {
+	quanta := 1
+	if len(args) > 0 && args[0].Type() == js.TypeNumber {
+		quanta = args[0].Int()
+	}
+	if quanta < 1 {
+		return js.ValueOf(false)
+	}
+
+	vo.schedMutex.Lock()
+	if !vo.tickMode {
+		vo.schedMutex.Unlock()
+		return js.ValueOf(false)
+	}
+	vo.tickBudget += quanta
+	vo.schedMutex.Unlock()
+	vo.schedCond.Broadcast()
+
+	return js.ValueOf(true)
+}
+
+// schedulerStats reports the pool size, the number of threads waiting for a
+// worker and the tick-mode quanta not yet dispatched
+func (vo *VMOrchestrator) schedulerStats() (workers, queueDepth, pendingTicks int) {
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+	return vo.poolSize(), vo.runQueue.Len(), vo.tickBudget
+}
+
+// clearRunQueue drops all queued work and wakes the scheduler so it can
+// notice the VM has stopped
+func (vo *VMOrchestrator) clearRunQueue() {
+	vo.schedMutex.Lock()
+	vo.runQueue.Clear()
+	vo.schedMutex.Unlock()
+	vo.schedCond.Broadcast()
+}
+
+// enqueueThread appends a thread to the tail of the run queue unless it is
+// already queued or mid-quantum
+func (vo *VMOrchestrator) enqueueThread(thread *VMThread) {
+	vo.schedMutex.Lock()
+	if thread.queued {
+		vo.schedMutex.Unlock()
+		return
+	}
+	thread.queued = true
+	vo.runQueue.Push(thread)
+	vo.schedMutex.Unlock()
+
+	// A signal could wake a worker the thread is not pinned to
+	if atomic.LoadInt32(&thread.affinity) >= 0 {
+		vo.schedCond.Broadcast()
+	} else {
+		vo.schedCond.Signal()
+	}
+}
+
+// requeueThread puts a thread back on the run queue after its quantum if it
+// is still runnable. The status check happens under schedMutex so a
+// concurrent ResumeThread either sees the thread queued or requeues it itself.
+func (vo *VMOrchestrator) requeueThread(thread *VMThread) {
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+
+	vo.inFlight--
+	if vo.inFlight == 0 && vo.drained != nil {
+		close(vo.drained)
+		vo.drained = nil
+	}
+	if atomic.LoadInt32(&vo.safepoint) == 1 {
+		vo.schedCond.Broadcast() // a safe point may be waiting for this quantum
+	}
+
+	thread.mutex.Lock()
+	vo.endYield(thread)
+	runnable := thread.status == "running"
+	thread.mutex.Unlock()
+
+	if !runnable || atomic.LoadInt32(&vo.isRunning) != 1 {
+		thread.queued = false
+		return
+	}
+	vo.runQueue.Push(thread)
+	vo.traceSchedule(thread.id, "preempt")
+	if atomic.LoadInt32(&thread.affinity) >= 0 {
+		vo.schedCond.Broadcast()
+	}
+}
+
+// nextThread pops the first queued thread the worker may run, blocking until
+// there is work to dispatch. Returns nil once the VM stops or a newer worker
+// pool has been started.
+func (vo *VMOrchestrator) nextThread(epoch uint64, worker int) *VMThread {
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+
+	next := vo.dispatchableWork(worker)
+	for next < 0 && vo.schedEpoch == epoch && atomic.LoadInt32(&vo.isRunning) == 1 {
+		vo.schedCond.Wait()
+		next = vo.dispatchableWork(worker)
+	}
+	if vo.schedEpoch != epoch || atomic.LoadInt32(&vo.isRunning) != 1 {
+		return nil
+	}
+
+	if vo.tickMode {
+		vo.tickBudget--
+	}
+	if vo.deterministic {
+		next = vo.schedRand.Intn(vo.runQueue.Len())
+	}
+	thread := vo.runQueue.Take(next)
+	vo.inFlight++
+	vo.traceSchedule(thread.id, "dispatch")
+	return thread
+}
+
+// Affinity returns the worker the thread is pinned to, or -1
+func (thread *VMThread) Affinity() int {
+	return int(atomic.LoadInt32(&thread.affinity))
+}
+
+// dispatchableWork returns the run queue position of the first thread the
+// worker may dequeue now, or -1 if there is none. Caller must hold schedMutex.
+func (vo *VMOrchestrator) dispatchableWork(worker int) int {
+	if vo.runQueue.Len() == 0 || !vo.bridgeReady || vo.mustPark() {
+		return -1
+	}
+	if vo.tickMode && vo.tickBudget <= 0 {
+		return -1
+	}
+	if vo.deterministic {
+		return 0 // the single worker takes any thread; nextThread picks which
+	}
+	now := time.Now()
+	return vo.runQueue.Next(worker, func(thread *VMThread) bool { return !vo.overQuota(thread, now) })
+}
+
+// waitForQuanta blocks until no worker is executing a quantum or the timeout
+// elapses. Returns true if every in-flight quantum finished.
+func (vo *VMOrchestrator) waitForQuanta(timeout time.Duration) bool {
+	vo.schedMutex.Lock()
+	if vo.inFlight == 0 {
+		vo.schedMutex.Unlock()
+		return true
+	}
+	if vo.drained == nil {
+		vo.drained = make(chan struct{})
+	}
+	drained := vo.drained
+	vo.schedMutex.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-drained:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// worker runs until the VM stops, giving each runnable thread it dequeues a
+// quantum of engine.Quantum * priority instructions, counting any priority
+// inherited through mutexes
+func (vo *VMOrchestrator) worker(epoch uint64, index int) {
+	for {
+		thread := vo.nextThread(epoch, index)
+		if thread == nil {
+			return
+		}
+
+		thread.mutex.RLock()
+		quantum := engine.Quantum * thread.effectivePriority()
+		thread.mutex.RUnlock()
+
+		if vo.logging(logDebug) {
+			vo.logf(logDebug, "worker %d runs thread %d for up to %d instructions", index, thread.id, quantum)
+		}
+
+		vo.executeThread(thread, quantum)
+		vo.requeueThread(thread)
+	}
+}
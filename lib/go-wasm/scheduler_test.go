@@ -0,0 +1,121 @@
+package main
+
+import (
+	"runtime"
+	"sync/atomic"
+	"syscall/js"
+	"testing"
+	"time"
+
+	"github.com/aquifer/vm-orchestrator/internal/engine"
+)
+
+// countingBridge returns a bridge whose executeInstruction counts its calls
+func countingBridge(t testing.TB, count *int64) js.Value {
+	bridge := js.Global().Get("Object").New()
+	fn := js.FuncOf(func(js.Value, []js.Value) interface{} {
+		atomic.AddInt64(count, 1)
+		return true
+	})
+	t.Cleanup(fn.Release)
+	bridge.Set("executeInstruction", fn)
+	return bridge
+}
+
+func TestWorkersParkWithoutBridge(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	requireOK(t, call(vo.Start))
+
+	time.Sleep(20 * time.Millisecond)
+	if got := stat(vo, "instructionsExecuted"); got != 0 {
+		t.Fatalf("executed %v instructions without a bridge", got)
+	}
+	if got := threadStatus(vo, 1); got != "running" {
+		t.Fatalf("main thread is %q, want running", got)
+	}
+}
+
+func TestTickGrantsQuanta(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	var executed int64
+	call(vo.Initialize, countingBridge(t, &executed))
+	if call(vo.Tick).Bool() {
+		t.Fatal("tick succeeded while free-running")
+	}
+	call(vo.SetTickMode, true)
+	requireOK(t, call(vo.Start))
+
+	time.Sleep(20 * time.Millisecond)
+	if got := atomic.LoadInt64(&executed); got != 0 {
+		t.Fatalf("executed %d instructions before any tick", got)
+	}
+
+	if !call(vo.Tick, 2).Bool() {
+		t.Fatal("tick failed")
+	}
+	eventually(t, "two quanta to run", func() bool { return atomic.LoadInt64(&executed) == 2*engine.Quantum })
+	time.Sleep(20 * time.Millisecond)
+	if got := atomic.LoadInt64(&executed); got != 2*engine.Quantum {
+		t.Fatalf("executed %d instructions for two ticks, want %d", got, 2*engine.Quantum)
+	}
+	if got := stat(vo, "pendingTicks"); got != 0 {
+		t.Errorf("pendingTicks = %v, want 0", got)
+	}
+}
+
+// BenchmarkTick measures a host-driven quantum from Tick until its last
+// instruction has run
+func BenchmarkTick(b *testing.B) {
+	vo := newOrchestrator(defaultRegisterCount)
+	var executed int64
+	call(vo.Initialize, countingBridge(b, &executed))
+	call(vo.SetTickMode, true)
+	call(vo.Start)
+	defer call(vo.Stop)
+
+	b.ResetTimer()
+	for i := 1; i <= b.N; i++ {
+		call(vo.Tick)
+		for atomic.LoadInt64(&executed) < int64(i*engine.Quantum) {
+			time.Sleep(time.Microsecond) // lets timers fire under js/wasm
+		}
+	}
+	b.ReportMetric(float64(executed)/b.Elapsed().Seconds(), "instructions/s")
+}
+
+// BenchmarkIdle is the baseline for the parked scheduler: a free-running VM
+// with the "sleep0" yield strategy, no bridge and no work. Each op hands the
+// CPU to every other goroutine once, so ns/op grows with whatever the idle
+// workers burn. "spin" adds one polling goroutine per worker in the shape of
+// the old executeThread loop for comparison.
+func BenchmarkIdle(b *testing.B) {
+	for _, spin := range []bool{false, true} {
+		name := "parked"
+		if spin {
+			name = "spin"
+		}
+		b.Run(name, func(b *testing.B) {
+			vo := newOrchestrator(defaultRegisterCount)
+			call(vo.SetYieldStrategy, "sleep0")
+			call(vo.Start)
+			defer call(vo.Stop)
+
+			if spin {
+				var stopped int32
+				defer atomic.StoreInt32(&stopped, 1)
+				for i := 0; i < vo.poolSize(); i++ {
+					go func() {
+						for atomic.LoadInt32(&stopped) == 0 { // the bridge never becomes ready
+							runtime.Gosched() // time.Sleep(0) alone never yields
+						}
+					}()
+				}
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				runtime.Gosched()
+			}
+		})
+	}
+}
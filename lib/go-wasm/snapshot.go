@@ -0,0 +1,462 @@
+// VM Snapshots
+// Checkpoints all thread state plus stats so a VM can be rolled back
+//
+// Snapshots are plain JS objects, so they can be inspected, stored or
+// posted between workers. Restore stops the scheduler, replaces the thread
+// map with copies of the snapshotted threads and resumes if the VM was
+// running. Thread stacks are always deep-copied so a snapshot never aliases
+// live thread memory. Snapshots carry every VM-wide counter and each
+// thread's instruction, cycle and CPU time counters, so a restored VM
+// reports the stats it had when the snapshot was taken.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"time"
+
+	"github.com/aquifer/vm-orchestrator/internal/engine"
+)
+
+// vmSnapshot is a point-in-time copy of orchestrator state
+type vmSnapshot struct {
+	threadCounter int32
+	registerCount int32
+	threads       []threadSnapshot
+	stats         engine.Stats
+}
+
+// threadSnapshot is a point-in-time copy of a single thread
+type threadSnapshot struct {
+	id        int
+	name      string
+	pc        uint64
+	registers []uint32
+	stack     []uint32
+	status    string
+	priority  int
+	tls       map[string]interface{}
+	createdAt time.Time
+	errno     uint32
+	wakeable  bool // a "waiting" thread's wait can be ended by WakeThread
+
+	resumeStatus string // status a "waiting" thread returns to, "" = running
+
+	instructionsExecuted uint64
+	cyclesExecuted       uint64
+	cpuTime              time.Duration
+}
+
+// Snapshot captures every active thread and the current stats at a safe
+// point, so no thread advances while the others are copied
+func (vo *VMOrchestrator) Snapshot(this js.Value, args []js.Value) interface{} {
+	token := vo.holdSafepoint()
+	defer vo.releaseSafepoint(token)
+	return js.ValueOf(vo.takeSnapshot().toJSObject())
+}
+
+// Restore rolls the VM back to a snapshot returned by Snapshot
+func (vo *VMOrchestrator) Restore(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeObject {
+		return js.ValueOf(false)
+	}
+
+	snapshot, ok := snapshotFromJS(args[0])
+	if !ok {
+		return js.ValueOf(false)
+	}
+
+	vo.restoreSnapshot(snapshot)
+	return js.ValueOf(true)
+}
+
+// DiffSnapshots compares two snapshots returned by Snapshot. For every
+// thread in both it reports the registers that changed and any PC or status
+// change; threads found in only one snapshot are listed as added or
+// removed. Returns null if either snapshot is invalid.
+func (vo *VMOrchestrator) DiffSnapshots(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[0].Type() != js.TypeObject || args[1].Type() != js.TypeObject {
+		return js.Null()
+	}
+
+	before, ok := snapshotFromJS(args[0])
+	if !ok {
+		return js.Null()
+	}
+	after, ok := snapshotFromJS(args[1])
+	if !ok {
+		return js.Null()
+	}
+	return js.ValueOf(diffSnapshots(before, after))
+}
+
+// takeSnapshot copies all thread state and stats
+func (vo *VMOrchestrator) takeSnapshot() *vmSnapshot {
+	snapshot := &vmSnapshot{
+		threadCounter: vo.threadIDs.Last(),
+		registerCount: atomic.LoadInt32(&vo.registerCount),
+	}
+
+	// Every thread stays locked until all are copied
+	threads := vo.lockAllThreads()
+	for _, thread := range threads {
+		snapshot.threads = append(snapshot.threads, thread.snapshot())
+	}
+	for _, thread := range threads {
+		thread.mutex.RUnlock()
+	}
+
+	vo.statsMutex.Lock()
+	vo.stats.UpdateExecutionTime(time.Now())
+	snapshot.stats = *vo.stats
+	vo.statsMutex.Unlock()
+
+	return snapshot
+}
+
+// restoreSnapshot stops execution, rebuilds the thread map from a snapshot
+// and resumes if the VM was running
+func (vo *VMOrchestrator) restoreSnapshot(snapshot *vmSnapshot) {
+	wasRunning := atomic.CompareAndSwapInt32(&vo.isRunning, 1, 0)
+	vo.clearRunQueue()
+	vo.terminateAllThreads()
+
+	// New thread IDs must not collide with restored ones
+	counter := snapshot.threadCounter
+	threads := make(map[int]*VMThread, len(snapshot.threads))
+	restored := make([]*VMThread, 0, len(snapshot.threads))
+	for _, ts := range snapshot.threads {
+		thread := ts.restore()
+		threads[thread.id] = thread
+		restored = append(restored, thread)
+		if int32(thread.id) > counter {
+			counter = int32(thread.id)
+		}
+	}
+
+	vo.threadMutex.Lock()
+	vo.threads = threads
+	for id := range threads {
+		delete(vo.zombies, id) // the restored thread supersedes it
+	}
+	vo.threadMutex.Unlock()
+	vo.threadIDs.Reset(counter)
+	atomic.StoreInt32(&vo.registerCount, snapshot.registerCount)
+
+	vo.statsMutex.Lock()
+	*vo.stats = snapshot.stats
+	vo.stats.LastUpdate = time.Now()
+	vo.stats.ClockRunning = wasRunning && atomic.LoadInt32(&vo.paused) == 0
+	vo.statsMutex.Unlock()
+
+	for _, thread := range restored {
+		if thread.status == "running" {
+			vo.enqueueThread(thread)
+		}
+	}
+
+	if wasRunning {
+		atomic.StoreInt32(&vo.isRunning, 1)
+		vo.startScheduler()
+	}
+}
+
+// snapshot copies the thread's state; caller must hold thread.mutex
+func (thread *VMThread) snapshot() threadSnapshot {
+	// A yield only lasts until the end of the quantum
+	status := thread.status
+	if thread.yielding {
+		status = "running"
+	}
+
+	ts := threadSnapshot{
+		id:        thread.id,
+		name:      thread.name,
+		pc:        thread.pc,
+		registers: append([]uint32(nil), thread.registers...),
+		stack:     append([]uint32(nil), thread.stack...),
+		status:    status,
+		priority:  thread.priority,
+		tls:       copyTLS(thread.tls),
+		createdAt: thread.createdAt,
+		errno:     thread.errno,
+		wakeable:  thread.wakeable,
+
+		resumeStatus: thread.resumeStatus,
+	}
+	ts.instructionsExecuted = thread.instructionsExecuted
+	ts.cyclesExecuted = thread.cyclesExecuted
+	ts.cpuTime = thread.cpuTime
+	return ts
+}
+
+// restore builds a fresh VMThread from a snapshot, copying the stack
+func (ts threadSnapshot) restore() *VMThread {
+	stack := make([]uint32, len(ts.stack), max(len(ts.stack), defaultMaxStackDepth))
+	copy(stack, ts.stack)
+
+	thread := &VMThread{
+		id:        ts.id,
+		name:      ts.name,
+		pc:        ts.pc,
+		registers: append([]uint32(nil), ts.registers...),
+		stack:     stack,
+		status:    ts.status,
+		priority:  ts.priority,
+		tls:       copyTLS(ts.tls),
+		affinity:  -1,
+		done:      make(chan struct{}),
+		createdAt: ts.createdAt,
+		errno:     ts.errno,
+		wakeable:  ts.wakeable,
+
+		resumeStatus: ts.resumeStatus,
+	}
+	thread.instructionsExecuted = ts.instructionsExecuted
+	thread.cyclesExecuted = ts.cyclesExecuted
+	thread.cpuTime = ts.cpuTime
+	thread.waitingSince = restoredWaitStart(ts.status)
+	thread.restoreFault()
+	return thread
+}
+
+// toJSObject converts a snapshot to a JavaScript object
+func (snapshot *vmSnapshot) toJSObject() map[string]interface{} {
+	threads := make([]interface{}, len(snapshot.threads))
+	for i, ts := range snapshot.threads {
+		thread := map[string]interface{}{
+			"id":        ts.id,
+			"name":      ts.name,
+			"pc":        addressToJS(ts.pc),
+			"registers": uint32sToJS(ts.registers),
+			"stack":     uint32sToJS(ts.stack),
+			"status":    ts.status,
+			"priority":  ts.priority,
+			"tls":       ts.tls,
+			"createdAt": ts.createdAt.UnixMilli(),
+			"errno":     ts.errno,
+			"wakeable":  ts.wakeable,
+
+			"instructionsExecuted": ts.instructionsExecuted,
+			"cyclesExecuted":       ts.cyclesExecuted,
+			"cpuTimeMs":            float64(ts.cpuTime) / float64(time.Millisecond),
+		}
+		if ts.resumeStatus != "" {
+			thread["resumeStatus"] = ts.resumeStatus
+		}
+		threads[i] = thread
+	}
+
+	return map[string]interface{}{
+		"threadCounter": snapshot.threadCounter,
+		"registerCount": snapshot.registerCount,
+		"threads":       threads,
+		"stats": map[string]interface{}{
+			"instructionsExecuted": snapshot.stats.InstructionsExecuted,
+			"memoryAllocated":      snapshot.stats.MemoryAllocated,
+			"threadsCreated":       snapshot.stats.ThreadsCreated,
+			"threadsTerminated":    snapshot.stats.ThreadsTerminated,
+			"executionTime":        snapshot.stats.ExecutionTime.Milliseconds(),
+			"cyclesExecuted":       snapshot.stats.CyclesExecuted,
+			"peakMemoryAllocated":  snapshot.stats.PeakMemoryAllocated,
+			"allocationErrors":     snapshot.stats.AllocationErrors,
+			"faults":               snapshot.stats.Faults,
+			"yields":               snapshot.stats.Yields,
+			"stackGrowths":         snapshot.stats.StackGrowths,
+			"peakActiveThreads":    snapshot.stats.PeakActiveThreads,
+			"cpuTimeMs":            float64(snapshot.stats.CPUTime) / float64(time.Millisecond),
+
+			"threadCreationRejections": snapshot.stats.RejectedThreads,
+		},
+	}
+}
+
+// snapshotFromJS parses a snapshot object produced by toJSObject
+func snapshotFromJS(v js.Value) (snapshot *vmSnapshot, ok bool) {
+	// Fields of the wrong type surface as js.ValueError panics
+	defer func() {
+		if recover() != nil {
+			snapshot, ok = nil, false
+		}
+	}()
+
+	threads := v.Get("threads")
+	stats := v.Get("stats")
+	if threads.Type() != js.TypeObject || stats.Type() != js.TypeObject {
+		return nil, false
+	}
+
+	snapshot = &vmSnapshot{
+		threadCounter: int32(v.Get("threadCounter").Int()),
+		registerCount: defaultRegisterCount,
+		threads:       make([]threadSnapshot, threads.Length()),
+		stats: engine.Stats{
+			InstructionsExecuted: uint64(stats.Get("instructionsExecuted").Int()),
+			MemoryAllocated:      uint64(stats.Get("memoryAllocated").Int()),
+			ThreadsCreated:       uint64(stats.Get("threadsCreated").Int()),
+			ThreadsTerminated:    uint64(stats.Get("threadsTerminated").Int()),
+			ExecutionTime:        time.Duration(stats.Get("executionTime").Int()) * time.Millisecond,
+
+			// Snapshots taken before the full stats were saved omit the rest
+			CyclesExecuted:      optionalUint64(stats, "cyclesExecuted"),
+			PeakMemoryAllocated: optionalUint64(stats, "peakMemoryAllocated"),
+			AllocationErrors:    optionalUint64(stats, "allocationErrors"),
+			Faults:              optionalUint64(stats, "faults"),
+			Yields:              optionalUint64(stats, "yields"),
+			StackGrowths:        optionalUint64(stats, "stackGrowths"),
+			RejectedThreads:     optionalUint64(stats, "threadCreationRejections"),
+			PeakActiveThreads:   optionalUint64(stats, "peakActiveThreads"),
+			CPUTime:             optionalDuration(stats, "cpuTimeMs"),
+		},
+	}
+
+	// Snapshots taken before the register file was configurable omit the count
+	if count := v.Get("registerCount"); !count.IsUndefined() {
+		snapshot.registerCount = int32(count.Int())
+	}
+	if snapshot.registerCount < 1 || snapshot.registerCount > maxRegisterCount {
+		return nil, false
+	}
+
+	for i := range snapshot.threads {
+		t := threads.Index(i)
+		ts := threadSnapshot{
+			id:       t.Get("id").Int(),
+			stack:    jsToUint32s(t.Get("stack")),
+			status:   t.Get("status").String(),
+			priority: t.Get("priority").Int(),
+			tls:      jsToPrimitiveMap(t.Get("tls")),
+		}
+
+		if ts.pc, ok = jsToAddress(t.Get("pc")); !ok {
+			return nil, false
+		}
+
+		// Snapshots taken before threads had names omit them
+		if name := t.Get("name"); name.Type() == js.TypeString {
+			ts.name = name.String()
+		}
+
+		// Older snapshots have no creation time; the thread is new on restore
+		ts.createdAt = time.Now()
+		if createdAt := t.Get("createdAt"); createdAt.Type() == js.TypeNumber {
+			ts.createdAt = time.UnixMilli(int64(createdAt.Float()))
+		}
+		if errno := t.Get("errno"); errno.Type() == js.TypeNumber {
+			ts.errno = uint32(errno.Int())
+		}
+		ts.wakeable = ts.status == "waiting" && t.Get("wakeable").Truthy()
+		if ts.status == "waiting" && t.Get("resumeStatus").Equal(js.ValueOf("paused")) {
+			ts.resumeStatus = "paused"
+		}
+		ts.instructionsExecuted = optionalUint64(t, "instructionsExecuted")
+		ts.cyclesExecuted = optionalUint64(t, "cyclesExecuted")
+		ts.cpuTime = optionalDuration(t, "cpuTimeMs")
+
+		ts.registers = jsToUint32s(t.Get("registers"))
+		if len(ts.registers) != int(snapshot.registerCount) || ts.priority < 1 {
+			return nil, false
+		}
+
+		if !engine.Restorable(ts.status) {
+			return nil, false
+		}
+		snapshot.threads[i] = ts
+	}
+
+	return snapshot, true
+}
+
+// optionalUint64 returns the counter v[key], or 0 if it is absent
+func optionalUint64(v js.Value, key string) uint64 {
+	if field := v.Get(key); field.Type() == js.TypeNumber && field.Float() > 0 {
+		return uint64(field.Float())
+	}
+	return 0
+}
+
+// optionalDuration returns the duration v[key] given in fractional
+// milliseconds, or 0 if it is absent
+func optionalDuration(v js.Value, key string) time.Duration {
+	if field := v.Get(key); field.Type() == js.TypeNumber && field.Float() > 0 {
+		return time.Duration(field.Float() * float64(time.Millisecond))
+	}
+	return 0
+}
+
+// diffSnapshots builds the DiffSnapshots result. Both snapshots hold their
+// threads in ID order.
+func diffSnapshots(before, after *vmSnapshot) map[string]interface{} {
+	afterByID := make(map[int]*threadSnapshot, len(after.threads))
+	for i := range after.threads {
+		afterByID[after.threads[i].id] = &after.threads[i]
+	}
+
+	changed := []interface{}{}
+	removed := []interface{}{}
+	for i := range before.threads {
+		old := &before.threads[i]
+		current, ok := afterByID[old.id]
+		if !ok {
+			removed = append(removed, old.id)
+			continue
+		}
+		delete(afterByID, old.id)
+
+		if diff := diffThreads(old, current); diff != nil {
+			changed = append(changed, diff)
+		}
+	}
+
+	added := []interface{}{}
+	for _, ts := range after.threads {
+		if _, ok := afterByID[ts.id]; ok {
+			added = append(added, ts.id)
+		}
+	}
+
+	return map[string]interface{}{
+		"threads": changed,
+		"added":   added,
+		"removed": removed,
+	}
+}
+
+// diffThreads compares two snapshots of the same thread, or returns nil if
+// its PC, status and registers are unchanged. A register present in only
+// one snapshot (the register file was resized) is null on the other side.
+func diffThreads(old, current *threadSnapshot) map[string]interface{} {
+	registers := []interface{}{}
+	for i := 0; i < max(len(old.registers), len(current.registers)); i++ {
+		var before, after interface{}
+		if i < len(old.registers) {
+			before = old.registers[i]
+		}
+		if i < len(current.registers) {
+			after = current.registers[i]
+		}
+		if before != after {
+			registers = append(registers, map[string]interface{}{
+				"index": i,
+				"old":   before,
+				"new":   after,
+			})
+		}
+	}
+
+	diff := map[string]interface{}{
+		"id":        old.id,
+		"registers": registers,
+	}
+	if old.pc != current.pc {
+		diff["pc"] = map[string]interface{}{"old": addressToJS(old.pc), "new": addressToJS(current.pc)}
+	}
+	if old.status != current.status {
+		diff["status"] = map[string]interface{}{"old": old.status, "new": current.status}
+	}
+	if len(registers) == 0 && len(diff) == 2 {
+		return nil
+	}
+	return diff
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestRestoreUndoesRegisterChanges(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+
+	threadID := createThread(t, vo, 0x2000)
+	for index := 0; index < 4; index++ {
+		requireOK(t, call(vo.SetRegister, threadID, index, 100+index))
+	}
+	eventually(t, "the thread to execute", func() bool { return threadPC(t, vo, threadID) > 0x2000 })
+
+	snapshot := call(vo.Snapshot)
+	saved := snapshotThread(t, snapshot, threadID)
+
+	for index := 0; index < 4; index++ {
+		requireOK(t, call(vo.SetRegister, threadID, index, 0))
+	}
+	requireOK(t, call(vo.SuspendThread, threadID))
+
+	if !call(vo.Restore, snapshot).Bool() {
+		t.Fatal("restore failed")
+	}
+	requireOK(t, call(vo.SuspendThread, threadID))
+
+	registers := call(vo.GetRegisters, threadID)
+	for index := 0; index < 4; index++ {
+		if got := registers.Index(index).Int(); got != 100+index {
+			t.Errorf("r%d = %d after restore, want %d", index, got, 100+index)
+		}
+	}
+	if got := threadPC(t, vo, threadID); got < uint64(saved.Get("pc").Int()) {
+		t.Errorf("pc %#x after restore is before the snapshot's %#x", got, saved.Get("pc").Int())
+	}
+}
+
+func TestSnapshotCarriesStatsAndThreadCounters(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+	call(vo.RecordAllocation, 64)
+	call(vo.RecordFree, 128) // counts an allocation error
+
+	eventually(t, "instructions to execute", func() bool { return stat(vo, "instructionsExecuted") >= 100 })
+	requireOK(t, call(vo.Pause))
+
+	snapshot := call(vo.Snapshot)
+	stats := snapshot.Get("stats")
+	main := snapshotThread(t, snapshot, 1)
+
+	requireOK(t, call(vo.Stop))
+	call(vo.Reset)
+	if !call(vo.Restore, snapshot).Bool() {
+		t.Fatal("restore failed")
+	}
+
+	for _, name := range []string{"instructionsExecuted", "cyclesExecuted", "threadsCreated", "allocationErrors", "peakActiveThreads"} {
+		if got, want := stat(vo, name), stats.Get(name).Float(); got != want || want == 0 {
+			t.Errorf("%s = %v after restore, want %v (non-zero)", name, got, want)
+		}
+	}
+
+	thread := vo.getThread(1)
+	thread.mutex.RLock()
+	instructions, cycles, cpuTime := thread.instructionsExecuted, thread.cyclesExecuted, thread.cpuTime
+	thread.mutex.RUnlock()
+	if want := uint64(main.Get("instructionsExecuted").Float()); instructions != want || want == 0 {
+		t.Errorf("thread executed %d instructions after restore, want %d (non-zero)", instructions, want)
+	}
+	if want := uint64(main.Get("cyclesExecuted").Float()); cycles != want {
+		t.Errorf("thread executed %d cycles after restore, want %d", cycles, want)
+	}
+	if cpuTime <= 0 && main.Get("cpuTimeMs").Float() > 0 {
+		t.Error("thread CPU time was not restored")
+	}
+}
+
+func TestRestoreRejectsMalformedSnapshots(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	createThread(t, vo, 0x1000)
+	snapshot := call(vo.Snapshot)
+
+	snapshot.Get("threads").Index(0).Set("status", "terminated")
+	if call(vo.Restore, snapshot).Bool() {
+		t.Error("restored a snapshot holding a terminated thread")
+	}
+	snapshot.Get("threads").Index(0).Set("status", "running")
+	snapshot.Get("threads").Index(0).Set("registers", []interface{}{1, 2})
+	if call(vo.Restore, snapshot).Bool() {
+		t.Error("restored a snapshot with a short register file")
+	}
+}
+
+// snapshotThread returns the thread with the given ID from a snapshot
+func snapshotThread(t *testing.T, snapshot js.Value, threadID int) js.Value {
+	t.Helper()
+	threads := snapshot.Get("threads")
+	for i := 0; i < threads.Length(); i++ {
+		if thread := threads.Index(i); thread.Get("id").Int() == threadID {
+			return thread
+		}
+	}
+	t.Fatalf("snapshot has no thread %d", threadID)
+	return js.Undefined()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"reflect"
+	"syscall/js"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	kept := createThread(t, vo, 0x40000000, 1, "paused")
+	idle := createThread(t, vo, 0x40001000, 1, "paused")
+	gone := createThread(t, vo, 0x40002000, 1, "paused")
+	requireOK(t, call(vo.SetRegister, kept, 5, 9))
+	before := call(vo.Snapshot)
+
+	requireOK(t, call(vo.SetRegister, kept, 3, 7))
+	requireOK(t, call(vo.SetRegister, kept, 5, 10))
+	requireOK(t, call(vo.StepThread, kept))
+	requireOK(t, call(vo.KillThread, gone))
+	added := createThread(t, vo, 0x40003000, 1, "paused")
+	after := call(vo.Snapshot)
+
+	diff := call(vo.DiffSnapshots, before, after)
+	if got := jsToInts(diff.Get("added")); !reflect.DeepEqual(got, []int{added}) {
+		t.Errorf("added = %v, want [%d]", got, added)
+	}
+	if got := jsToInts(diff.Get("removed")); !reflect.DeepEqual(got, []int{gone}) {
+		t.Errorf("removed = %v, want [%d]", got, gone)
+	}
+
+	threads := diff.Get("threads")
+	if threads.Length() != 1 {
+		t.Fatalf("%d threads changed, want only thread %d (thread %d is unchanged)", threads.Length(), kept, idle)
+	}
+	changed := threads.Index(0)
+	if got := changed.Get("id").Int(); got != kept {
+		t.Fatalf("changed thread is %d, want %d", got, kept)
+	}
+	var registers [][3]int
+	for i := 0; i < changed.Get("registers").Length(); i++ {
+		r := changed.Get("registers").Index(i)
+		registers = append(registers, [3]int{r.Get("index").Int(), r.Get("old").Int(), r.Get("new").Int()})
+	}
+	if want := [][3]int{{3, 0, 7}, {5, 9, 10}}; !reflect.DeepEqual(registers, want) {
+		t.Errorf("register changes = %v, want %v", registers, want)
+	}
+	pc := changed.Get("pc")
+	if pc.Get("old").Float() != 0x40000000 || pc.Get("new").Float() != 0x40000004 {
+		t.Errorf("pc change = %v -> %v, want 0x40000000 -> 0x40000004", pc.Get("old"), pc.Get("new"))
+	}
+	if changed.Get("status").Truthy() {
+		t.Errorf("reported a status change for a thread that stayed paused")
+	}
+}
+
+func TestDiffSnapshotsStatusAndIdentity(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	id := createThread(t, vo, 0x40000000, 1, "paused", "", map[string]interface{}{"autostart": false})
+	before := call(vo.Snapshot)
+
+	if diff := call(vo.DiffSnapshots, before, before); diff.Get("threads").Length() != 0 ||
+		diff.Get("added").Length() != 0 || diff.Get("removed").Length() != 0 {
+		t.Error("a snapshot differs from itself")
+	}
+
+	requireOK(t, call(vo.StartThread, id))
+	status := call(vo.DiffSnapshots, before, call(vo.Snapshot)).Get("threads").Index(0).Get("status")
+	if status.Get("old").String() != "ready" || status.Get("new").String() != "paused" {
+		t.Errorf("status change = %v -> %v, want ready -> paused", status.Get("old"), status.Get("new"))
+	}
+
+	if !call(vo.DiffSnapshots, before, js.Global().Get("Object").New()).IsNull() {
+		t.Error("diffed against an invalid snapshot")
+	}
+}
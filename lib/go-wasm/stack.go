@@ -0,0 +1,192 @@
+// Thread Stacks
+// Push/pop access to each thread's stack so the emulator can keep call
+// frames in the orchestrator
+//
+// Stacks are bounded by a configurable maximum depth. Pushing past it
+// faults the thread and fires the stack-overflow callback.
+//
+// A stack starts with the capacity given to CreateThread (default 1024
+// entries) and grows when a push finds it full, either by doubling or by a
+// fixed increment (SetStackGrowth). Growth never allocates beyond the
+// maximum depth, and each reallocation is counted in the stackGrowths stat
+// so hosts can tune the initial capacity of deep-recursion guests.
+
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"syscall/js"
+
+	"github.com/aquifer/vm-orchestrator/internal/engine"
+)
+
+const (
+	// defaultMaxStackDepth matches the default initial stack capacity
+	defaultMaxStackDepth = 1024
+	// defaultStackCapacity is the initial capacity of a new thread's stack
+	defaultStackCapacity = 1024
+)
+
+// SetMaxStackDepth sets the maximum number of entries a thread stack may hold
+func (vo *VMOrchestrator) SetMaxStackDepth(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(false)
+	}
+
+	depth := args[0].Int()
+	if depth < 1 {
+		return js.ValueOf(false)
+	}
+
+	atomic.StoreInt32(&vo.maxStackDepth, int32(depth))
+	return js.ValueOf(true)
+}
+
+// SetStackGrowth sets how full stacks grow: "double" (the default) or
+// "fixed", which adds increment entries per reallocation
+// Arguments: policy, increment (required for "fixed")
+func (vo *VMOrchestrator) SetStackGrowth(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return js.ValueOf(false)
+	}
+
+	switch args[0].String() {
+	case "double":
+		atomic.StoreInt32(&vo.stackGrowStep, 0)
+	case "fixed":
+		if len(args) < 2 || args[1].Type() != js.TypeNumber || args[1].Int() < 1 {
+			return js.ValueOf(false)
+		}
+		atomic.StoreInt32(&vo.stackGrowStep, int32(args[1].Int()))
+	default:
+		return js.ValueOf(false)
+	}
+	return js.ValueOf(true)
+}
+
+// PushStack pushes a value onto a thread's stack
+// Arguments: threadID, value
+func (vo *VMOrchestrator) PushStack(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(false, errInvalidArgument, "pushStack requires a thread ID and value")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	value := uint32(args[1].Int())
+	limit := int(atomic.LoadInt32(&vo.maxStackDepth))
+
+	thread.mutex.Lock()
+	if status := thread.status; !engine.Active(status) {
+		thread.mutex.Unlock()
+		return vo.fail(false, errInvalidState, "thread %d is %s", threadID, status)
+	}
+	if len(thread.stack) >= limit {
+		depth := len(thread.stack)
+		thread.mutex.Unlock()
+		vo.stackOverflow(thread, depth)
+		return vo.fail(false, errStackOverflow, "thread %d stack exceeded %d entries", threadID, limit)
+	}
+	grew := vo.pushStack(thread, limit, value)
+	thread.mutex.Unlock()
+
+	if grew {
+		vo.countStackGrowth()
+	}
+	return vo.succeed(true, nil)
+}
+
+// PopStack pops the top value from a thread's stack
+// Returns -1 if the thread is unknown or its stack is empty
+func (vo *VMOrchestrator) PopStack(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(-1, errInvalidArgument, "popStack requires a thread ID")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(-1, threadID)
+	}
+
+	thread.mutex.Lock()
+	defer thread.mutex.Unlock()
+
+	if len(thread.stack) == 0 {
+		return vo.fail(-1, errStackEmpty, "thread %d stack is empty", threadID)
+	}
+	top := len(thread.stack) - 1
+	value := thread.stack[top]
+	thread.stack = thread.stack[:top]
+	return vo.succeed(value, map[string]interface{}{"value": value})
+}
+
+// pushStack appends values to a thread's stack, growing it by the growth
+// policy without exceeding limit entries of capacity. The caller checks the
+// depth against limit first. Returns true if the stack was reallocated.
+// Caller must hold thread.mutex.
+func (vo *VMOrchestrator) pushStack(thread *VMThread, limit int, values ...uint32) bool {
+	depth := len(thread.stack) + len(values)
+	if depth <= cap(thread.stack) {
+		thread.stack = append(thread.stack, values...)
+		return false
+	}
+
+	capacity := cap(thread.stack) * 2
+	if increment := int(atomic.LoadInt32(&vo.stackGrowStep)); increment > 0 {
+		capacity = cap(thread.stack) + increment
+	}
+	capacity = max(min(capacity, limit), depth)
+
+	stack := make([]uint32, len(thread.stack), capacity)
+	copy(stack, thread.stack)
+	thread.stack = append(stack, values...)
+	return true
+}
+
+// countStackGrowth records a stack reallocation in the stats
+func (vo *VMOrchestrator) countStackGrowth() {
+	vo.statsMutex.Lock()
+	vo.stats.StackGrowths++
+	vo.statsMutex.Unlock()
+}
+
+// OnStackOverflow registers a callback invoked as callback(threadID, depth)
+// when a push exceeds the maximum stack depth. Passing null clears it.
+func (vo *VMOrchestrator) OnStackOverflow(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	vo.stackOverflowCallback = args[0]
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// stackOverflow faults a thread whose stack overflowed at depth entries, as
+// any other fault, and fires the stack-overflow callback. Caller must not
+// hold thread.mutex.
+func (vo *VMOrchestrator) stackOverflow(thread *VMThread, depth int) {
+	vo.fault(thread, faultStackOverflow, fmt.Sprintf("stack overflow at depth %d", depth), nil)
+	vo.fireStackOverflow(thread.id, depth)
+}
+
+// fireStackOverflow queues a call to the registered stack-overflow callback
+func (vo *VMOrchestrator) fireStackOverflow(threadID int, depth int) {
+	vo.postEvent(eventCritical, "", func() {
+		vo.callbackMutex.RLock()
+		callback := vo.stackOverflowCallback
+		vo.callbackMutex.RUnlock()
+
+		if callback.Type() == js.TypeFunction {
+			callback.Invoke(threadID, depth)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// pushN pushes n values onto a thread's stack through the public API
+func pushN(t *testing.T, vo *VMOrchestrator, threadID, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		requireOK(t, call(vo.PushStack, threadID, i))
+	}
+}
+
+func TestStackGrowthPolicies(t *testing.T) {
+	tests := []struct {
+		policy    []interface{}
+		growths   float64
+		finalCap  int
+		maxDepth  int
+		pushCount int
+	}{
+		{[]interface{}{"double"}, 4, 64, 1024, 64},    // 4, 8, 16, 32, 64
+		{[]interface{}{"fixed", 4}, 15, 64, 1024, 64}, // 4, 8, ..., 64
+		{[]interface{}{"double"}, 4, 50, 50, 50},      // the last doubling is capped at the max depth
+		{[]interface{}{"fixed", 100}, 1, 50, 50, 50},  // so is a large increment
+	}
+	for _, test := range tests {
+		vo := newTestOrchestrator(t)
+		if !call(vo.SetStackGrowth, test.policy...).Bool() {
+			t.Fatalf("setStackGrowth(%v) failed", test.policy)
+		}
+		call(vo.SetMaxStackDepth, test.maxDepth)
+		id := createThread(t, vo, 0x1000, 1, "paused", "", map[string]interface{}{"stackCapacity": 4})
+		pushN(t, vo, id, test.pushCount)
+
+		if got := stat(vo, "stackGrowths"); got != test.growths {
+			t.Errorf("%v: stackGrowths = %v, want %v", test.policy, got, test.growths)
+		}
+		thread := vo.getThread(id)
+		thread.mutex.RLock()
+		capacity := cap(thread.stack)
+		thread.mutex.RUnlock()
+		if capacity != test.finalCap {
+			t.Errorf("%v: capacity = %d, want %d", test.policy, capacity, test.finalCap)
+		}
+	}
+}
+
+func TestStackOverflowAtMaxDepth(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.SetMaxStackDepth, 8)
+	id := createThread(t, vo, 0x1000, 1, "paused")
+	pushN(t, vo, id, 8)
+
+	requireError(t, call(vo.PushStack, id, 9), errStackOverflow)
+	if got := threadStatus(vo, id); got != "faulted" {
+		t.Errorf("thread is %q after overflowing, want faulted", got)
+	}
+}
+
+func TestSetStackGrowthRejectsBadPolicies(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	for _, policy := range [][]interface{}{{"fixed"}, {"fixed", 0}, {"triple"}, {2}} {
+		if call(vo.SetStackGrowth, policy...).Bool() {
+			t.Errorf("setStackGrowth(%v) succeeded", policy)
+		}
+	}
+}
+
+// BenchmarkRecursion pushes and unwinds a deep call chain from a small
+// initial stack under each growth policy
+func BenchmarkRecursion(b *testing.B) {
+	const depth = 1 << 14
+	for _, policy := range []struct {
+		name string
+		step int32
+	}{{"double", 0}, {"fixed-64", 64}, {"fixed-1024", 1024}} {
+		b.Run(policy.name, func(b *testing.B) {
+			vo := newOrchestrator(defaultRegisterCount)
+			atomic.StoreInt32(&vo.stackGrowStep, policy.step)
+			for i := 0; i < b.N; i++ {
+				thread := &VMThread{stack: make([]uint32, 0, 16)}
+				for frame := 0; frame < depth; frame++ {
+					vo.pushStack(thread, depth, uint32(frame))
+				}
+				thread.stack = thread.stack[:0]
+			}
+			b.ReportMetric(float64(vo.stats.StackGrowths), "growths")
+			b.SetBytes(depth * 4)
+		})
+	}
+}
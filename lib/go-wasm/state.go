@@ -0,0 +1,451 @@
+// State Export
+// Human-readable JSON dump of the whole orchestrator
+//
+// ExportState serializes configuration, threads, stats, breakpoints and the
+// trace buffer through plain structs copied under the live locks. Unlike
+// snapshots the dump is a portable string meant for tooling and bug reports,
+// and ImportState rebuilds a stopped orchestrator from it. Collections are
+// emitted in a fixed order (threads by ID, breakpoints by address), so an
+// export -> import -> export round trip yields identical JSON. Addresses are
+// encoded as decimal strings so JS consumers never lose precision.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"syscall/js"
+	"time"
+
+	"github.com/aquifer/vm-orchestrator/internal/engine"
+	"github.com/aquifer/vm-orchestrator/internal/ring"
+)
+
+// exportedState is the top-level JSON document
+type exportedState struct {
+	Config        exportedConfig       `json:"config"`
+	ThreadCounter int32                `json:"threadCounter"`
+	Threads       []exportedThread     `json:"threads"`
+	Stats         exportedStats        `json:"stats"`
+	Breakpoints   []exportedBreakpoint `json:"breakpoints"`
+	Trace         *exportedTrace       `json:"trace"` // null while tracing is off
+}
+
+// exportedConfig holds the orchestrator's tunables
+type exportedConfig struct {
+	RegisterCount      int32 `json:"registerCount"`
+	MaxStackDepth      int32 `json:"maxStackDepth"`
+	BatchSize          int32 `json:"batchSize"`
+	MaxWorkers         int   `json:"maxWorkers"`
+	TickMode           bool  `json:"tickMode"`
+	ThroughputWindowMs int64 `json:"throughputWindowMs"`
+
+	// Dumps made before CPU features were exported have none; they import
+	// with the baseline set
+	CPUFeatures *uint32 `json:"cpuFeatures"`
+}
+
+// exportedThread is one thread's complete state
+type exportedThread struct {
+	ID                   int                    `json:"id"`
+	Name                 string                 `json:"name"`
+	PC                   uint64                 `json:"pc,string"`
+	Registers            []uint32               `json:"registers"`
+	Stack                []uint32               `json:"stack"`
+	Status               string                 `json:"status"`
+	Priority             int                    `json:"priority"`
+	Affinity             int32                  `json:"affinity"`
+	InstructionsExecuted uint64                 `json:"instructionsExecuted"`
+	InstructionLimit     uint64                 `json:"instructionLimit"`
+	WaitingOn            []int                  `json:"waitingOn"`
+	Watches              []int                  `json:"watches"`
+	PendingInterrupts    []int                  `json:"pendingInterrupts"`
+	TLS                  map[string]interface{} `json:"tls"`
+	FaultMessage         string                 `json:"faultMessage"`
+	CPUTimeNs            int64                  `json:"cpuTimeNs"`
+	CreatedAtMs          int64                  `json:"createdAtMs"` // Unix milliseconds
+	Errno                uint32                 `json:"errno"`
+	Wakeable             bool                   `json:"wakeable"` // the wait can be ended by WakeThread
+}
+
+// exportedStats mirrors engine.Stats
+type exportedStats struct {
+	InstructionsExecuted uint64 `json:"instructionsExecuted"`
+	MemoryAllocated      uint64 `json:"memoryAllocated"`
+	PeakMemoryAllocated  uint64 `json:"peakMemoryAllocated"`
+	AllocationErrors     uint64 `json:"allocationErrors"`
+	Faults               uint64 `json:"faults"`
+	Yields               uint64 `json:"yields"`
+	ThreadsCreated       uint64 `json:"threadsCreated"`
+	ThreadsTerminated    uint64 `json:"threadsTerminated"`
+	PeakActiveThreads    uint64 `json:"peakActiveThreads"`
+	ExecutionTimeNs      int64  `json:"executionTimeNs"`
+	CPUTimeNs            int64  `json:"cpuTimeNs"`
+}
+
+// exportedBreakpoint is a breakpoint address with its optional condition
+type exportedBreakpoint struct {
+	Address   uint64             `json:"address,string"`
+	Condition *exportedCondition `json:"condition"`
+}
+
+// exportedCondition mirrors breakpointCondition
+type exportedCondition struct {
+	Register int    `json:"register"`
+	Op       string `json:"op"`
+	Value    uint32 `json:"value"`
+}
+
+// exportedTrace is the trace buffer, oldest entry first
+type exportedTrace struct {
+	Capacity int                  `json:"capacity"`
+	Entries  []exportedTraceEntry `json:"entries"`
+}
+
+// exportedTraceEntry mirrors traceEntry
+type exportedTraceEntry struct {
+	ThreadID int    `json:"threadID"`
+	Name     string `json:"name"`
+	PC       uint64 `json:"pc,string"`
+	Seq      uint64 `json:"seq"`
+}
+
+// ExportState returns the full orchestrator state as a JSON string
+func (vo *VMOrchestrator) ExportState(this js.Value, args []js.Value) interface{} {
+	data, err := json.Marshal(vo.exportState())
+	if err != nil {
+		return js.Null()
+	}
+	return js.ValueOf(string(data))
+}
+
+// ImportState replaces the orchestrator state with a dump produced by
+// ExportState. The VM must be stopped.
+func (vo *VMOrchestrator) ImportState(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return vo.fail(false, errInvalidArgument, "importState requires a JSON string")
+	}
+	if atomic.LoadInt32(&vo.isRunning) != 0 {
+		return vo.fail(false, errAlreadyRunning, "the VM must be stopped to import state")
+	}
+
+	var state exportedState
+	if err := json.Unmarshal([]byte(args[0].String()), &state); err != nil {
+		return vo.fail(false, errInvalidArgument, "invalid state JSON: %v", err)
+	}
+	if err := state.validate(); err != nil {
+		return vo.fail(false, errInvalidArgument, "invalid state: %v", err)
+	}
+
+	vo.importState(&state)
+	return vo.succeed(true, nil)
+}
+
+// exportState copies the live state into export structs
+func (vo *VMOrchestrator) exportState() *exportedState {
+	state := &exportedState{
+		Config: exportedConfig{
+			RegisterCount: atomic.LoadInt32(&vo.registerCount),
+			MaxStackDepth: atomic.LoadInt32(&vo.maxStackDepth),
+			BatchSize:     atomic.LoadInt32(&vo.batchSize),
+		},
+		ThreadCounter: vo.threadIDs.Last(),
+		Threads:       []exportedThread{},
+		Breakpoints:   []exportedBreakpoint{},
+	}
+
+	features := atomic.LoadUint32(&vo.cpuFeatures)
+	state.Config.CPUFeatures = &features
+
+	vo.schedMutex.Lock()
+	state.Config.MaxWorkers = vo.maxWorkers
+	state.Config.TickMode = vo.tickMode
+	vo.schedMutex.Unlock()
+
+	vo.threadMutex.RLock()
+	for _, thread := range vo.threads {
+		thread.mutex.RLock()
+		state.Threads = append(state.Threads, thread.export())
+		thread.mutex.RUnlock()
+	}
+	vo.threadMutex.RUnlock()
+	sort.Slice(state.Threads, func(i, j int) bool { return state.Threads[i].ID < state.Threads[j].ID })
+
+	vo.statsMutex.Lock()
+	vo.stats.UpdateExecutionTime(time.Now())
+	state.Config.ThroughputWindowMs = vo.throughput.window.Milliseconds()
+	state.Stats = exportedStats{
+		InstructionsExecuted: vo.stats.InstructionsExecuted,
+		MemoryAllocated:      vo.stats.MemoryAllocated,
+		PeakMemoryAllocated:  vo.stats.PeakMemoryAllocated,
+		AllocationErrors:     vo.stats.AllocationErrors,
+		Faults:               vo.stats.Faults,
+		Yields:               vo.stats.Yields,
+		ThreadsCreated:       vo.stats.ThreadsCreated,
+		ThreadsTerminated:    vo.stats.ThreadsTerminated,
+		PeakActiveThreads:    vo.stats.PeakActiveThreads,
+		ExecutionTimeNs:      int64(vo.stats.ExecutionTime),
+		CPUTimeNs:            int64(vo.stats.CPUTime),
+	}
+	vo.statsMutex.Unlock()
+
+	vo.breakpointMutex.RLock()
+	for address, condition := range vo.breakpoints {
+		breakpoint := exportedBreakpoint{Address: address}
+		if condition != nil {
+			breakpoint.Condition = &exportedCondition{
+				Register: condition.register,
+				Op:       condition.op,
+				Value:    condition.value,
+			}
+		}
+		state.Breakpoints = append(state.Breakpoints, breakpoint)
+	}
+	vo.breakpointMutex.RUnlock()
+	sort.Slice(state.Breakpoints, func(i, j int) bool {
+		return state.Breakpoints[i].Address < state.Breakpoints[j].Address
+	})
+
+	if buffer := vo.trace.Load(); buffer != nil {
+		entries := buffer.Snapshot()
+		state.Trace = &exportedTrace{
+			Capacity: buffer.Cap(),
+			Entries:  make([]exportedTraceEntry, len(entries)),
+		}
+		for i, entry := range entries {
+			state.Trace.Entries[i] = exportedTraceEntry{ThreadID: entry.threadID, Name: entry.name, PC: entry.pc, Seq: entry.seq}
+		}
+	}
+
+	return state
+}
+
+// export copies the thread's state; caller must hold thread.mutex
+func (thread *VMThread) export() exportedThread {
+	watches := make([]int, 0, len(thread.watches))
+	for index := range thread.watches {
+		watches = append(watches, index)
+	}
+	sort.Ints(watches)
+
+	// A yield only lasts until the end of the quantum
+	status := thread.status
+	if thread.yielding {
+		status = "running"
+	}
+
+	return exportedThread{
+		ID:                   thread.id,
+		Name:                 thread.name,
+		PC:                   thread.pc,
+		Registers:            append([]uint32{}, thread.registers...),
+		Stack:                append([]uint32{}, thread.stack...),
+		Status:               status,
+		Priority:             thread.priority,
+		Affinity:             atomic.LoadInt32(&thread.affinity),
+		InstructionsExecuted: thread.instructionsExecuted,
+		InstructionLimit:     thread.instructionLimit,
+		WaitingOn:            append([]int{}, thread.waitingOn...),
+		Watches:              watches,
+		PendingInterrupts:    append([]int{}, thread.pendingInterrupts...),
+		TLS:                  copyTLS(thread.tls),
+		FaultMessage:         thread.faultMessage,
+		CPUTimeNs:            int64(thread.cpuTime),
+		CreatedAtMs:          thread.createdAt.UnixMilli(),
+		Errno:                thread.errno,
+		Wakeable:             thread.wakeable,
+	}
+}
+
+// validate checks an imported state for values the orchestrator cannot hold
+func (state *exportedState) validate() error {
+	config := state.Config
+	if config.RegisterCount < 1 || config.RegisterCount > maxRegisterCount {
+		return fmt.Errorf("registerCount %d out of range", config.RegisterCount)
+	}
+	if config.MaxStackDepth < 1 || config.BatchSize < 1 || config.MaxWorkers < 1 || config.ThroughputWindowMs < 1 {
+		return fmt.Errorf("config values must be positive")
+	}
+
+	seen := make(map[int]bool, len(state.Threads))
+	for _, thread := range state.Threads {
+		if thread.ID < 1 {
+			return fmt.Errorf("thread ID %d is not positive", thread.ID)
+		}
+		if seen[thread.ID] {
+			return fmt.Errorf("thread ID %d appears more than once", thread.ID)
+		}
+		seen[thread.ID] = true
+		if len(thread.Registers) != int(config.RegisterCount) {
+			return fmt.Errorf("thread %d has %d registers, want %d", thread.ID, len(thread.Registers), config.RegisterCount)
+		}
+		if thread.Priority < 1 {
+			return fmt.Errorf("thread %d has priority %d", thread.ID, thread.Priority)
+		}
+		if thread.Affinity < -1 || int(thread.Affinity) >= config.MaxWorkers {
+			return fmt.Errorf("thread %d has affinity %d", thread.ID, thread.Affinity)
+		}
+		if !engine.Restorable(thread.Status) {
+			return fmt.Errorf("thread %d has status %q", thread.ID, thread.Status)
+		}
+		for _, index := range thread.Watches {
+			if index < 0 || index >= len(thread.Registers) {
+				return fmt.Errorf("thread %d watches register %d", thread.ID, index)
+			}
+		}
+	}
+
+	for _, breakpoint := range state.Breakpoints {
+		if condition := breakpoint.Condition; condition != nil {
+			switch condition.Op {
+			case "==", "!=", "<", ">", "<=", ">=":
+			default:
+				return fmt.Errorf("breakpoint %d has operator %q", breakpoint.Address, condition.Op)
+			}
+		}
+	}
+
+	if state.Trace != nil && (state.Trace.Capacity < 1 || len(state.Trace.Entries) > state.Trace.Capacity) {
+		return fmt.Errorf("trace capacity %d out of range", state.Trace.Capacity)
+	}
+	return nil
+}
+
+// importState replaces the live state with a validated dump. Caller must
+// ensure the VM is stopped.
+func (vo *VMOrchestrator) importState(state *exportedState) {
+	vo.clearRunQueue()
+
+	atomic.StoreInt32(&vo.registerCount, state.Config.RegisterCount)
+	atomic.StoreInt32(&vo.maxStackDepth, state.Config.MaxStackDepth)
+	atomic.StoreInt32(&vo.batchSize, state.Config.BatchSize)
+	features := uint32(baselineCPUFeatures)
+	if state.Config.CPUFeatures != nil {
+		features = *state.Config.CPUFeatures
+	}
+	atomic.StoreUint32(&vo.cpuFeatures, features)
+
+	vo.schedMutex.Lock()
+	vo.maxWorkers = state.Config.MaxWorkers
+	vo.tickMode = state.Config.TickMode
+	vo.tickBudget = 0
+	vo.schedMutex.Unlock()
+
+	counter := state.ThreadCounter
+	threads := make(map[int]*VMThread, len(state.Threads))
+	for _, exported := range state.Threads {
+		threads[exported.ID] = exported.restore()
+		if int32(exported.ID) > counter {
+			counter = int32(exported.ID)
+		}
+	}
+
+	vo.threadMutex.Lock()
+	vo.threads = threads
+	vo.exitStates = make(map[int]threadExit)
+	vo.zombies = make(map[int]*VMThread)
+	vo.threadIDs.Reset(counter)
+	vo.threadMutex.Unlock()
+
+	vo.statsMutex.Lock()
+	*vo.stats = engine.Stats{
+		InstructionsExecuted: state.Stats.InstructionsExecuted,
+		MemoryAllocated:      state.Stats.MemoryAllocated,
+		PeakMemoryAllocated:  state.Stats.PeakMemoryAllocated,
+		AllocationErrors:     state.Stats.AllocationErrors,
+		Faults:               state.Stats.Faults,
+		Yields:               state.Stats.Yields,
+		ThreadsCreated:       state.Stats.ThreadsCreated,
+		ThreadsTerminated:    state.Stats.ThreadsTerminated,
+		PeakActiveThreads:    state.Stats.PeakActiveThreads,
+		ExecutionTime:        time.Duration(state.Stats.ExecutionTimeNs),
+		CPUTime:              time.Duration(state.Stats.CPUTimeNs),
+		LastUpdate:           time.Now(),
+	}
+	vo.throughput = throughputMeter{window: time.Duration(state.Config.ThroughputWindowMs) * time.Millisecond}
+	vo.statsMutex.Unlock()
+
+	breakpoints := make(map[uint64]*breakpointCondition, len(state.Breakpoints))
+	for _, breakpoint := range state.Breakpoints {
+		var condition *breakpointCondition
+		if exported := breakpoint.Condition; exported != nil {
+			condition = &breakpointCondition{register: exported.Register, op: exported.Op, value: exported.Value}
+		}
+		breakpoints[breakpoint.Address] = condition
+	}
+	vo.breakpointMutex.Lock()
+	vo.breakpoints = breakpoints
+	vo.breakpointMutex.Unlock()
+
+	if state.Trace == nil {
+		vo.trace.Store(nil)
+	} else {
+		buffer := ring.New[traceEntry](state.Trace.Capacity)
+		for _, entry := range state.Trace.Entries {
+			buffer.Push(traceEntry{threadID: entry.ThreadID, name: entry.Name, pc: entry.PC, seq: entry.Seq})
+			// Numbering continues after the imported entries
+			if entry.Seq > atomic.LoadUint64(&vo.sequence) {
+				atomic.StoreUint64(&vo.sequence, entry.Seq)
+			}
+		}
+		vo.trace.Store(buffer)
+	}
+
+	// Threads created while stopped wait on the run queue for Start
+	for _, thread := range threads {
+		if thread.status == "running" {
+			vo.enqueueThread(thread)
+		}
+	}
+}
+
+// restore builds a fresh VMThread from an exported thread
+func (exported exportedThread) restore() *VMThread {
+	stack := make([]uint32, len(exported.Stack), max(len(exported.Stack), defaultMaxStackDepth))
+	copy(stack, exported.Stack)
+
+	var watches map[int]struct{}
+	if len(exported.Watches) > 0 {
+		watches = make(map[int]struct{}, len(exported.Watches))
+		for _, index := range exported.Watches {
+			watches[index] = struct{}{}
+		}
+	}
+
+	thread := &VMThread{
+		id:                   exported.ID,
+		name:                 exported.Name,
+		pc:                   exported.PC,
+		registers:            append([]uint32(nil), exported.Registers...),
+		stack:                stack,
+		status:               exported.Status,
+		priority:             exported.Priority,
+		affinity:             exported.Affinity,
+		done:                 make(chan struct{}),
+		watches:              watches,
+		instructionsExecuted: exported.InstructionsExecuted,
+		instructionLimit:     exported.InstructionLimit,
+		waitingOn:            append([]int(nil), exported.WaitingOn...),
+		pendingInterrupts:    append([]int(nil), exported.PendingInterrupts...),
+		tls:                  copyTLS(exported.TLS),
+		faultMessage:         exported.FaultMessage,
+		cpuTime:              time.Duration(exported.CPUTimeNs),
+		waitingSince:         restoredWaitStart(exported.Status),
+		createdAt:            restoredCreationTime(exported.CreatedAtMs),
+		errno:                exported.Errno,
+		wakeable:             exported.Status == "waiting" && exported.Wakeable,
+	}
+	thread.restoreFault()
+	return thread
+}
+
+// restoredCreationTime converts an exported creation time. Dumps made before
+// creation times were exported have none, so the thread counts as new.
+func restoredCreationTime(createdAtMs int64) time.Time {
+	if createdAtMs == 0 {
+		return time.Now()
+	}
+	return time.UnixMilli(createdAtMs)
+}
@@ -0,0 +1,59 @@
+// Stats Cache
+// Coalesces frequent stats reads into one build per interval
+//
+// Each GetStats call walks every thread and builds a fresh object under
+// statsMutex, which adds up when a stats heartbeat and manual polling run
+// side by side. With SetStatsCacheMs(n), the first read builds the object
+// and every read (and heartbeat tick) within the next n milliseconds
+// returns that same object. The object is shared, so callers must not
+// modify it. Reset drops the cached object.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"time"
+)
+
+// statsCache is the last stats object built and when it was built
+type statsCache struct {
+	value   js.Value
+	builtAt time.Time
+}
+
+// SetStatsCacheMs sets how long a stats object is reused, or disables
+// caching when passed 0
+func (vo *VMOrchestrator) SetStatsCacheMs(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber || args[0].Float() < 0 {
+		return js.ValueOf(false)
+	}
+
+	atomic.StoreInt64(&vo.statsCacheTTL, int64(args[0].Float()*float64(time.Millisecond)))
+	vo.statsMutex.Lock()
+	vo.statsCache = statsCache{}
+	vo.statsMutex.Unlock()
+	return js.ValueOf(true)
+}
+
+// cachedStats returns the stats object, reusing the cached one while it is
+// fresh. Must be called from the JS thread.
+func (vo *VMOrchestrator) cachedStats() js.Value {
+	ttl := time.Duration(atomic.LoadInt64(&vo.statsCacheTTL))
+	if ttl <= 0 {
+		return js.ValueOf(vo.statsObject())
+	}
+
+	vo.statsMutex.RLock()
+	cached := vo.statsCache
+	vo.statsMutex.RUnlock()
+	if !cached.value.IsUndefined() && time.Since(cached.builtAt) < ttl {
+		return cached.value
+	}
+
+	value := js.ValueOf(vo.statsObject())
+	vo.statsMutex.Lock()
+	vo.statsCache = statsCache{value: value, builtAt: time.Now()}
+	vo.statsMutex.Unlock()
+	return value
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsCache(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	if call(vo.SetStatsCacheMs, -1).Bool() {
+		t.Error("setStatsCacheMs accepted a negative interval")
+	}
+
+	// Caching is off by default
+	if call(vo.GetStats).Equal(call(vo.GetStats)) {
+		t.Fatal("uncached reads returned the same object")
+	}
+
+	if !call(vo.SetStatsCacheMs, 50).Bool() {
+		t.Fatal("setStatsCacheMs failed")
+	}
+	first := call(vo.GetStats)
+	createThread(t, vo, 0x40000000, 1, "paused")
+	second := call(vo.GetStats)
+	if !first.Equal(second) {
+		t.Fatal("two reads within the window built different objects")
+	}
+	if got := second.Get("threadsCreated").Int(); got != 0 {
+		t.Errorf("cached stats show %d threads created, want the stale 0", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	refreshed := call(vo.GetStats)
+	if refreshed.Equal(first) {
+		t.Fatal("stats were not rebuilt after the window expired")
+	}
+	if got := refreshed.Get("threadsCreated").Int(); got != 1 {
+		t.Errorf("refreshed stats show %d threads created, want 1", got)
+	}
+
+	call(vo.SetStatsCacheMs, 0)
+	if call(vo.GetStats).Equal(call(vo.GetStats)) {
+		t.Error("reads are still cached after setStatsCacheMs(0)")
+	}
+}
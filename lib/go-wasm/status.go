@@ -0,0 +1,115 @@
+// Thread Status Transitions
+// Funnels every status change through one helper so it can be observed
+//
+// setStatus is the only writer of VMThread.status once a thread exists. Each
+// change is reported to the OnThreadStateChange callback through the event
+// queue (see events.go), so the callback never runs under the thread mutex
+// the transition was made with. Status changes are the lowest-priority
+// events and the first to be dropped when the queue is full.
+
+package main
+
+import (
+	"syscall/js"
+	"time"
+)
+
+// OnThreadStateChange registers a callback invoked as
+// callback(threadID, oldStatus, newStatus) on every thread status
+// transition. Passing null clears it.
+func (vo *VMOrchestrator) OnThreadStateChange(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	vo.stateChangeCallback = args[0]
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// setStatus moves a thread to a new status, stamps when it started waiting
+// and reports the transition. Setting the current status is a no-op. A
+// thread that would start running while another is focused is suspended
+// instead (see focus.go). Caller must hold thread.mutex.
+func (vo *VMOrchestrator) setStatus(thread *VMThread, status string) {
+	old := thread.status
+	if status == "running" && old != "running" && vo.holdForFocus(thread) {
+		status = "suspended" // held while another thread is focused
+	}
+	if old == status {
+		return
+	}
+	if old == "suspended" {
+		thread.focusHeld = false
+	}
+	thread.status = status
+	vo.traceStatusChange(thread.id, old, status)
+
+	switch {
+	case status == "waiting":
+		thread.waitingSince = time.Now()
+	case old == "waiting":
+		thread.waitingSince = time.Time{}
+		thread.endWait()
+	}
+
+	vo.callbackMutex.RLock()
+	observed := vo.stateChangeCallback.Type() == js.TypeFunction
+	vo.callbackMutex.RUnlock()
+
+	if observed {
+		threadID := thread.id
+		vo.postEvent(eventLow, "", func() { vo.fireStateChange(threadID, old, status) })
+	}
+}
+
+// ThreadStatus returns the thread's status under its mutex
+func (thread *VMThread) ThreadStatus() string {
+	thread.mutex.RLock()
+	defer thread.mutex.RUnlock()
+	return thread.status
+}
+
+// longestWait returns the thread that has been "waiting" the longest and for
+// how long, or ID -1 if none is. Yields are not waits and are skipped.
+func (vo *VMOrchestrator) longestWait(now time.Time) (threadID int, wait time.Duration) {
+	vo.threadMutex.RLock()
+	defer vo.threadMutex.RUnlock()
+
+	threadID = -1
+	for id, thread := range vo.threads {
+		thread.mutex.RLock()
+		if thread.status == "waiting" && !thread.yielding {
+			waited := now.Sub(thread.waitingSince)
+			// Ties go to the lower ID so the result does not depend on map order
+			if threadID < 0 || waited > wait || (waited == wait && id < threadID) {
+				threadID, wait = id, waited
+			}
+		}
+		thread.mutex.RUnlock()
+	}
+	return threadID, wait
+}
+
+// restoredWaitStart returns waitingSince for a thread restored with the
+// given status; waits are timed from the restore, not the original wait
+func restoredWaitStart(status string) time.Time {
+	if status == "waiting" {
+		return time.Now()
+	}
+	return time.Time{}
+}
+
+// fireStateChange invokes the registered state change callback, if any.
+// Runs on the event dispatcher.
+func (vo *VMOrchestrator) fireStateChange(threadID int, old, status string) {
+	vo.callbackMutex.RLock()
+	callback := vo.stateChangeCallback
+	vo.callbackMutex.RUnlock()
+
+	if callback.Type() == js.TypeFunction {
+		callback.Invoke(threadID, old, status)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLongestWaitGrows(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+	if got := stat(vo, "longestWaitThreadID"); got != -1 {
+		t.Fatalf("longestWaitThreadID = %v with no waiters, want -1", got)
+	}
+
+	first := createThread(t, vo, 0x40000000)
+	second := createThread(t, vo, 0x50000000)
+	requireOK(t, call(vo.WaitThread, first, 1))
+	time.Sleep(20 * time.Millisecond)
+	requireOK(t, call(vo.WaitThread, second, 1))
+
+	stats := call(vo.GetStats)
+	if got := stats.Get("longestWaitThreadID").Int(); got != first {
+		t.Fatalf("longestWaitThreadID = %d, want the earlier waiter %d", got, first)
+	}
+	before := stats.Get("longestWaitMs").Float()
+	if before < 20 {
+		t.Errorf("longestWaitMs = %v after waiting 20ms", before)
+	}
+
+	// The main thread keeps running while the wait grows
+	time.Sleep(30 * time.Millisecond)
+	if after := stat(vo, "longestWaitMs"); after < before+30 {
+		t.Errorf("longestWaitMs grew from %v to %v over 30ms", before, after)
+	}
+	if got := threadStatus(vo, 1); got != "running" {
+		t.Errorf("main thread is %q, want running", got)
+	}
+
+	requireOK(t, call(vo.WakeThread, first))
+	if got := stat(vo, "longestWaitThreadID"); got != float64(second) {
+		t.Errorf("longestWaitThreadID = %v after waking %d, want %d", got, first, second)
+	}
+	requireOK(t, call(vo.WakeThread, second))
+	if got := stat(vo, "longestWaitMs"); got != 0 {
+		t.Errorf("longestWaitMs = %v with no waiters, want 0", got)
+	}
+}
@@ -0,0 +1,151 @@
+// Syscall Dispatch
+// Routes guest system calls to JS handlers by syscall number
+//
+// When the guest executes a syscall instruction, the emulator bridge either
+// calls dispatchSyscall(threadID, number, args) itself or returns
+// { status: "syscall", syscall, args } from executeInstruction so the
+// orchestrator dispatches it before advancing past the instruction. The handler registered for the
+// number with RegisterSyscall runs synchronously as
+// handler(args, threadID), where args defaults to a copy of the thread's
+// registers, and a numeric return value is written to the result register
+// (r0). Handlers report failures through SetErrno. A syscall without a
+// handler sets the thread's errno to ENOSYS and fires OnUnhandledSyscall.
+// A handler that throws faults the thread like a throwing bridge call, and
+// DispatchSyscall then fails with bridge_exception.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+const (
+	// syscallResultRegister receives a handler's return value
+	syscallResultRegister = 0
+	// errnoENOSYS is Linux's "function not implemented" errno
+	errnoENOSYS = 38
+)
+
+// RegisterSyscall maps a syscall number to a handler, or removes the
+// mapping when passed null
+// Arguments: number, handler(args, threadID) or null
+func (vo *VMOrchestrator) RegisterSyscall(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[0].Type() != js.TypeNumber || !isCallbackArg(args[1]) {
+		return js.ValueOf(false)
+	}
+
+	number := args[0].Int()
+
+	vo.callbackMutex.Lock()
+	if args[1].Type() == js.TypeFunction {
+		vo.syscalls[number] = args[1]
+	} else {
+		delete(vo.syscalls, number)
+	}
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// OnUnhandledSyscall registers a callback invoked as
+// callback(threadID, number) when a syscall has no handler. Passing null
+// clears it.
+func (vo *VMOrchestrator) OnUnhandledSyscall(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	vo.unhandledSyscall = args[0]
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// DispatchSyscall runs the handler for a guest syscall on a thread. Returns
+// { handled, value }: handled is false for an unhandled syscall, and value
+// is what the handler returned (undefined if nothing).
+// Arguments: threadID, number, optional args array (defaults to registers)
+func (vo *VMOrchestrator) DispatchSyscall(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[0].Type() != js.TypeNumber || args[1].Type() != js.TypeNumber {
+		return vo.fail(false, errInvalidArgument, "dispatchSyscall requires a thread ID and a syscall number")
+	}
+
+	threadID, number := args[0].Int(), args[1].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	syscallArgs := js.Undefined()
+	if len(args) > 2 {
+		syscallArgs = args[2]
+	}
+
+	handled, value, err := vo.dispatchSyscall(thread, number, syscallArgs)
+	if err != nil {
+		return vo.fail(false, errBridgeException, "syscall %d handler threw and faulted thread %d: %v", number, threadID, err)
+	}
+	return vo.succeed(handled, map[string]interface{}{"handled": handled, "value": value})
+}
+
+// dispatchSyscall runs the handler for syscall number on a thread, passing
+// syscallArgs if it is an object and a copy of the registers otherwise.
+// Returns whether a handler was registered, what it returned, and the
+// exception if it threw and faulted the thread.
+func (vo *VMOrchestrator) dispatchSyscall(thread *VMThread, number int, syscallArgs js.Value) (handled bool, value js.Value, err error) {
+	vo.callbackMutex.RLock()
+	handler, registered := vo.syscalls[number]
+	vo.callbackMutex.RUnlock()
+
+	if !registered {
+		thread.mutex.Lock()
+		thread.errno = errnoENOSYS
+		thread.mutex.Unlock()
+
+		vo.logf(logDebug, "thread %d: unhandled syscall %d", thread.id, number)
+		vo.fireUnhandledSyscall(thread.id, number)
+		return false, js.Undefined(), nil
+	}
+
+	if syscallArgs.Type() != js.TypeObject {
+		thread.mutex.RLock()
+		syscallArgs = js.ValueOf(uint32sToJS(thread.registers))
+		thread.mutex.RUnlock()
+	}
+
+	if value, err = vo.callSyscallHandler(thread, handler, syscallArgs); err != nil {
+		return true, js.Undefined(), err
+	}
+
+	if value.Type() == js.TypeNumber {
+		thread.mutex.Lock()
+		if syscallResultRegister < len(thread.registers) {
+			thread.registers[syscallResultRegister] = uint32(value.Int())
+		}
+		thread.mutex.Unlock()
+	}
+	return true, value, nil
+}
+
+// callSyscallHandler invokes a handler, faulting the thread if it throws
+func (vo *VMOrchestrator) callSyscallHandler(thread *VMThread, handler, args js.Value) (js.Value, error) {
+	value, err := callJS(func() js.Value { return handler.Invoke(args, thread.id) })
+	if err != nil {
+		vo.fault(thread, faultException, err.Error(), nil)
+	}
+	return value, err
+}
+
+// fireUnhandledSyscall queues a call to the unhandled syscall callback
+func (vo *VMOrchestrator) fireUnhandledSyscall(threadID, number int) {
+	vo.postEvent(eventNormal, "", func() {
+		vo.callbackMutex.RLock()
+		callback := vo.unhandledSyscall
+		vo.callbackMutex.RUnlock()
+
+		if callback.Type() == js.TypeFunction {
+			callback.Invoke(threadID, number)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// sysAdd is the syscall number the tests register an adding handler for
+const sysAdd = 64
+
+func TestDispatchSyscall(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	if !call(vo.RegisterSyscall, sysAdd, jsFunction("args", "threadID", "return args[1] + args[2];")).Bool() {
+		t.Fatal("registerSyscall failed")
+	}
+	id := createThread(t, vo, 0x2000, 1, "paused")
+	requireOK(t, call(vo.SetRegister, id, 1, 40))
+	requireOK(t, call(vo.SetRegister, id, 2, 2))
+
+	// The handler sees the registers by default, and its result lands in r0
+	result := requireOK(t, call(vo.DispatchSyscall, id, sysAdd))
+	if !result.Get("handled").Bool() || result.Get("value").Int() != 42 {
+		t.Fatalf("dispatchSyscall = { handled: %v, value: %v }, want { true, 42 }", result.Get("handled"), result.Get("value"))
+	}
+	if got := call(vo.GetRegisters, id).Index(syscallResultRegister).Int(); got != 42 {
+		t.Errorf("result register = %d, want 42", got)
+	}
+
+	// Explicit args replace the registers
+	args := js.ValueOf([]interface{}{0, 5, 6})
+	if got := requireOK(t, call(vo.DispatchSyscall, id, sysAdd, args)).Get("value").Int(); got != 11 {
+		t.Errorf("dispatchSyscall with args returned %d, want 11", got)
+	}
+}
+
+func TestSyscallInstructionStatus(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func(args []js.Value) interface{} {
+			return map[string]interface{}{"status": statusSyscall, "syscall": sysAdd, "args": []interface{}{0, 3, 4}}
+		},
+	}))
+	call(vo.RegisterSyscall, sysAdd, jsFunction("args", "threadID", "return args[1] + args[2];"))
+	id := createThread(t, vo, 0x2000, 1, "paused")
+
+	if got := uint64(requireOK(t, call(vo.StepThread, id)).Get("pc").Float()); got != 0x2004 {
+		t.Errorf("pc = %#x after a syscall instruction, want 0x2004", got)
+	}
+	if got := call(vo.GetRegisters, id).Index(syscallResultRegister).Int(); got != 7 {
+		t.Errorf("result register = %d, want 7", got)
+	}
+}
+
+func TestUnhandledSyscall(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	unhandled := make(chan [2]int, 1)
+	call(vo.OnUnhandledSyscall, newCallback(t, func(args []js.Value) {
+		unhandled <- [2]int{args[0].Int(), args[1].Int()}
+	}))
+	call(vo.RegisterSyscall, sysAdd, jsFunction("args", "threadID", "return 0;"))
+	call(vo.RegisterSyscall, sysAdd, js.Null())
+	id := createThread(t, vo, 0x2000, 1, "paused")
+
+	if requireOK(t, call(vo.DispatchSyscall, id, sysAdd)).Get("handled").Bool() {
+		t.Fatal("a removed syscall handler still ran")
+	}
+	if got := call(vo.GetErrno, id).Int(); got != errnoENOSYS {
+		t.Errorf("errno = %d after an unhandled syscall, want ENOSYS", got)
+	}
+	select {
+	case got := <-unhandled:
+		if got != [2]int{id, sysAdd} {
+			t.Errorf("onUnhandledSyscall got (%d, %d), want (%d, %d)", got[0], got[1], id, sysAdd)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for onUnhandledSyscall")
+	}
+}
+
+func TestThrowingSyscallHandlerFaultsThread(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.RegisterSyscall, sysAdd, jsFunction("args", "threadID", `throw new Error("EIO")`))
+	id := createThread(t, vo, 0x2000, 1, "paused")
+
+	requireError(t, call(vo.DispatchSyscall, id, sysAdd), errBridgeException)
+	if got := threadStatus(vo, id); got != "faulted" {
+		t.Errorf("thread is %q after its syscall handler threw, want faulted", got)
+	}
+	requireError(t, call(vo.DispatchSyscall, 99, sysAdd), errUnknownThread)
+}
@@ -0,0 +1,79 @@
+// Thread Limit
+// Caps the number of active threads so a runaway guest cannot exhaust memory
+//
+// SetMaxThreads(n) makes thread creation fail with "thread_limit" once n
+// threads are active (zombies and faulted threads do not count); 0, the default, means no
+// limit. Each refused thread is counted in the threadCreationRejections
+// stat, and OnThreadLimitReached fires the first time a creation is
+// refused after the limit was set.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+)
+
+// SetMaxThreads sets the active thread limit, or removes it when passed 0.
+// Threads already above a lowered limit keep running.
+func (vo *VMOrchestrator) SetMaxThreads(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber || args[0].Int() < 0 {
+		return js.ValueOf(false)
+	}
+
+	atomic.StoreInt32(&vo.maxThreads, int32(args[0].Int()))
+	atomic.StoreInt32(&vo.threadLimitHit, 0)
+	return js.ValueOf(true)
+}
+
+// OnThreadLimitReached registers a callback invoked as callback(limit) the
+// first time the thread limit refuses a creation. Passing null clears it.
+func (vo *VMOrchestrator) OnThreadLimitReached(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	vo.threadLimitCallback = args[0]
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// admitThreads returns how many of n new threads fit under the thread
+// limit, counting and reporting the rest as rejected
+func (vo *VMOrchestrator) admitThreads(n int) int {
+	limit := int(atomic.LoadInt32(&vo.maxThreads))
+	if limit == 0 {
+		return n
+	}
+
+	vo.threadMutex.RLock()
+	admitted := vo.threads.Admit(n, limit)
+	vo.threadMutex.RUnlock()
+
+	if rejected := n - admitted; rejected > 0 {
+		vo.statsMutex.Lock()
+		vo.stats.RejectedThreads += uint64(rejected)
+		vo.statsMutex.Unlock()
+
+		vo.logf(logError, "thread limit %d reached, %d thread(s) not created", limit, rejected)
+		if atomic.CompareAndSwapInt32(&vo.threadLimitHit, 0, 1) {
+			vo.fireThreadLimitReached(limit)
+		}
+	}
+	return admitted
+}
+
+// fireThreadLimitReached queues a call to the thread limit callback
+func (vo *VMOrchestrator) fireThreadLimitReached(limit int) {
+	vo.postEvent(eventNormal, "", func() {
+		vo.callbackMutex.RLock()
+		callback := vo.threadLimitCallback
+		vo.callbackMutex.RUnlock()
+
+		if callback.Type() == js.TypeFunction {
+			callback.Invoke(limit)
+		}
+	})
+}
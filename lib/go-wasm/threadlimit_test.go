@@ -0,0 +1,49 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+func TestThreadLimit(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	reached := make(chan int, 4)
+	call(vo.OnThreadLimitReached, newCallback(t, func(args []js.Value) { reached <- args[0].Int() }))
+	if call(vo.SetMaxThreads, -1).Bool() {
+		t.Fatal("setMaxThreads(-1) succeeded")
+	}
+	if !call(vo.SetMaxThreads, 3).Bool() {
+		t.Fatal("setMaxThreads(3) failed")
+	}
+
+	ids := pausedThreads(t, vo, 3)
+	requireError(t, call(vo.CreateThread, 0x4000, 1, "paused"), errThreadLimit)
+	if got := stat(vo, "threadCreationRejections"); got != 1 {
+		t.Fatalf("threadCreationRejections = %v, want 1", got)
+	}
+	select {
+	case limit := <-reached:
+		if limit != 3 {
+			t.Errorf("onThreadLimitReached got limit %d, want 3", limit)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for onThreadLimitReached")
+	}
+
+	// Later rejections are counted but not reported again
+	requireError(t, call(vo.CreateThread, 0x4000, 1, "paused"), errThreadLimit)
+	if got := stat(vo, "threadCreationRejections"); got != 2 {
+		t.Errorf("threadCreationRejections = %v, want 2", got)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if len(reached) != 0 {
+		t.Error("onThreadLimitReached fired a second time")
+	}
+
+	// A terminated thread frees its slot, and 0 lifts the limit
+	requireOK(t, call(vo.KillThread, ids[0]))
+	createThread(t, vo, 0x4000, 1, "paused")
+	call(vo.SetMaxThreads, 0)
+	pausedThreads(t, vo, 5)
+}
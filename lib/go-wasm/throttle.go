@@ -0,0 +1,103 @@
+// Speed Limit
+// Paces execution to approximate a target instruction rate
+//
+// SetSpeedLimit caps how many instructions per second the VM executes, e.g.
+// to watch a guest in slow motion. Pacing is time-based: a pacer keeps the
+// time at which the instructions executed so far are "due" at the target
+// rate, and executeThread sleeps until then after each step or batch. Sleeps
+// that overshoot are made up by shorter ones, but idle time is not banked as
+// credit, so a thread resuming after a pause cannot burst above the limit. By default one pacer is shared by all threads; with
+// perThread set every thread is paced to the limit on its own.
+
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"syscall/js"
+	"time"
+)
+
+// maxThrottleSleep bounds each sleep so Stop is noticed promptly even at
+// very low limits
+const maxThrottleSleep = 10 * time.Millisecond
+
+// speedLimit is an active execution speed limit
+type speedLimit struct {
+	rate      float64 // instructions per second
+	perThread bool
+	mutex     sync.Mutex // guards global
+	global    pacer      // shared pacer when !perThread
+}
+
+// pacer tracks when the instructions executed so far are due
+type pacer struct {
+	next time.Time
+}
+
+// SetSpeedLimit caps execution at instructionsPerSecond across all threads,
+// or per thread when perThread is true. 0 removes the limit.
+// Arguments: instructionsPerSecond, optional perThread
+func (vo *VMOrchestrator) SetSpeedLimit(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber {
+		return js.ValueOf(false)
+	}
+
+	rate := args[0].Float()
+	if rate < 0 {
+		return js.ValueOf(false)
+	}
+	if rate == 0 {
+		vo.speedLimit.Store(nil)
+		return js.ValueOf(true)
+	}
+
+	perThread := len(args) > 1 && args[1].Truthy()
+	vo.speedLimit.Store(&speedLimit{rate: rate, perThread: perThread})
+	return js.ValueOf(true)
+}
+
+// throttle charges n executed instructions against the speed limit and
+// sleeps until they are due. Returns how long it slept.
+func (vo *VMOrchestrator) throttle(thread *VMThread, n int) time.Duration {
+	limit := vo.speedLimit.Load()
+	if limit == nil || n <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	if limit.perThread {
+		thread.mutex.Lock()
+		if thread.paceLimit != limit {
+			// The limit changed; start pacing afresh
+			thread.pace, thread.paceLimit = pacer{}, limit
+		}
+		wait = thread.pace.advance(now, n, limit.rate)
+		thread.mutex.Unlock()
+	} else {
+		limit.mutex.Lock()
+		wait = limit.global.advance(now, n, limit.rate)
+		limit.mutex.Unlock()
+	}
+
+	slept := time.Duration(0)
+	for slept < wait && atomic.LoadInt32(&vo.isRunning) == 1 {
+		step := min(wait-slept, maxThrottleSleep)
+		time.Sleep(step)
+		slept += step
+	}
+	return slept
+}
+
+// advance accounts for n instructions executed at now and returns how long
+// to wait until they are due at rate. Falling behind by less than
+// maxThrottleSleep is timer overshoot and is made up by the next waits;
+// anything longer is idle time and is dropped.
+func (p *pacer) advance(now time.Time, n int, rate float64) time.Duration {
+	if now.Sub(p.next) > maxThrottleSleep {
+		p.next = now
+	}
+	p.next = p.next.Add(time.Duration(float64(n) / rate * float64(time.Second)))
+	return p.next.Sub(now)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// measureRate runs the VM for window and returns the instructions per
+// second it executed
+func measureRate(t *testing.T, vo *VMOrchestrator, window time.Duration) float64 {
+	t.Helper()
+	time.Sleep(50 * time.Millisecond) // settle past startup
+	before, start := stat(vo, "instructionsExecuted"), time.Now()
+	time.Sleep(window)
+	after, elapsed := stat(vo, "instructionsExecuted"), time.Since(start)
+	return (after - before) / elapsed.Seconds()
+}
+
+func TestSpeedLimitIsShared(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	const limit = 2000
+	if !call(vo.SetSpeedLimit, limit).Bool() {
+		t.Fatal("setSpeedLimit failed")
+	}
+	requireOK(t, call(vo.Start))
+	createThread(t, vo, 0x40000000)
+
+	// Timer resolution under Node is about a millisecond, so allow 15%
+	if rate := measureRate(t, vo, 400*time.Millisecond); rate < limit*0.85 || rate > limit*1.15 {
+		t.Fatalf("ran %.0f instructions/s across two threads, want about %d", rate, limit)
+	}
+}
+
+func TestSpeedLimitPerThread(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	const limit = 1000
+	call(vo.SetSpeedLimit, limit, true)
+	// A worker sleeping out one thread's pace holds up the threads queued
+	// behind it, so give each thread its own worker
+	call(vo.SetMaxWorkers, 2)
+	requireOK(t, call(vo.Start))
+	createThread(t, vo, 0x40000000)
+
+	// Two paced workers each lose time when a stall outlasts the catch-up
+	// window, so allow more slack below the limit than above it
+	if rate := measureRate(t, vo, 600*time.Millisecond); rate < 2*limit*0.75 || rate > 2*limit*1.15 {
+		t.Fatalf("ran %.0f instructions/s with two threads limited to %d each, want about %d", rate, limit, 2*limit)
+	}
+}
+
+func TestSetSpeedLimitZeroRemovesLimit(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	if call(vo.SetSpeedLimit, -1).Bool() {
+		t.Error("a negative limit was accepted")
+	}
+	call(vo.SetSpeedLimit, 10)
+	call(vo.SetSpeedLimit, 0)
+	if vo.speedLimit.Load() != nil {
+		t.Error("setSpeedLimit(0) left a limit in place")
+	}
+}
@@ -0,0 +1,116 @@
+// Throughput Meter
+// Derives instructions-per-second from a sliding window of samples
+//
+// A sampler goroutine records (timestamp, instructionsExecuted) pairs into a
+// small ring buffer while the VM runs. The rate is computed between the
+// oldest sample still inside the window and the live counter, so once the VM
+// goes idle the old samples age out and the reported rate decays to zero.
+
+package main
+
+import (
+	"syscall/js"
+	"time"
+)
+
+const (
+	// throughputSamples is the ring buffer size; samples are taken every
+	// window/throughputSamples
+	throughputSamples = 16
+	// defaultThroughputWindow is the initial sliding window length
+	defaultThroughputWindow = time.Second
+)
+
+// throughputSample is one point in the throughput ring buffer
+type throughputSample struct {
+	at           time.Time
+	instructions uint64
+}
+
+// throughputMeter holds recent instruction count samples. Guarded by statsMutex.
+type throughputMeter struct {
+	window  time.Duration
+	samples [throughputSamples]throughputSample
+	next    int
+	count   int
+}
+
+// SetThroughputWindow sets the sliding window (in milliseconds) used to
+// compute instructionsPerSecond. Existing samples are discarded.
+func (vo *VMOrchestrator) SetThroughputWindow(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(false)
+	}
+
+	ms := args[0].Int()
+	if ms < 1 {
+		return js.ValueOf(false)
+	}
+
+	vo.statsMutex.Lock()
+	vo.throughput = throughputMeter{window: time.Duration(ms) * time.Millisecond}
+	vo.statsMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// sampleThroughput records instruction count samples until the run ends
+func (vo *VMOrchestrator) sampleThroughput(done <-chan struct{}) {
+	timer := time.NewTimer(vo.throughputInterval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-timer.C:
+			vo.statsMutex.Lock()
+			vo.throughput.record(now, vo.stats.InstructionsExecuted)
+			interval := vo.throughput.interval()
+			vo.statsMutex.Unlock()
+			timer.Reset(interval)
+		}
+	}
+}
+
+// throughputInterval returns the current sampling interval
+func (vo *VMOrchestrator) throughputInterval() time.Duration {
+	vo.statsMutex.RLock()
+	defer vo.statsMutex.RUnlock()
+	return vo.throughput.interval()
+}
+
+// interval is the time between samples for the configured window
+func (m *throughputMeter) interval() time.Duration {
+	return m.window / throughputSamples
+}
+
+// record appends a sample, overwriting the oldest once the ring is full
+func (m *throughputMeter) record(at time.Time, instructions uint64) {
+	m.samples[m.next] = throughputSample{at, instructions}
+	m.next = (m.next + 1) % throughputSamples
+	if m.count < throughputSamples {
+		m.count++
+	}
+}
+
+// rate returns instructions per second between the oldest sample inside
+// the window and the live counter
+func (m *throughputMeter) rate(now time.Time, instructions uint64) float64 {
+	cutoff := now.Add(-m.window)
+
+	// Walk from oldest to newest and use the first sample inside the window
+	for i := 0; i < m.count; i++ {
+		sample := m.samples[(m.next-m.count+i+throughputSamples)%throughputSamples]
+		if sample.at.Before(cutoff) {
+			continue
+		}
+
+		elapsed := now.Sub(sample.at).Seconds()
+		if elapsed <= 0 || instructions < sample.instructions {
+			return 0
+		}
+		return float64(instructions-sample.instructions) / elapsed
+	}
+	return 0
+}
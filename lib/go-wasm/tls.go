@@ -0,0 +1,83 @@
+// Thread-Local Storage
+// Per-thread key/value slots for guest runtime context (errno, current
+// exception pointer, ...)
+//
+// Values are restricted to JS primitives (numbers, strings, booleans) so
+// they stay serializable and can be captured in snapshots.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// SetTLS stores a value in a thread's local storage
+// Arguments: threadID, key, value (number, string, boolean, or null to delete)
+func (vo *VMOrchestrator) SetTLS(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 || args[1].Type() != js.TypeString {
+		return vo.fail(false, errInvalidArgument, "setTLS requires a thread ID, string key and value")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	key := args[1].String()
+	if args[2].IsNull() || args[2].IsUndefined() {
+		thread.mutex.Lock()
+		delete(thread.tls, key)
+		thread.mutex.Unlock()
+		return vo.succeed(true, nil)
+	}
+
+	value, ok := jsToPrimitive(args[2])
+	if !ok {
+		return vo.fail(false, errInvalidArgument, "TLS values must be numbers, strings or booleans")
+	}
+
+	thread.mutex.Lock()
+	if thread.tls == nil {
+		thread.tls = make(map[string]interface{})
+	}
+	thread.tls[key] = value
+	thread.mutex.Unlock()
+
+	return vo.succeed(true, nil)
+}
+
+// GetTLS reads a value from a thread's local storage
+// Returns null if the thread or key does not exist
+func (vo *VMOrchestrator) GetTLS(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[1].Type() != js.TypeString {
+		return js.Null()
+	}
+
+	thread := vo.getThread(args[0].Int())
+	if thread == nil {
+		return js.Null()
+	}
+
+	thread.mutex.RLock()
+	value, ok := thread.tls[args[1].String()]
+	thread.mutex.RUnlock()
+
+	if !ok {
+		return js.Null()
+	}
+	return js.ValueOf(value)
+}
+
+// copyTLS returns a shallow copy of a TLS map; values are immutable primitives
+func copyTLS(tls map[string]interface{}) map[string]interface{} {
+	if len(tls) == 0 {
+		return nil
+	}
+
+	copied := make(map[string]interface{}, len(tls))
+	for key, value := range tls {
+		copied[key] = value
+	}
+	return copied
+}
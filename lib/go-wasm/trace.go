@@ -0,0 +1,89 @@
+// Instruction Trace
+// Ring buffer of recently executed (threadID, pc) pairs for post-mortem
+// debugging
+//
+// The buffer (see internal/ring) is published through an atomic pointer, so
+// with tracing off the hot path costs a single nil check. Batched execution is disabled
+// while tracing because the PCs inside a batch are not visible to Go.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+
+	"github.com/aquifer/vm-orchestrator/internal/ring"
+)
+
+// traceEntry is one executed instruction
+type traceEntry struct {
+	threadID int
+	name     string // thread name at the time of execution
+	pc       uint64
+	seq      uint64 // instruction sequence number, see instructionSequence
+}
+
+// EnableTrace starts recording the last capacity executed instructions.
+// Any previously recorded trace is discarded.
+func (vo *VMOrchestrator) EnableTrace(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(false)
+	}
+
+	capacity := args[0].Int()
+	if capacity < 1 {
+		return js.ValueOf(false)
+	}
+
+	vo.trace.Store(ring.New[traceEntry](capacity))
+	return js.ValueOf(true)
+}
+
+// DisableTrace stops recording and drops the trace buffer
+func (vo *VMOrchestrator) DisableTrace(this js.Value, args []js.Value) interface{} {
+	vo.trace.Store(nil)
+	return js.ValueOf(true)
+}
+
+// GetTrace returns the recorded instructions, oldest first, as
+// [{ threadID, name, pc, seq }]. seq totally orders entries across threads
+// regardless of clock resolution. Empty when tracing is off.
+func (vo *VMOrchestrator) GetTrace(this js.Value, args []js.Value) interface{} {
+	buffer := vo.trace.Load()
+	if buffer == nil {
+		return js.ValueOf([]interface{}{})
+	}
+
+	entries := buffer.Snapshot()
+	trace := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		trace[i] = map[string]interface{}{
+			"threadID": entry.threadID,
+			"name":     entry.name,
+			"pc":       addressToJS(entry.pc),
+			"seq":      entry.seq,
+		}
+	}
+	return js.ValueOf(trace)
+}
+
+// traceInstruction records an executed instruction if tracing is on
+func (vo *VMOrchestrator) traceInstruction(thread *VMThread, pc, seq uint64) {
+	buffer := vo.trace.Load()
+	if buffer == nil {
+		return
+	}
+
+	thread.mutex.RLock()
+	name := thread.name
+	thread.mutex.RUnlock()
+
+	buffer.Push(traceEntry{threadID: thread.id, name: name, pc: pc, seq: seq})
+}
+
+// instructionSequence advances the global instruction sequence by n
+// executed instructions and returns the number of the last one. Every
+// instruction gets a distinct number, in execution order across threads.
+func (vo *VMOrchestrator) instructionSequence(n uint64) uint64 {
+	return atomic.AddUint64(&vo.sequence, n)
+}
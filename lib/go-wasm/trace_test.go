@@ -0,0 +1,109 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestTraceKeepsMostRecentEntries(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	const capacity, steps = 8, 20
+	if !call(vo.EnableTrace, capacity).Bool() {
+		t.Fatal("enableTrace failed")
+	}
+
+	id := createThread(t, vo, 0x1000, 1, "paused")
+	for i := 0; i < steps; i++ {
+		requireOK(t, call(vo.StepThread, id))
+	}
+
+	trace := call(vo.GetTrace)
+	if trace.Length() != capacity {
+		t.Fatalf("trace holds %d entries, want %d", trace.Length(), capacity)
+	}
+	for i := 0; i < capacity; i++ {
+		entry := trace.Index(i)
+		step := steps - capacity + i
+		if got, want := uint64(entry.Get("pc").Float()), uint64(0x1000+4*step); got != want {
+			t.Errorf("entry %d pc = %#x, want %#x", i, got, want)
+		}
+		if got := entry.Get("threadID").Int(); got != id {
+			t.Errorf("entry %d thread = %d, want %d", i, got, id)
+		}
+		if got, want := entry.Get("seq").Int(), step+1; got != want {
+			t.Errorf("entry %d seq = %d, want %d", i, got, want)
+		}
+	}
+
+	call(vo.DisableTrace)
+	if got := call(vo.GetTrace).Length(); got != 0 {
+		t.Errorf("trace holds %d entries after disable, want 0", got)
+	}
+}
+
+func TestEnableTraceRejectsZeroCapacity(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	if call(vo.EnableTrace, 0).Bool() {
+		t.Error("enableTrace(0) succeeded")
+	}
+}
+
+func TestTraceSequenceIsUniqueAcrossThreads(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	// Go bridge calls from several workers nest and stall each other, so
+	// the workers share a JS bridge instead
+	bridge := js.Global().Get("Object").New()
+	bridge.Set("executeInstruction", jsFunction("pc", "return true;"))
+	call(vo.Initialize, bridge)
+	call(vo.SetYieldStrategy, "gosched")
+	call(vo.SetMaxWorkers, 4)
+	call(vo.EnableTrace, 1<<16)
+	requireOK(t, call(vo.Start))
+	ids := []int{1}
+	for i := 1; i <= 3; i++ {
+		ids = append(ids, createThread(t, vo, uint64(0x10000*i)))
+	}
+	eventually(t, "every thread to run", func() bool {
+		for _, id := range ids {
+			if thread := call(vo.GetThread, id); thread.Get("instructionsExecuted").Int() < 100 {
+				return false
+			}
+		}
+		return true
+	})
+	requireOK(t, call(vo.Pause))
+	eventually(t, "the last quanta to be traced", func() bool {
+		traced := float64(call(vo.GetTrace).Length())
+		return traced == stat(vo, "instructionSequence") && traced == stat(vo, "instructionsExecuted")
+	})
+
+	trace := call(vo.GetTrace)
+	seen := make(map[int]bool, trace.Length())
+	last := map[int]int{} // thread ID -> its latest seq
+	for i := 0; i < trace.Length(); i++ {
+		entry := trace.Index(i)
+		seq, id := entry.Get("seq").Int(), entry.Get("threadID").Int()
+		if seen[seq] {
+			t.Fatalf("seq %d appears twice", seq)
+		}
+		seen[seq] = true
+		if seq <= last[id] {
+			t.Fatalf("thread %d seq went from %d to %d", id, last[id], seq)
+		}
+		last[id] = seq
+	}
+	if len(last) != 4 {
+		t.Errorf("trace covers %d threads, want 4", len(last))
+	}
+
+	// Every instruction was numbered, with no gaps
+	for seq := 1; seq <= trace.Length(); seq++ {
+		if !seen[seq] {
+			t.Fatalf("seq %d is missing from %d entries", seq, trace.Length())
+		}
+	}
+	if got := stat(vo, "instructionSequence"); got != float64(trace.Length()) {
+		t.Errorf("instructionSequence = %v, want %d", got, trace.Length())
+	}
+}
@@ -11,66 +11,272 @@
 package main
 
 import (
+	"math/rand"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"syscall/js"
 	"time"
+
+	"github.com/aquifer/vm-orchestrator/internal/engine"
+	"github.com/aquifer/vm-orchestrator/internal/ring"
 )
 
+// defaultInstructionLength is how far the PC advances when the emulator
+// does not report an instruction's length
+const defaultInstructionLength = 4
+
 // VMOrchestrator manages the overall Android VM execution
 type VMOrchestrator struct {
 	emulatorPtr   js.Value
-	isRunning     int32 // atomic bool
-	threads       map[int]*VMThread
+	emulatorMutex sync.RWMutex
+	isRunning     int32 // atomic: 0 stopped, 1 running, 2 stopping gracefully
+	paused        int32 // atomic, changed under schedMutex: 1 while frozen by Pause
+	threads       engine.Threads[*VMThread]
 	threadMutex   sync.RWMutex
-	threadCounter int32
-	stats         *VMStats
+	threadIDs     engine.IDs
+	maxStackDepth int32 // atomic
+	stackGrowStep int32 // atomic, fixed stack growth increment, 0 = doubling
+	batchSize     int32 // atomic, instructions per bridge call
+	registerCount int32 // atomic, size of each thread's register file
+	logLevel      int32 // atomic, most verbose level logged (logOff...logDebug)
+	reapTimeout   int64 // atomic, nanoseconds before zombies are reaped, 0 = never
+	freezeOnFault int32 // atomic: 1 to pause the VM when a thread faults
+	rejectStopped int32 // atomic: 1 to refuse thread creation while the VM is not running
+	maxThreads    int32 // atomic, active thread limit, 0 = unlimited
+	focused       int32 // atomic: ID of the thread FocusThread keeps runnable, 0 = none
+	bridgeTimeout int64 // atomic, nanoseconds a bridge call may run, 0 = no watchdog
+	legacyResults bool  // return bare values instead of { ok, ... } results
+	stats         *engine.Stats
 	statsMutex    sync.RWMutex
+	throughput    throughputMeter // guarded by statsMutex
+	guestClock    guestClock      // guarded by statsMutex
+	statsCache    statsCache      // guarded by statsMutex
+	statsCacheTTL int64           // atomic, nanoseconds a stats object is reused, 0 = no caching
+	runDone       chan struct{}   // closed when the current run stops, guarded by schedMutex
+	runQueue      engine.RunQueue[*VMThread]
+	schedMutex    sync.Mutex
+	schedCond     *sync.Cond
+	schedEpoch    uint64             // incremented on every pool start so stale workers exit
+	maxWorkers    int                // worker pool size, guarded by schedMutex
+	inFlight      int                // quanta currently executing, guarded by schedMutex
+	drained       chan struct{}      // closed when inFlight drops to 0, guarded by schedMutex
+	parked        int                // in-flight quanta parked at an instruction boundary, guarded by schedMutex
+	bridgeReady   bool               // an emulator is attached, guarded by schedMutex
+	tickMode      bool               // quanta are only dispatched when granted by Tick, guarded by schedMutex
+	tickBudget    int                // quanta granted by Tick and not yet dispatched, guarded by schedMutex
+	exitStates    map[int]threadExit // final state of terminated threads, guarded by threadMutex
+	zombies       map[int]*VMThread  // terminated threads kept until reaped, guarded by threadMutex
+
+	deterministic     bool       // seeded single-worker scheduling, guarded by schedMutex
+	deterministicSeed int64      // guarded by schedMutex
+	schedRand         *rand.Rand // picks the next thread in deterministic mode, guarded by schedMutex
+
+	cpuFeatures uint32 // atomic, feature bitmask advertised to the guest
+
+	threadLimitHit int32 // atomic: 1 once OnThreadLimitReached has fired for the current limit
+
+	sequence uint64 // atomic, instructions numbered so far, see instructionSequence
+
+	safepoint        int32            // atomic, changed under schedMutex: 1 while any safe point is held
+	safepointHolds   map[int]struct{} // outstanding safe point tokens, guarded by schedMutex
+	safepointCounter int              // last token issued, guarded by schedMutex
+
+	breakpoints     map[uint64]*breakpointCondition // nil condition = unconditional
+	breakpointMutex sync.RWMutex
+
+	interruptHandlers map[int]uint64 // vector -> handler address
+	interruptMutex    sync.RWMutex
+
+	trace      atomic.Pointer[ring.Buffer[traceEntry]]    // nil while tracing is off
+	schedTrace atomic.Pointer[ring.Buffer[scheduleEvent]] // nil while schedule tracing is off
+	faultLog   atomic.Pointer[ring.Buffer[faultRecord]]   // recent thread faults, never nil
+
+	speedLimit   atomic.Pointer[speedLimit]   // nil when execution is unthrottled
+	addressSpace atomic.Pointer[addressSpace] // nil = 64-bit wrapping PC
+
+	milestone atomic.Pointer[instructionMilestone] // nil when no milestone callback is registered
+	latency   atomic.Pointer[latencyHistogram]     // nil while latency recording is off
+	opcodes   atomic.Pointer[opcodeProfile]        // nil while opcode profiling is off
+	yield     atomic.Pointer[yieldStrategy]        // nil = "sleep0"
+	snapshots atomic.Pointer[autoSnapshotter]      // nil while automatic snapshots are off
+
+	regions     []memoryRegion // mapped guest memory, sorted by base
+	regionMutex sync.RWMutex
+
+	disassembler js.Value
+	disasmCache  map[uint64]string // instruction text by PC
+	disasmMutex  sync.RWMutex
+
+	casLocks [casStripes]sync.Mutex // serialize CompareAndSwap by address
+
+	guestMutexes      map[int]*guestMutex
+	guestMutexCounter int
+	guestCondVars     map[int]*guestCondVar
+	guestCondCounter  int
+	guestSyncMutex    sync.Mutex // guards guest synchronization objects
+
+	threadGroups map[int]struct{}
+	groupCounter int
+	groupMutex   sync.Mutex // guards thread groups and membership changes
+
+	groupQuotas map[int]*groupQuota // CPU quotas by group ID, guarded by schedMutex
+
+	breakpointCallback    js.Value
+	watchCallback         js.Value
+	stackOverflowCallback js.Value
+	terminatedListeners   []js.Value
+	deadlockCallback      js.Value
+	faultCallback         js.Value
+	stateChangeCallback   js.Value
+	idleCallback          js.Value
+	logCallback           js.Value
+	symbolizer            js.Value
+	freezeCallback        js.Value
+	syscalls              map[int]js.Value // syscall handlers by number
+	unhandledSyscall      js.Value
+	threadLimitCallback   js.Value
+	heartbeat             *statsHeartbeat // nil when no stats heartbeat is registered
+	callbackMutex         sync.RWMutex
+
+	events eventQueue // callbacks deferred out of locked sections
 }
 
 // VMThread represents an execution thread
 type VMThread struct {
 	id        int
-	pc        uint32
-	registers [16]uint32
+	name      string // optional label for diagnostics, not unique
+	pc        uint64
+	registers []uint32
 	stack     []uint32
-	status    string // "running", "waiting", "terminated"
+	status    string        // "ready", "running", "paused", "waiting", "suspended", "faulted", "terminated"; written via setStatus
+	priority  int           // scheduling weight, 1 = normal
+	queued    bool          // guarded by schedMutex: on the run queue or executing a quantum
+	done      chan struct{} // closed once the thread terminates
 	mutex     sync.RWMutex
-}
 
-// VMStats tracks execution statistics
-type VMStats struct {
-	instructionsExecuted uint64
-	memoryAllocated      uint64
-	threadsCreated      uint64
-	threadsTerminated   uint64
-	executionTime       time.Duration
-	lastUpdate          time.Time
+	// bypassBreakpoint lets the instruction a thread stopped on execute
+	// once without re-triggering the breakpoint
+	bypassBreakpoint bool
+	watches          map[int]struct{} // watched register indices
+
+	instructionsExecuted uint64                 // guarded by mutex
+	cyclesExecuted       uint64                 // emulated cycles, guarded by mutex
+	instructionLimit     uint64                 // auto-terminate after this many instructions, 0 = unlimited
+	exitReason           string                 // "halted", "stopped", "instruction_limit", "killed" or "migrated" once terminated, "faulted" once faulted
+	waitingOn            []int                  // threads this thread waits for while "waiting"
+	tls                  map[string]interface{} // thread-local storage, primitive values only
+	faultMessage         string                 // why the emulator bridge faulted the thread
+	faultAddress         *uint64                // address of the access that faulted the thread, if any
+	pendingInterrupts    []int                  // queued interrupt vectors, oldest first
+	yielding             bool                   // "waiting" only until the current quantum ends
+	affinity             int32                  // atomic: worker index the thread is pinned to, -1 = any
+	cpuTime              time.Duration          // time spent executing quanta and steps
+	pace                 pacer                  // per-thread speed limit pacing
+	paceLimit            *speedLimit            // the limit pace was started under
+	groupID              int                    // thread group, 0 = none
+	startMode            string                 // status StartThread gives a "ready" thread
+	waitingSince         time.Time              // when the thread last entered "waiting", zero otherwise
+	emulator             js.Value               // bridge override, undefined = orchestrator default
+	bridgeCallStart      int64                  // atomic: UnixNano start of the bridge call in progress, 0 = none
+	inheritedPriority    int                    // boost from threads blocked on mutexes it owns, 0 = none
+	createdAt            time.Time              // when the thread was created (or first created, if restored)
+	terminatedAt         time.Time              // when the thread terminated, zero while alive
+
+	// WaitThread waits, see wait.go
+	wakeable    bool        // the current wait can be ended by WakeThread or its timeout
+	waitTimer   *time.Timer // ends the current wait on timeout, nil without one
+	timedOut    bool        // the last wakeable wait ended by timing out
+	wakePending bool        // a WakeThread arrived before the wait it was meant to end
+
+	// resumeStatus is what a "waiting" thread returns to when any wait
+	// ends: "running", or "paused" if it was single-stepping. Guarded by
+	// mutex.
+	resumeStatus string
+
+	errno uint32 // last error reported for the thread (e.g. by a failed syscall), guarded by mutex
+
+	// lastPC is the instruction executing or last executed, so after a fault
+	// it names the faulting instruction. Batches set it to the last PC the
+	// bridge reports, or the PC the batch started at. Guarded by mutex.
+	lastPC uint64
+
+	contexts map[string]threadContext // saved by SaveContext, guarded by mutex
+
+	focusHeld bool // suspended by FocusThread until Unfocus, guarded by mutex
 }
 
-var (
-	globalOrchestrator *VMOrchestrator
-	orchestratorMutex  sync.Mutex
-)
+// threadExit records the final state of a terminated thread
+type threadExit struct {
+	id           int
+	name         string
+	pc           uint64
+	registers    []uint32
+	reason       string
+	instructions uint64
+	fault        string // fault message if the thread faulted
+}
 
-// CreateOrchestrator creates a new VM orchestrator instance
+// CreateOrchestrator creates a new, independent VM orchestrator instance.
+// Each call returns a fresh handle so several VMs can run side by side in
+// the same WASM module; thread IDs and stats are tracked per instance.
+// Accepts an optional options object: { registerCount } sets the register
+// file size of every thread (default 16) and { legacyResults: true } makes
+// methods return bare booleans and IDs instead of structured results (see
+// results.go). Returns null for invalid options.
 func CreateOrchestrator(this js.Value, args []js.Value) interface{} {
-	orchestratorMutex.Lock()
-	defer orchestratorMutex.Unlock()
-
-	if globalOrchestrator != nil {
-		return js.ValueOf(globalOrchestrator.toJSObject())
+	registerCount := defaultRegisterCount
+	legacyResults := false
+	if len(args) > 0 && args[0].Type() == js.TypeObject {
+		if count := args[0].Get("registerCount"); !count.IsUndefined() {
+			if count.Type() != js.TypeNumber || count.Int() < 1 || count.Int() > maxRegisterCount {
+				return js.Null()
+			}
+			registerCount = count.Int()
+		}
+		if legacy := args[0].Get("legacyResults"); !legacy.IsUndefined() {
+			if legacy.Type() != js.TypeBoolean {
+				return js.Null()
+			}
+			legacyResults = legacy.Bool()
+		}
 	}
 
+	orchestrator := newOrchestrator(registerCount)
+	orchestrator.legacyResults = legacyResults
+	return js.ValueOf(orchestrator.toJSObject())
+}
+
+// newOrchestrator allocates an orchestrator with empty thread and stats state
+func newOrchestrator(registerCount int) *VMOrchestrator {
 	orchestrator := &VMOrchestrator{
-		threads: make(map[int]*VMThread),
-		stats: &VMStats{
-			lastUpdate: time.Now(),
+		maxStackDepth: defaultMaxStackDepth,
+		registerCount: int32(registerCount),
+		threads:       make(map[int]*VMThread),
+		exitStates:    make(map[int]threadExit),
+		zombies:       make(map[int]*VMThread),
+		breakpoints:   make(map[uint64]*breakpointCondition),
+		maxWorkers:    defaultMaxWorkers,
+		batchSize:     1,
+		cpuFeatures:   baselineCPUFeatures,
+		stats: &engine.Stats{
+			LastUpdate: time.Now(),
 		},
+		throughput: throughputMeter{window: defaultThroughputWindow},
+		guestClock: guestClock{hz: defaultTickRate},
 	}
-
-	globalOrchestrator = orchestrator
-	return js.ValueOf(orchestrator.toJSObject())
+	orchestrator.schedCond = sync.NewCond(&orchestrator.schedMutex)
+	orchestrator.interruptHandlers = make(map[int]uint64)
+	orchestrator.guestMutexes = make(map[int]*guestMutex)
+	orchestrator.guestCondVars = make(map[int]*guestCondVar)
+	orchestrator.disasmCache = make(map[uint64]string)
+	orchestrator.events.capacity = defaultEventQueueSize
+	orchestrator.faultLog.Store(ring.New[faultRecord](defaultFaultLogSize))
+	orchestrator.threadGroups = make(map[int]struct{})
+	orchestrator.safepointHolds = make(map[int]struct{})
+	orchestrator.groupQuotas = make(map[int]*groupQuota)
+	orchestrator.syscalls = make(map[int]js.Value)
+	return orchestrator
 }
 
 // Initialize initializes the orchestrator with emulator pointer
@@ -79,7 +285,16 @@ func (vo *VMOrchestrator) Initialize(this js.Value, args []js.Value) interface{}
 		return js.ValueOf(false)
 	}
 
+	// Workers park until a usable bridge is attached, so publish readiness
+	// under schedMutex and wake them
+	vo.schedMutex.Lock()
+	vo.emulatorMutex.Lock()
 	vo.emulatorPtr = args[0]
+	vo.emulatorMutex.Unlock()
+	vo.bridgeReady = args[0].Truthy()
+	vo.schedMutex.Unlock()
+	vo.schedCond.Broadcast()
+
 	atomic.StoreInt32(&vo.isRunning, 0)
 	return js.ValueOf(true)
 }
@@ -87,144 +302,1129 @@ func (vo *VMOrchestrator) Initialize(this js.Value, args []js.Value) interface{}
 // Start begins VM execution
 func (vo *VMOrchestrator) Start(this js.Value, args []js.Value) interface{} {
 	if !atomic.CompareAndSwapInt32(&vo.isRunning, 0, 1) {
-		return js.ValueOf(false) // Already running
+		return vo.fail(false, errAlreadyRunning, "the VM is already running")
 	}
 
 	vo.statsMutex.Lock()
-	vo.stats.executionTime = 0
-	vo.stats.lastUpdate = time.Now()
+	vo.stats.ExecutionTime = 0
+	vo.stats.SetClock(time.Now(), true)
 	vo.statsMutex.Unlock()
 
+	done := vo.beginRun()
+	go vo.sampleThroughput(done)
+	vo.startScheduler()
+	vo.logf(logInfo, "VM started")
+
 	// Start main thread
 	vo.CreateThread(js.Value{}, []js.Value{js.ValueOf(0x1000)}) // Start at address 0x1000
 
-	return js.ValueOf(true)
+	return vo.succeed(true, nil)
 }
 
 // Stop halts VM execution
 func (vo *VMOrchestrator) Stop(this js.Value, args []js.Value) interface{} {
 	if !atomic.CompareAndSwapInt32(&vo.isRunning, 1, 0) {
-		return js.ValueOf(false) // Not running
+		return vo.fail(false, errNotRunning, "the VM is not running")
 	}
 
-	// Terminate all threads
-	vo.threadMutex.Lock()
-	for _, thread := range vo.threads {
-		thread.mutex.Lock()
-		thread.status = "terminated"
-		thread.mutex.Unlock()
+	vo.teardown()
+	return vo.succeed(true, nil)
+}
+
+// StopGraceful halts VM execution after every in-flight instruction
+// completes, so final thread state is intact. Returns a Promise resolving to
+// true once all workers have drained, or false if timeoutMs elapsed first;
+// in that case the VM is torn down abruptly as with Stop. A Promise is used
+// because blocking a JS call on a timer would stall the WASM event loop.
+func (vo *VMOrchestrator) StopGraceful(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return rejectedPromise("stopGraceful requires a timeout in milliseconds")
+	}
+	timeout := time.Duration(args[0].Int()) * time.Millisecond
+
+	// The stopping state keeps Start and Stop out until teardown completes
+	if !atomic.CompareAndSwapInt32(&vo.isRunning, 1, 2) {
+		return js.Global().Get("Promise").Call("resolve", false) // Not running
+	}
+
+	// Workers notice isRunning at the next instruction boundary; wake any
+	// parked ones so they exit too
+	vo.schedCond.Broadcast()
+
+	return newPromise(func(resolve, reject js.Value) {
+		drained := vo.waitForQuanta(timeout)
+		vo.teardown()
+		atomic.StoreInt32(&vo.isRunning, 0)
+		resolve.Invoke(drained)
+	})
+}
+
+// teardown terminates all threads, drops queued work and freezes the
+// execution clock. Called once isRunning has left the running state.
+func (vo *VMOrchestrator) teardown() {
+	vo.endRun()
+	vo.clearPause()
+	vo.clearSafepoints()
+	terminated := vo.terminateAllThreads()
+	vo.clearRunQueue()
+	vo.logf(logInfo, "VM stopped, %d threads terminated", terminated)
+
+	// Freeze the execution clock
+	vo.statsMutex.Lock()
+	vo.stats.SetClock(time.Now(), false)
+	vo.stats.ThreadsTerminated += uint64(terminated)
+	vo.statsMutex.Unlock()
+}
+
+// Reset clears all per-session state so the orchestrator can host a new VM
+// session: stats, thread IDs, threads, exit states, thread groups, guest
+// mutexes and condition variables, breakpoints, memory regions, cached
+// disassembly and the fault log.
+// The emulator bridge, callbacks and configuration are kept. Returns false
+// while the VM is running or stopping.
+func (vo *VMOrchestrator) Reset(this js.Value, args []js.Value) interface{} {
+	if atomic.LoadInt32(&vo.isRunning) != 0 {
+		return js.ValueOf(false)
 	}
+
+	vo.clearRunQueue()
+
+	vo.threadMutex.Lock()
 	vo.threads = make(map[int]*VMThread)
+	vo.exitStates = make(map[int]threadExit)
+	vo.zombies = make(map[int]*VMThread)
+	vo.threadIDs.Reset(0)
+	atomic.StoreInt32(&vo.focused, 0)
 	vo.threadMutex.Unlock()
 
+	vo.guestSyncMutex.Lock()
+	vo.guestMutexes = make(map[int]*guestMutex)
+	vo.guestMutexCounter = 0
+	vo.guestCondVars = make(map[int]*guestCondVar)
+	vo.guestCondCounter = 0
+	vo.guestSyncMutex.Unlock()
+
+	vo.groupMutex.Lock()
+	vo.threadGroups = make(map[int]struct{})
+	vo.groupCounter = 0
+	vo.groupMutex.Unlock()
+	vo.clearGroupQuotas()
+
+	vo.breakpointMutex.Lock()
+	vo.breakpoints = make(map[uint64]*breakpointCondition)
+	vo.breakpointMutex.Unlock()
+
+	vo.regionMutex.Lock()
+	vo.regions = nil
+	vo.regionMutex.Unlock()
+
+	vo.disasmMutex.Lock()
+	vo.disasmCache = make(map[uint64]string)
+	vo.disasmMutex.Unlock()
+
+	vo.statsMutex.Lock()
+	*vo.stats = engine.NewStats(time.Now())
+	vo.throughput = throughputMeter{window: vo.throughput.window}
+	vo.guestClock = guestClock{hz: vo.guestClock.hz, cycleHz: vo.guestClock.cycleHz}
+	vo.statsCache = statsCache{}
+	vo.statsMutex.Unlock()
+	atomic.StoreUint64(&vo.events.dropped, 0)
+	atomic.StoreUint64(&vo.events.failed, 0)
+	vo.faultLog.Load().Clear()
+	if auto := vo.snapshots.Load(); auto != nil {
+		auto.snapshots.Clear()
+	}
+	atomic.StoreUint64(&vo.sequence, 0)
+
 	return js.ValueOf(true)
 }
 
+// beginRun opens a new run, reseeds deterministic scheduling, starts the
+// stats heartbeat if one is registered and returns a channel closed when
+// the run ends. Background goroutines tied to a run select on it to exit
+// cleanly.
+func (vo *VMOrchestrator) beginRun() <-chan struct{} {
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+
+	vo.runDone = make(chan struct{})
+	vo.reseedScheduler()
+	vo.startHeartbeat(vo.runDone)
+	go vo.watchdog(vo.runDone)
+	return vo.runDone
+}
+
+// endRun closes the current run's done channel
+func (vo *VMOrchestrator) endRun() {
+	vo.schedMutex.Lock()
+	defer vo.schedMutex.Unlock()
+
+	if vo.runDone != nil {
+		close(vo.runDone)
+		vo.runDone = nil
+	}
+}
+
 // CreateThread creates a new execution thread
+// Arguments: startPC, optional priority (defaults to 1), optional mode
+// ("running" or "paused"; paused threads only advance via StepThread),
+// optional name, optional options object. With { autostart: false } the
+// thread is created "ready" and does not enter its mode until StartThread,
+// so registers and breakpoints can be set before its first instruction.
+// { stackCapacity } sets the initial stack capacity (default 1024 entries).
+// While the VM is stopped the thread waits on the run queue until Start,
+// unless SetAllowThreadsWhileStopped(false) makes the call fail instead.
+// While regions are mapped, startPC must pass ValidateAddress for "execute".
 func (vo *VMOrchestrator) CreateThread(this js.Value, args []js.Value) interface{} {
 	if len(args) < 1 {
-		return js.ValueOf(-1)
+		return vo.fail(-1, errInvalidArgument, "createThread requires a start PC")
+	}
+	if !vo.creationAllowed() {
+		return vo.fail(-1, errNotRunning, "threads cannot be created while the VM is stopped")
+	}
+
+	spec := defaultThreadSpec()
+
+	var ok bool
+	if spec.startPC, ok = jsToAddress(args[0]); !ok {
+		return vo.fail(-1, errInvalidArgument, "invalid start PC")
+	}
+	if len(args) > 1 && args[1].Type() == js.TypeNumber {
+		spec.priority = args[1].Int()
+	}
+	if len(args) > 2 && args[2].Type() == js.TypeString {
+		spec.status = args[2].String()
+	}
+	if len(args) > 3 && args[3].Type() == js.TypeString {
+		spec.name = args[3].String()
+	}
+	if len(args) > 4 && args[4].Type() == js.TypeObject {
+		if message := spec.applyOptions(args[4]); message != "" {
+			return vo.fail(-1, errInvalidArgument, "%s", message)
+		}
+	}
+	if message := spec.validate(); message != "" {
+		return vo.fail(-1, errInvalidArgument, "%s", message)
+	}
+	if code, message := vo.validateAddress(spec.startPC, permExecute); code != "" {
+		return vo.fail(-1, code, "invalid start PC: %s", message)
+	}
+
+	if vo.admitThreads(1) == 0 {
+		return vo.fail(-1, errThreadLimit, "the thread limit has been reached")
 	}
 
-	startPC := uint32(args[0].Int())
+	thread := vo.newThread(spec)
+	vo.addThreads(thread)
+	return vo.succeed(thread.id, map[string]interface{}{"threadID": thread.id})
+}
+
+// SetAllowThreadsWhileStopped sets whether CreateThread and CreateThreads
+// work while the VM is not running. Allowed (the default), the new threads
+// are queued and begin executing when the VM starts; otherwise creation
+// fails with "not_running".
+func (vo *VMOrchestrator) SetAllowThreadsWhileStopped(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeBoolean {
+		return js.ValueOf(false)
+	}
 
-	threadID := int(atomic.AddInt32(&vo.threadCounter, 1))
-	thread := &VMThread{
-		id:     threadID,
-		pc:     startPC,
-		stack:  make([]uint32, 0, 1024),
-		status: "running",
+	var reject int32
+	if !args[0].Bool() {
+		reject = 1
 	}
+	atomic.StoreInt32(&vo.rejectStopped, reject)
+	return js.ValueOf(true)
+}
+
+// creationAllowed reports whether threads may be created in the current VM
+// state
+func (vo *VMOrchestrator) creationAllowed() bool {
+	return atomic.LoadInt32(&vo.isRunning) == 1 || atomic.LoadInt32(&vo.rejectStopped) == 0
+}
+
+// threadSpec holds the parameters of a thread to create
+type threadSpec struct {
+	startPC       uint64
+	priority      int
+	status        string // "running" or "paused", the mode the thread starts in
+	name          string
+	autostart     bool
+	stackCapacity int
+}
 
+// defaultThreadSpec returns the spec CreateThread starts from before
+// applying its arguments
+func defaultThreadSpec() threadSpec {
+	return threadSpec{
+		priority:      defaultThreadPriority,
+		status:        "running",
+		autostart:     true,
+		stackCapacity: defaultStackCapacity,
+	}
+}
+
+// applyOptions reads the CreateThread options object ({ autostart,
+// stackCapacity }). Returns an error message, or "" if the options are valid.
+func (spec *threadSpec) applyOptions(options js.Value) string {
+	if autostart := options.Get("autostart"); !autostart.IsUndefined() {
+		if autostart.Type() != js.TypeBoolean {
+			return "autostart must be a boolean"
+		}
+		spec.autostart = autostart.Bool()
+	}
+	if capacity := options.Get("stackCapacity"); !capacity.IsUndefined() {
+		if capacity.Type() != js.TypeNumber || capacity.Int() < 0 {
+			return "stackCapacity must be a non-negative integer"
+		}
+		spec.stackCapacity = capacity.Int()
+	}
+	return ""
+}
+
+// validate checks the priority and mode. Returns an error message, or "" if
+// the spec is valid.
+func (spec *threadSpec) validate() string {
+	if spec.priority < 1 {
+		return "priority must be at least 1"
+	}
+	if spec.status != "running" && spec.status != "paused" {
+		return "mode must be \"running\" or \"paused\""
+	}
+	return ""
+}
+
+// newThread allocates a thread with a fresh ID from a validated spec. The
+// thread is not visible until addThreads.
+func (vo *VMOrchestrator) newThread(spec threadSpec) *VMThread {
+	status, startMode := spec.status, ""
+	if !spec.autostart {
+		startMode, status = status, "ready"
+	}
+
+	return &VMThread{
+		id:        vo.threadIDs.Next(),
+		name:      spec.name,
+		pc:        spec.startPC,
+		registers: make([]uint32, atomic.LoadInt32(&vo.registerCount)),
+		stack:     make([]uint32, 0, min(spec.stackCapacity, int(atomic.LoadInt32(&vo.maxStackDepth)))),
+		status:    status,
+		priority:  spec.priority,
+		affinity:  -1,
+		done:      make(chan struct{}),
+		startMode: startMode,
+		createdAt: time.Now(),
+	}
+}
+
+// addThreads publishes newly allocated threads, counts them and schedules
+// the running ones. Running threads created while another thread is
+// focused are held suspended instead.
+func (vo *VMOrchestrator) addThreads(threads ...*VMThread) {
 	vo.threadMutex.Lock()
-	vo.threads[threadID] = thread
+	for _, thread := range threads {
+		if thread.status == "running" && vo.holdForFocus(thread) {
+			thread.status = "suspended" // not yet published, see FocusThread
+		}
+		vo.threads[thread.id] = thread
+	}
+	active := vo.threads.Active()
 	vo.threadMutex.Unlock()
 
 	vo.statsMutex.Lock()
-	vo.stats.threadsCreated++
+	vo.stats.ThreadsAdded(len(threads), active)
 	vo.statsMutex.Unlock()
 
-	// Start thread execution (async in WASM via JavaScript)
-	// Note: In WebAssembly, goroutines are handled differently
-	// We'll use JavaScript's async/await for concurrency
-	go func() {
-		vo.executeThread(thread)
-	}()
+	for _, thread := range threads {
+		vo.logf(logInfo, "thread %d created at %#x (%s)", thread.id, thread.pc, thread.status)
 
-	return js.ValueOf(threadID)
+		// Hand the thread to the scheduler rather than free-running it in
+		// its own goroutine, so instruction quanta are dispatched by priority
+		if thread.status == "running" {
+			vo.enqueueThread(thread)
+		}
+	}
 }
 
-// executeThread executes a thread's instructions
-func (vo *VMOrchestrator) executeThread(thread *VMThread) {
-	for atomic.LoadInt32(&vo.isRunning) == 1 {
+// executeThread executes up to quantum instructions on a thread.
+// A thread that leaves the "running" status (e.g. suspended) ends its
+// quantum early and is not requeued, so it consumes no CPU until resumed.
+func (vo *VMOrchestrator) executeThread(thread *VMThread, quantum int) {
+	// Time spent sleeping for the speed limit is not CPU time
+	start, throttled := time.Now(), time.Duration(0)
+	defer func() { vo.chargeCPUTime(thread, start.Add(throttled)) }()
+
+	sinceSleep := 0
+	for executed := 0; executed < quantum; {
+		if atomic.LoadInt32(&vo.isRunning) != 1 {
+			return
+		}
+		if vo.mustPark() && !vo.parkAtBoundary() {
+			return
+		}
+
 		thread.mutex.Lock()
 		if thread.status != "running" {
 			thread.mutex.Unlock()
-			break
+			return
+		}
+		if len(thread.pendingInterrupts) > 0 {
+			thread.mutex.Unlock()
+			if !vo.deliverInterrupt(thread) {
+				return
+			}
+			continue
 		}
 		pc := thread.pc
+		if !thread.bypassBreakpoint && vo.breakpointHit(thread, pc) {
+			vo.setStatus(thread, "paused")
+			thread.bypassBreakpoint = true
+			thread.mutex.Unlock()
+			vo.fireBreakpoint(thread.id, pc)
+			return
+		}
 		thread.mutex.Unlock()
 
-		// Execute instruction via emulator
-		if vo.emulatorPtr.Truthy() {
-			// Call C++ emulator's executeInstruction
-			// This would need to be bridged properly
-			result := vo.emulatorPtr.Call("executeInstruction", js.ValueOf(int(pc)))
-			if !result.Bool() {
-				break
-			}
+		used, ok := vo.step(thread, pc, quantum-executed)
+		if !ok {
+			return
 		}
+		executed += used
+		throttled += vo.throttle(thread, used)
 
-		// Update PC
-		thread.mutex.Lock()
-		thread.pc += 4
-		thread.mutex.Unlock()
+		// Yield to other goroutines
+		vo.yieldWorker(used, &sinceSleep)
+	}
+}
+
+// stepInstruction executes the instruction at pc for a thread, advances its
+// PC and updates stats. Returns false if the emulator halted the thread.
+func (vo *VMOrchestrator) stepInstruction(thread *VMThread, pc uint64) bool {
+	thread.mutex.Lock()
+	exhausted := thread.instructionLimit > 0 && thread.instructionsExecuted >= thread.instructionLimit
+	watched := thread.watchedValues()
+	if !exhausted {
+		thread.lastPC = pc
+	}
+	thread.mutex.Unlock()
 
-		// Update stats
-		vo.statsMutex.Lock()
-		vo.stats.instructionsExecuted++
-		vo.statsMutex.Unlock()
+	if exhausted {
+		vo.terminateThread(thread, "instruction_limit")
+		return false
+	}
 
-		// Yield to other goroutines
-		time.Sleep(0)
+	enforced := vo.regionsMapped()
+	if enforced && !vo.accessAllowed(pc, permExecute) {
+		vo.segfault(thread, pc, "execute")
+		return false
 	}
 
-	// Thread terminated
+	emulator := vo.threadEmulator(thread)
+	length := uint64(defaultInstructionLength)
+	cycles := uint64(1)
+	yield := false
+	var target uint64
+	branched := false
+
+	// Execute instruction via emulator
+	if emulator.Truthy() {
+		// Call C++ emulator's executeInstruction
+		// This would need to be bridged properly
+		result, ok := vo.callBridge(thread, emulator, "executeInstruction", addressToJS(pc))
+		if !ok {
+			return false
+		}
+		var status string
+		if status, length, yield = instructionResult(result); !vo.checkStatus(thread, result, status, pc) {
+			return false
+		}
+		cycles = reportedCycles(result, 1)
+		target, branched = branchTarget(result)
+		if enforced && !vo.checkAccesses(thread, result) {
+			return false
+		}
+		if profile := vo.opcodes.Load(); profile != nil {
+			profile.record(result)
+		}
+		if status == statusSyscall && !vo.instructionSyscall(thread, result, pc) {
+			return false
+		}
+	}
+
+	vo.traceInstruction(thread, pc, vo.instructionSequence(1))
+
+	next, inRange := vo.advancePC(pc, length)
+	if branched {
+		next, inRange = vo.boundPC(target)
+	}
+	if !inRange {
+		vo.pcOutOfRange(thread, pc)
+		return false
+	}
+
+	// Update PC
+	thread.mutex.Lock()
+	thread.pc = next
+	thread.instructionsExecuted++
+	thread.cyclesExecuted += cycles
+	thread.bypassBreakpoint = false
+	changes := thread.changedRegisters(watched)
+	if len(changes) > 0 && thread.status == "running" {
+		vo.setStatus(thread, "paused")
+	}
+	thread.mutex.Unlock()
+
+	// Update stats
+	vo.countInstructions(1, cycles)
+
+	for _, change := range changes {
+		vo.fireWatch(thread.id, change)
+	}
+
+	if yield {
+		vo.yieldThread(thread)
+	}
+	return true
+}
+
+// chargeCPUTime adds the time since start to the thread's and the VM's CPU
+// time. The clock behind time.Now in browsers is performance.now, which is
+// coarsened to between 5µs and 1ms depending on isolation settings, so a
+// single step may be charged as zero; totals over many quanta are accurate.
+func (vo *VMOrchestrator) chargeCPUTime(thread *VMThread, start time.Time) {
+	elapsed := time.Since(start)
+
 	thread.mutex.Lock()
-	thread.status = "terminated"
+	thread.cpuTime += elapsed
+	groupID := thread.groupID
 	thread.mutex.Unlock()
 
+	vo.chargeGroup(groupID, elapsed)
+
+	vo.statsMutex.Lock()
+	vo.stats.CPUTime += elapsed
+	vo.statsMutex.Unlock()
+}
+
+// instructionResult decodes an executeInstruction result: either a legacy
+// boolean, or { status, length, yield } for ISAs with variable-length
+// instructions and spin hints (cycles, opcode and pc are read separately).
+// The status is one of the instruction statuses (see bridgestatus.go); a
+// plain boolean or an object with { ok } instead of a status means
+// statusOK or statusHalt. The length falls back to
+// defaultInstructionLength when not reported.
+func instructionResult(result js.Value) (status string, length uint64, yield bool) {
+	if result.Type() != js.TypeObject {
+		return okStatus(result.Bool()), defaultInstructionLength, false
+	}
+
+	length = defaultInstructionLength
+	if reported := result.Get("length"); reported.Type() == js.TypeNumber && reported.Int() > 0 {
+		length = uint64(reported.Int())
+	}
+	status = okStatus(result.Get("ok").Truthy())
+	if reported := result.Get("status"); reported.Type() == js.TypeString {
+		status = reported.String()
+	}
+	return status, length, result.Get("yield").Truthy()
+}
+
+// branchTarget returns the PC an executeInstruction result sets, as
+// { status: "branch", pc } (or legacy { ok, pc }) for branches, calls and
+// returns. The PC is only advanced past
+// the instruction when no target is reported.
+func branchTarget(result js.Value) (uint64, bool) {
+	if result.Type() != js.TypeObject {
+		return 0, false
+	}
+	return jsToAddress(result.Get("pc"))
+}
+
+// emulator returns the attached emulator bridge
+func (vo *VMOrchestrator) emulator() js.Value {
+	vo.emulatorMutex.RLock()
+	defer vo.emulatorMutex.RUnlock()
+	return vo.emulatorPtr
+}
+
+// terminateAllThreads terminates every active thread and empties the thread
+// map. Returns the number of threads terminated.
+func (vo *VMOrchestrator) terminateAllThreads() int {
+	var exits []threadExit
+
+	vo.threadMutex.Lock()
+	for id, thread := range vo.threads {
+		if exit, _, ok := vo.markTerminated(thread, "stopped"); ok {
+			vo.exitStates[id] = exit
+			exits = append(exits, exit)
+		}
+	}
+	vo.threads = make(map[int]*VMThread)
+	vo.threadMutex.Unlock()
+
+	sort.Slice(exits, func(i, j int) bool { return exits[i].id < exits[j].id })
+	for _, exit := range exits {
+		vo.fireThreadTerminated(exit)
+	}
+	return len(exits)
+}
+
+// terminateThread marks a thread terminated and removes it from the thread
+// map, firing the idle callback if it was the last thread (see idle.go)
+func (vo *VMOrchestrator) terminateThread(thread *VMThread, reason string) {
+	exit, wasActive, ok := vo.markTerminated(thread, reason)
+	if !ok {
+		return
+	}
+
 	vo.threadMutex.Lock()
 	delete(vo.threads, thread.id)
+	vo.addZombie(thread)
+	vo.exitStates[thread.id] = exit
+	idle := wasActive && vo.threads.Active() == 0 && atomic.LoadInt32(&vo.isRunning) == 1
 	vo.threadMutex.Unlock()
 
 	vo.statsMutex.Lock()
-	vo.stats.threadsTerminated++
+	vo.stats.ThreadsTerminated++
 	vo.statsMutex.Unlock()
+
+	vo.logf(logInfo, "thread %d terminated (%s)", exit.id, exit.reason)
+	vo.fireThreadTerminated(exit)
+	if idle {
+		vo.fireIdle()
+	}
+}
+
+// KillThread forcibly terminates one thread with exit reason "killed"
+// without stopping the VM. A thread mid-quantum stops at its next
+// instruction boundary; guest mutexes it holds pass to their oldest
+// waiters.
+func (vo *VMOrchestrator) KillThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(false, errInvalidArgument, "killThread requires a thread ID")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	vo.killThread(thread)
+	return vo.succeed(true, nil)
+}
+
+// killThread terminates a thread with reason "killed" and releases the
+// guest mutexes it owns, so their waiters are not orphaned
+func (vo *VMOrchestrator) killThread(thread *VMThread) {
+	vo.terminateThread(thread, "killed")
+	vo.releaseOwnedMutexes(thread.id)
+}
+
+// OnThreadTerminated registers a listener invoked as callback(exit) each time
+// a thread terminates, where exit has the same shape JoinThread resolves
+// with. Several listeners may be registered; passing null removes them all.
+// Listeners run on the event dispatcher like other callbacks (see
+// events.go), so they may call back into the VM; termination events are
+// never dropped.
+func (vo *VMOrchestrator) OnThreadTerminated(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	if args[0].Type() == js.TypeFunction {
+		vo.terminatedListeners = append(vo.terminatedListeners, args[0])
+	} else {
+		vo.terminatedListeners = nil
+	}
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// fireThreadTerminated queues a call to every termination listener
+func (vo *VMOrchestrator) fireThreadTerminated(exit threadExit) {
+	vo.postEvent(eventCritical, "", func() {
+		vo.callbackMutex.RLock()
+		listeners := vo.terminatedListeners
+		vo.callbackMutex.RUnlock()
+
+		if len(listeners) == 0 {
+			return
+		}
+
+		payload := js.ValueOf(exit.toJSObject())
+		for _, listener := range listeners {
+			listener.Invoke(payload)
+		}
+	})
+}
+
+// SuspendThread parks a running thread without stopping the VM
+// Suspending an already-suspended thread is a no-op that returns true
+func (vo *VMOrchestrator) SuspendThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(false, errInvalidArgument, "suspendThread requires a thread ID")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.Lock()
+	defer thread.mutex.Unlock()
+
+	switch thread.status {
+	case "suspended":
+		return vo.succeed(true, nil)
+	case "running":
+		vo.setStatus(thread, "suspended")
+		return vo.succeed(true, nil)
+	default:
+		return vo.fail(false, errInvalidState, "thread %d is %s", threadID, thread.status)
+	}
+}
+
+// ResumeThread returns a suspended thread to "running" and wakes the
+// scheduler. While another thread is focused it stays held until Unfocus.
+func (vo *VMOrchestrator) ResumeThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(false, errInvalidArgument, "resumeThread requires a thread ID")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.Lock()
+	if status := thread.status; status != "suspended" {
+		thread.mutex.Unlock()
+		return vo.fail(false, errInvalidState, "thread %d is %s, not suspended", threadID, status)
+	}
+	vo.setStatus(thread, "running")
+	thread.mutex.Unlock()
+
+	vo.enqueueThread(thread)
+	return vo.succeed(true, nil)
+}
+
+// StartThread moves a thread created with { autostart: false } from "ready"
+// into the mode it was created with
+func (vo *VMOrchestrator) StartThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(false, errInvalidArgument, "startThread requires a thread ID")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.Lock()
+	if status := thread.status; status != "ready" {
+		thread.mutex.Unlock()
+		return vo.fail(false, errInvalidState, "thread %d is %s, not ready", threadID, status)
+	}
+	mode := thread.startMode
+	if mode == "" {
+		mode = "running" // e.g. restored from a snapshot
+	}
+	vo.setStatus(thread, mode)
+	thread.mutex.Unlock()
+
+	if mode == "running" {
+		vo.enqueueThread(thread)
+	}
+	return vo.succeed(true, nil)
+}
+
+// StepThread executes exactly one instruction on a paused thread and returns
+// the new PC, or -1 if the thread is unknown, terminated or not paused
+func (vo *VMOrchestrator) StepThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(-1, errInvalidArgument, "stepThread requires a thread ID")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(-1, threadID)
+	}
+
+	thread.mutex.RLock()
+	status := thread.status
+	pc := thread.pc
+	thread.mutex.RUnlock()
+
+	if status != "paused" {
+		return vo.fail(-1, errInvalidState, "thread %d is %s, not paused", threadID, status)
+	}
+	start := time.Now()
+	_, ok := vo.step(thread, pc, 1)
+	vo.chargeCPUTime(thread, start)
+	if !ok {
+		return vo.fail(-1, errHalted, "thread %d stopped while stepping", threadID)
+	}
+
+	thread.mutex.RLock()
+	next := addressToJS(thread.pc)
+	thread.mutex.RUnlock()
+	return vo.succeed(next, map[string]interface{}{"pc": next})
+}
+
+// SetThreadMode switches a thread between free-running ("running") and
+// single-step ("paused") execution
+func (vo *VMOrchestrator) SetThreadMode(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[1].Type() != js.TypeString {
+		return vo.fail(false, errInvalidArgument, "setThreadMode requires a thread ID and a mode")
+	}
+
+	mode := args[1].String()
+	if mode != "running" && mode != "paused" {
+		return vo.fail(false, errInvalidArgument, "mode must be \"running\" or \"paused\"")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.Lock()
+	if status := thread.status; status != "running" && status != "paused" {
+		thread.mutex.Unlock()
+		return vo.fail(false, errInvalidState, "thread %d is %s", threadID, status)
+	}
+	vo.setStatus(thread, mode)
+	thread.mutex.Unlock()
+
+	if mode == "running" {
+		vo.enqueueThread(thread)
+	}
+	return vo.succeed(true, nil)
+}
+
+// SetThreadName labels a thread for stats, traces and exit records
+// Arguments: threadID, name (an empty string clears it)
+func (vo *VMOrchestrator) SetThreadName(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 || args[1].Type() != js.TypeString {
+		return vo.fail(false, errInvalidArgument, "setThreadName requires a thread ID and a string name")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.Lock()
+	thread.name = args[1].String()
+	thread.mutex.Unlock()
+
+	return vo.succeed(true, nil)
+}
+
+// SetThreadInstructionLimit caps how many instructions a thread may execute
+// before it auto-terminates with reason "instruction_limit"
+// Arguments: threadID, limit (0 = unlimited)
+func (vo *VMOrchestrator) SetThreadInstructionLimit(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(false, errInvalidArgument, "setThreadInstructionLimit requires a thread ID and a limit")
+	}
+
+	limit := args[1].Int()
+	if limit < 0 {
+		return vo.fail(false, errInvalidArgument, "limit must not be negative")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	thread.mutex.Lock()
+	thread.instructionLimit = uint64(limit)
+	thread.mutex.Unlock()
+
+	return vo.succeed(true, nil)
+}
+
+// JoinThread returns a Promise that resolves with the thread's exit PC and
+// final register file once it terminates or faults (reason "faulted").
+// Already-terminated or faulted threads resolve immediately; IDs that never
+// existed reject.
+func (vo *VMOrchestrator) JoinThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return rejectedPromise("joinThread requires a thread ID")
+	}
+	threadID := args[0].Int()
+
+	vo.threadMutex.RLock()
+	thread := vo.threads[threadID]
+	exit, exited := vo.exitStates[threadID]
+	vo.threadMutex.RUnlock()
+
+	if thread == nil && !exited {
+		return rejectedPromise("unknown thread")
+	}
+
+	return newPromise(func(resolve, reject js.Value) {
+		if thread != nil {
+			<-thread.done
+			thread.mutex.RLock()
+			exit = thread.exitState()
+			thread.mutex.RUnlock()
+		}
+		resolve.Invoke(exit.toJSObject())
+	})
+}
+
+// markTerminated transitions a thread to "terminated" and wakes any joiners.
+// wasActive is false if the thread had faulted, so it no longer counted as
+// active. Returns false if the thread had already terminated.
+func (vo *VMOrchestrator) markTerminated(thread *VMThread, reason string) (exit threadExit, wasActive, ok bool) {
+	thread.mutex.Lock()
+	defer thread.mutex.Unlock()
+
+	if thread.status == "terminated" {
+		return threadExit{}, false, false
+	}
+	wasActive = engine.Active(thread.status)
+	vo.setStatus(thread, "terminated")
+	thread.exitReason = reason
+	thread.terminatedAt = time.Now()
+	thread.closeDone()
+	return thread.exitState(), wasActive, true
+}
+
+// closeDone wakes the thread's joiners. A faulted thread's joiners were
+// already woken when it faulted. Caller must hold thread.mutex.
+func (thread *VMThread) closeDone() {
+	select {
+	case <-thread.done:
+	default:
+		close(thread.done)
+	}
+}
+
+// ageMs returns the real time since the thread was created, frozen once it
+// terminates. Caller must hold thread.mutex.
+func (thread *VMThread) ageMs(now time.Time) float64 {
+	if !thread.terminatedAt.IsZero() {
+		now = thread.terminatedAt
+	}
+	return float64(now.Sub(thread.createdAt)) / float64(time.Millisecond)
+}
+
+// exitState captures the thread's final state; caller must hold thread.mutex
+func (thread *VMThread) exitState() threadExit {
+	return threadExit{
+		id:           thread.id,
+		name:         thread.name,
+		pc:           thread.pc,
+		registers:    append([]uint32(nil), thread.registers...),
+		reason:       thread.exitReason,
+		instructions: thread.instructionsExecuted,
+		fault:        thread.faultMessage,
+	}
+}
+
+// toJSObject converts an exit record to a JavaScript object
+func (exit threadExit) toJSObject() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                   exit.id,
+		"name":                 exit.name,
+		"pc":                   addressToJS(exit.pc),
+		"registers":            uint32sToJS(exit.registers),
+		"reason":               exit.reason,
+		"limitExceeded":        exit.reason == "instruction_limit",
+		"instructionsExecuted": exit.instructions,
+		"faultMessage":         exit.fault,
+	}
+}
+
+// getThread looks up an active thread by ID
+func (vo *VMOrchestrator) getThread(threadID int) *VMThread {
+	vo.threadMutex.RLock()
+	defer vo.threadMutex.RUnlock()
+	return vo.threads[threadID]
 }
 
 // GetStats returns execution statistics
 func (vo *VMOrchestrator) GetStats(this js.Value, args []js.Value) interface{} {
-	vo.statsMutex.RLock()
-	defer vo.statsMutex.RUnlock()
+	return vo.cachedStats()
+}
+
+// statsObject builds the stats object returned by GetStats
+func (vo *VMOrchestrator) statsObject() map[string]interface{} {
+	workers, queueDepth, pendingTicks := vo.schedulerStats()
+	threadStats, active := vo.threadStats()
+	longestWaiter, longestWait := vo.longestWait(time.Now())
+
+	vo.statsMutex.Lock()
+	defer vo.statsMutex.Unlock()
+
+	now := time.Now()
+	vo.stats.UpdateExecutionTime(now)
 
 	statsObj := map[string]interface{}{
-		"instructionsExecuted": vo.stats.instructionsExecuted,
-		"memoryAllocated":      vo.stats.memoryAllocated,
-		"threadsCreated":       vo.stats.threadsCreated,
-		"threadsTerminated":    vo.stats.threadsTerminated,
-		"executionTime":        vo.stats.executionTime.Milliseconds(),
-		"activeThreads":        len(vo.threads),
+		"instructionsExecuted": vo.stats.InstructionsExecuted,
+		"memoryAllocated":      vo.stats.MemoryAllocated,
+		"threadsCreated":       vo.stats.ThreadsCreated,
+		"threadsTerminated":    vo.stats.ThreadsTerminated,
+		"executionTime":        vo.stats.ExecutionTime.Milliseconds(),
+		"activeThreads":        active,
+		"schedulingPolicy":     schedulingPolicy,
+		"workerPoolSize":       workers,
+		"runQueueDepth":        queueDepth,
+		"threadStats":          threadStats,
+
+		"instructionsPerSecond": vo.throughput.rate(now, vo.stats.InstructionsExecuted),
+		"peakMemoryAllocated":   vo.stats.PeakMemoryAllocated,
+		"allocationErrors":      vo.stats.AllocationErrors,
+		"pendingTicks":          pendingTicks,
+		"faults":                vo.stats.Faults,
+		"yields":                vo.stats.Yields,
+		"stackGrowths":          vo.stats.StackGrowths,
+		"cpuTimeMs":             float64(vo.stats.CPUTime) / float64(time.Millisecond),
+		"droppedEvents":         atomic.LoadUint64(&vo.events.dropped),
+		"failedEvents":          atomic.LoadUint64(&vo.events.failed),
+		"longestWaitMs":         float64(longestWait) / float64(time.Millisecond),
+		"longestWaitThreadID":   longestWaiter,
+		"parallelism":           parallelism(),
+		"cyclesExecuted":        vo.stats.CyclesExecuted,
+
+		"threadCreationRejections": vo.stats.RejectedThreads,
+		"instructionSequence":      atomic.LoadUint64(&vo.sequence),
+		"peakActiveThreads":        vo.stats.PeakActiveThreads,
 	}
 
-	return js.ValueOf(statsObj)
+	return statsObj
+}
+
+// threadStats returns per-thread stats for every thread in the map, ordered
+// by ID, and how many of them are active (not faulted). Counters are read
+// under each thread's mutex to avoid torn 64-bit reads.
+func (vo *VMOrchestrator) threadStats() (stats []interface{}, active int) {
+	vo.threadMutex.RLock()
+	threads := vo.threads.Sorted()
+	vo.threadMutex.RUnlock()
+
+	now := time.Now()
+	stats = make([]interface{}, len(threads))
+	for i, thread := range threads {
+		thread.mutex.RLock()
+		if engine.Active(thread.status) {
+			active++
+		}
+		stats[i] = map[string]interface{}{
+			"id":                   thread.id,
+			"name":                 thread.name,
+			"pc":                   addressToJS(thread.pc),
+			"status":               thread.status,
+			"instructionsExecuted": thread.instructionsExecuted,
+			"stackDepth":           len(thread.stack),
+			"faultMessage":         thread.faultMessage,
+			"faultAddress":         faultAddressToJS(thread.faultAddress),
+			"affinity":             atomic.LoadInt32(&thread.affinity),
+			"cpuTimeMs":            float64(thread.cpuTime) / float64(time.Millisecond),
+			"ageMs":                thread.ageMs(now),
+			"cyclesExecuted":       thread.cyclesExecuted,
+		}
+		thread.mutex.RUnlock()
+	}
+	return stats, active
 }
 
-// GetThreadCount returns the number of active threads
+// GetThreadCount returns the number of active threads, not counting faulted
+// ones
 func (vo *VMOrchestrator) GetThreadCount(this js.Value, args []js.Value) interface{} {
 	vo.threadMutex.RLock()
 	defer vo.threadMutex.RUnlock()
-	return js.ValueOf(len(vo.threads))
+	return js.ValueOf(vo.threads.Active())
+}
+
+// ResetPeakThreadCount restarts the peakActiveThreads high-water mark from
+// the number of threads active now
+func (vo *VMOrchestrator) ResetPeakThreadCount(this js.Value, args []js.Value) interface{} {
+	vo.threadMutex.RLock()
+	defer vo.threadMutex.RUnlock()
+
+	vo.statsMutex.Lock()
+	vo.stats.PeakActiveThreads = uint64(vo.threads.Active())
+	vo.statsMutex.Unlock()
+	return js.ValueOf(true)
+}
+
+// ListThreadIDs returns the IDs of all active threads in ascending order.
+// Passing true also lists zombies (terminated threads not yet reaped).
+func (vo *VMOrchestrator) ListThreadIDs(this js.Value, args []js.Value) interface{} {
+	withZombies := len(args) > 0 && args[0].Type() == js.TypeBoolean && args[0].Bool()
+
+	vo.threadMutex.RLock()
+	ids := make([]int, 0, len(vo.threads))
+	for id := range vo.threads {
+		ids = append(ids, id)
+	}
+	if withZombies {
+		for id := range vo.zombies {
+			ids = append(ids, id)
+		}
+	}
+	vo.threadMutex.RUnlock()
+
+	sort.Ints(ids)
+	result := make([]interface{}, len(ids))
+	for i, id := range ids {
+		result[i] = id
+	}
+	return js.ValueOf(result)
+}
+
+// GetThread returns one thread's state, or null for an unknown ID. All
+// fields are read in a single critical section so pc and registers always
+// belong to the same instruction boundary. Zombies (terminated threads not
+// yet reaped) are returned with zombie: true and their exit reason.
+func (vo *VMOrchestrator) GetThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.Null()
+	}
+
+	thread, zombie := vo.lookupThread(args[0].Int())
+	if thread == nil {
+		return js.Null()
+	}
+
+	thread.mutex.RLock()
+	defer thread.mutex.RUnlock()
+
+	return js.ValueOf(map[string]interface{}{
+		"id":                   thread.id,
+		"name":                 thread.name,
+		"pc":                   addressToJS(thread.pc),
+		"lastPC":               addressToJS(thread.lastPC),
+		"status":               thread.status,
+		"registers":            uint32sToJS(thread.registers),
+		"stackDepth":           len(thread.stack),
+		"priority":             thread.priority,
+		"effectivePriority":    thread.effectivePriority(),
+		"group":                thread.groupID,
+		"instructionsExecuted": thread.instructionsExecuted,
+		"cyclesExecuted":       thread.cyclesExecuted,
+		"cpuTimeMs":            float64(thread.cpuTime) / float64(time.Millisecond),
+		"ageMs":                thread.ageMs(time.Now()),
+		"zombie":               zombie,
+		"exitReason":           thread.exitReason,
+		"timedOut":             thread.timedOut,
+		"wakePending":          thread.wakePending,
+		"errno":                thread.errno,
+	})
 }
 
 // IsRunning returns whether the VM is currently running
@@ -238,10 +1438,206 @@ func (vo *VMOrchestrator) toJSObject() map[string]interface{} {
 		"initialize":     js.FuncOf(vo.Initialize),
 		"start":          js.FuncOf(vo.Start),
 		"stop":           js.FuncOf(vo.Stop),
+		"stopGraceful":   js.FuncOf(vo.StopGraceful),
+		"reset":          js.FuncOf(vo.Reset),
 		"createThread":   js.FuncOf(vo.CreateThread),
+		"createThreads":  js.FuncOf(vo.CreateThreads),
+		"getThread":      js.FuncOf(vo.GetThread),
+		"dumpThreads":    js.FuncOf(vo.DumpThreads),
 		"getStats":       js.FuncOf(vo.GetStats),
 		"getThreadCount": js.FuncOf(vo.GetThreadCount),
 		"isRunning":      js.FuncOf(vo.IsRunning),
+		"pause":          js.FuncOf(vo.Pause),
+		"resume":         js.FuncOf(vo.Resume),
+		"isPaused":       js.FuncOf(vo.IsPaused),
+
+		"setFreezeOnFault": js.FuncOf(vo.SetFreezeOnFault),
+		"onFreeze":         js.FuncOf(vo.OnFreeze),
+		"getGuestTicks":    js.FuncOf(vo.GetGuestTicks),
+		"setTickRate":      js.FuncOf(vo.SetTickRate),
+		"setStatsCacheMs":  js.FuncOf(vo.SetStatsCacheMs),
+		"listThreadIDs":    js.FuncOf(vo.ListThreadIDs),
+
+		"setAllowThreadsWhileStopped": js.FuncOf(vo.SetAllowThreadsWhileStopped),
+		"replayTrace":                 js.FuncOf(vo.ReplayTrace),
+		"killThread":                  js.FuncOf(vo.KillThread),
+		"resetPeakThreadCount":        js.FuncOf(vo.ResetPeakThreadCount),
+
+		"getFaultLog":     js.FuncOf(vo.GetFaultLog),
+		"clearFaultLog":   js.FuncOf(vo.ClearFaultLog),
+		"setFaultLogSize": js.FuncOf(vo.SetFaultLogSize),
+		"getErrno":        js.FuncOf(vo.GetErrno),
+		"setErrno":        js.FuncOf(vo.SetErrno),
+		"setCpuFeatures":  js.FuncOf(vo.SetCpuFeatures),
+		"getCpuFeatures":  js.FuncOf(vo.GetCpuFeatures),
+		"setMaxThreads":   js.FuncOf(vo.SetMaxThreads),
+		"saveContext":     js.FuncOf(vo.SaveContext),
+		"restoreContext":  js.FuncOf(vo.RestoreContext),
+
+		"onThreadLimitReached": js.FuncOf(vo.OnThreadLimitReached),
+
+		"enableScheduleTrace":  js.FuncOf(vo.EnableScheduleTrace),
+		"disableScheduleTrace": js.FuncOf(vo.DisableScheduleTrace),
+		"getScheduleTrace":     js.FuncOf(vo.GetScheduleTrace),
+
+		"registerSyscall":    js.FuncOf(vo.RegisterSyscall),
+		"dispatchSyscall":    js.FuncOf(vo.DispatchSyscall),
+		"onUnhandledSyscall": js.FuncOf(vo.OnUnhandledSyscall),
+
+		// Thread control
+		"suspendThread": js.FuncOf(vo.SuspendThread),
+		"resumeThread":  js.FuncOf(vo.ResumeThread),
+		"joinThread":    js.FuncOf(vo.JoinThread),
+		"startThread":   js.FuncOf(vo.StartThread),
+		"stepThread":    js.FuncOf(vo.StepThread),
+		"setThreadMode": js.FuncOf(vo.SetThreadMode),
+		"focusThread":   js.FuncOf(vo.FocusThread),
+		"unfocus":       js.FuncOf(vo.Unfocus),
+		"setMaxWorkers": js.FuncOf(vo.SetMaxWorkers),
+		"setTickMode":   js.FuncOf(vo.SetTickMode),
+		"tick":          js.FuncOf(vo.Tick),
+
+		"setThreadEmulator": js.FuncOf(vo.SetThreadEmulator),
+		"setBridgeTimeout":  js.FuncOf(vo.SetBridgeTimeout),
+		"setParallelism":    js.FuncOf(vo.SetParallelism),
+		"setLogLevel":       js.FuncOf(vo.SetLogLevel),
+		"onLog":             js.FuncOf(vo.OnLog),
+
+		"reapThread":     js.FuncOf(vo.ReapThread),
+		"setReapTimeout": js.FuncOf(vo.SetReapTimeout),
+
+		"setYieldStrategy": js.FuncOf(vo.SetYieldStrategy),
+
+		"getCyclesPerInstruction": js.FuncOf(vo.GetCyclesPerInstruction),
+
+		"readMemory":  js.FuncOf(vo.ReadMemory),
+		"writeMemory": js.FuncOf(vo.WriteMemory),
+
+		"getCallStack": js.FuncOf(vo.GetCallStack),
+		"onSymbolize":  js.FuncOf(vo.OnSymbolize),
+
+		"enableOpcodeProfiling":  js.FuncOf(vo.EnableOpcodeProfiling),
+		"disableOpcodeProfiling": js.FuncOf(vo.DisableOpcodeProfiling),
+		"getOpcodeProfile":       js.FuncOf(vo.GetOpcodeProfile),
+
+		"setThreadInstructionLimit": js.FuncOf(vo.SetThreadInstructionLimit),
+		"setThreadAffinity":         js.FuncOf(vo.SetThreadAffinity),
+		"onThreadTerminated":        js.FuncOf(vo.OnThreadTerminated),
+		"onFault":                   js.FuncOf(vo.OnFault),
+		"onIdle":                    js.FuncOf(vo.OnIdle),
+		"injectFault":               js.FuncOf(vo.InjectFault),
+		"yieldThread":               js.FuncOf(vo.YieldThread),
+		"setThreadName":             js.FuncOf(vo.SetThreadName),
+		"setDeterministic":          js.FuncOf(vo.SetDeterministic),
+		"onThreadStateChange":       js.FuncOf(vo.OnThreadStateChange),
+
+		// Thread groups
+		"createThreadGroup": js.FuncOf(vo.CreateThreadGroup),
+		"addThreadToGroup":  js.FuncOf(vo.AddThreadToGroup),
+		"suspendGroup":      js.FuncOf(vo.SuspendGroup),
+		"resumeGroup":       js.FuncOf(vo.ResumeGroup),
+		"terminateGroup":    js.FuncOf(vo.TerminateGroup),
+		"setGroupCpuQuota":  js.FuncOf(vo.SetGroupCpuQuota),
+
+		// Deadlock detection
+		"waitThread":     js.FuncOf(vo.WaitThread),
+		"wakeThread":     js.FuncOf(vo.WakeThread),
+		"detectDeadlock": js.FuncOf(vo.DetectDeadlock),
+		"onDeadlock":     js.FuncOf(vo.OnDeadlock),
+
+		// Guest synchronization
+		"createMutex": js.FuncOf(vo.CreateMutex),
+		"lockMutex":   js.FuncOf(vo.LockMutex),
+		"unlockMutex": js.FuncOf(vo.UnlockMutex),
+
+		"createCondVar": js.FuncOf(vo.CreateCondVar),
+		"condWait":      js.FuncOf(vo.CondWait),
+		"condSignal":    js.FuncOf(vo.CondSignal),
+		"condBroadcast": js.FuncOf(vo.CondBroadcast),
+
+		// Thread-local storage
+		"setTLS": js.FuncOf(vo.SetTLS),
+		"getTLS": js.FuncOf(vo.GetTLS),
+
+		// Tracing
+		"enableTrace":  js.FuncOf(vo.EnableTrace),
+		"disableTrace": js.FuncOf(vo.DisableTrace),
+		"getTrace":     js.FuncOf(vo.GetTrace),
+
+		// Performance monitoring
+		"setThroughputWindow": js.FuncOf(vo.SetThroughputWindow),
+		"setBatchSize":        js.FuncOf(vo.SetBatchSize),
+		"setSpeedLimit":       js.FuncOf(vo.SetSpeedLimit),
+		"onStats":             js.FuncOf(vo.OnStats),
+		"stopStatsHeartbeat":  js.FuncOf(vo.StopStatsHeartbeat),
+		"setEventQueueSize":   js.FuncOf(vo.SetEventQueueSize),
+
+		"onInstructionMilestone": js.FuncOf(vo.OnInstructionMilestone),
+
+		"enableLatencyHistogram":  js.FuncOf(vo.EnableLatencyHistogram),
+		"disableLatencyHistogram": js.FuncOf(vo.DisableLatencyHistogram),
+		"getLatencyHistogram":     js.FuncOf(vo.GetLatencyHistogram),
+		"resetHistogram":          js.FuncOf(vo.ResetHistogram),
+
+		// Memory
+		"recordAllocation": js.FuncOf(vo.RecordAllocation),
+		"recordFree":       js.FuncOf(vo.RecordFree),
+
+		"setAddressSpaceSize": js.FuncOf(vo.SetAddressSpaceSize),
+		"compareAndSwap":      js.FuncOf(vo.CompareAndSwap),
+
+		"mapRegion":       js.FuncOf(vo.MapRegion),
+		"unmapRegion":     js.FuncOf(vo.UnmapRegion),
+		"checkAccess":     js.FuncOf(vo.CheckAccess),
+		"validateAddress": js.FuncOf(vo.ValidateAddress),
+		"listRegions":     js.FuncOf(vo.ListRegions),
+
+		// Interrupts
+		"setInterruptHandler": js.FuncOf(vo.SetInterruptHandler),
+		"sendInterrupt":       js.FuncOf(vo.SendInterrupt),
+		"sendInterruptAll":    js.FuncOf(vo.SendInterruptAll),
+		"returnFromInterrupt": js.FuncOf(vo.ReturnFromInterrupt),
+
+		// Thread stacks
+		"setMaxStackDepth": js.FuncOf(vo.SetMaxStackDepth),
+		"setStackGrowth":   js.FuncOf(vo.SetStackGrowth),
+		"pushStack":        js.FuncOf(vo.PushStack),
+		"popStack":         js.FuncOf(vo.PopStack),
+		"onStackOverflow":  js.FuncOf(vo.OnStackOverflow),
+
+		// Debugging
+		"getRegisters":    js.FuncOf(vo.GetRegisters),
+		"setRegister":     js.FuncOf(vo.SetRegister),
+		"setBreakpoint":   js.FuncOf(vo.SetBreakpoint),
+		"clearBreakpoint": js.FuncOf(vo.ClearBreakpoint),
+		"listBreakpoints": js.FuncOf(vo.ListBreakpoints),
+		"onBreakpoint":    js.FuncOf(vo.OnBreakpoint),
+
+		"setRegisterWatch":   js.FuncOf(vo.SetRegisterWatch),
+		"clearRegisterWatch": js.FuncOf(vo.ClearRegisterWatch),
+		"onWatch":            js.FuncOf(vo.OnWatch),
+
+		"onDisassemble":         js.FuncOf(vo.OnDisassemble),
+		"getDisassembly":        js.FuncOf(vo.GetDisassembly),
+		"invalidateDisassembly": js.FuncOf(vo.InvalidateDisassembly),
+
+		// Checkpointing
+		"snapshot": js.FuncOf(vo.Snapshot),
+		"restore":  js.FuncOf(vo.Restore),
+
+		"diffSnapshots": js.FuncOf(vo.DiffSnapshots),
+
+		"setAutoSnapshot":  js.FuncOf(vo.SetAutoSnapshot),
+		"getAutoSnapshots": js.FuncOf(vo.GetAutoSnapshots),
+
+		"requestSafepoint": js.FuncOf(vo.RequestSafepoint),
+		"releaseSafepoint": js.FuncOf(vo.ReleaseSafepoint),
+
+		"exportState": js.FuncOf(vo.ExportState),
+		"importState": js.FuncOf(vo.ImportState),
+
+		"exportThread": js.FuncOf(vo.ExportThread),
+		"importThread": js.FuncOf(vo.ImportThread),
 	}
 }
 
@@ -255,4 +1651,3 @@ func main() {
 	// Keep the program running
 	select {}
 }
-
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+func TestOrchestratorsAreIsolated(t *testing.T) {
+	a, b := newTestOrchestrator(t), newTestOrchestrator(t)
+
+	// Both instances hand out the same thread IDs
+	idsA := []int{createThread(t, a, 0x1000), createThread(t, a, 0x2000)}
+	idsB := []int{createThread(t, b, 0x1000)}
+	if idsA[0] != 1 || idsA[1] != 2 || idsB[0] != 1 {
+		t.Fatalf("got IDs %v and %v, want [1 2] and [1]", idsA, idsB)
+	}
+
+	call(a.RecordAllocation, 4096)
+	requireOK(t, call(a.KillThread, 1))
+
+	call(a.Initialize, stepBridge(t))
+	requireOK(t, call(a.Start))
+	eventually(t, "instructions on the first VM", func() bool {
+		return stat(a, "instructionsExecuted") > 0
+	})
+
+	if got := stat(b, "instructionsExecuted"); got != 0 {
+		t.Errorf("second VM executed %v instructions", got)
+	}
+	if got := stat(b, "memoryAllocated"); got != 0 {
+		t.Errorf("second VM has %v bytes allocated", got)
+	}
+	if got := stat(b, "threadsCreated"); got != 1 {
+		t.Errorf("second VM created %v threads, want 1", got)
+	}
+	if got := stat(b, "threadsTerminated"); got != 0 {
+		t.Errorf("second VM terminated %v threads, want 0", got)
+	}
+	if threadStatus(b, 1) == "" {
+		t.Error("killing thread 1 on the first VM removed it from the second")
+	}
+}
+
+func TestExecutionTimeAccumulatesWhileRunning(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+
+	time.Sleep(20 * time.Millisecond)
+	first := stat(vo, "executionTime")
+	if first <= 0 {
+		t.Fatalf("executionTime = %v after 20ms of running, want > 0", first)
+	}
+	time.Sleep(20 * time.Millisecond)
+	second := stat(vo, "executionTime")
+	if second <= first {
+		t.Fatalf("executionTime went from %v to %v, want it to grow", first, second)
+	}
+
+	requireOK(t, call(vo.Stop))
+	stopped := stat(vo, "executionTime")
+	if stopped < second {
+		t.Fatalf("executionTime fell from %v to %v on Stop", second, stopped)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if frozen := stat(vo, "executionTime"); frozen != stopped {
+		t.Fatalf("executionTime moved from %v to %v while stopped", stopped, frozen)
+	}
+}
+
+func TestResetClearsSession(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+	createThread(t, vo, 0x2000)
+	call(vo.RecordAllocation, 512)
+	call(vo.SetBreakpoint, 0x8000)
+	eventually(t, "instructions to execute", func() bool { return stat(vo, "instructionsExecuted") > 0 })
+
+	if call(vo.Reset).Bool() {
+		t.Fatal("reset succeeded while running")
+	}
+	requireOK(t, call(vo.Stop))
+	if !call(vo.Reset).Bool() {
+		t.Fatal("reset failed while stopped")
+	}
+
+	// Configuration and sentinels rather than session counters
+	kept := map[string]bool{"workerPoolSize": true, "parallelism": true, "longestWaitThreadID": true}
+	stats := call(vo.GetStats)
+	keys := js.Global().Get("Object").Call("keys", stats)
+	for i := 0; i < keys.Length(); i++ {
+		name := keys.Index(i).String()
+		if value := stats.Get(name); !kept[name] && value.Type() == js.TypeNumber && value.Float() != 0 {
+			t.Errorf("%s = %v after reset, want 0", name, value.Float())
+		}
+	}
+	if got := call(vo.ListBreakpoints).Length(); got != 0 {
+		t.Errorf("%d breakpoints survived reset", got)
+	}
+	if id := createThread(t, vo, 0x1000); id != 1 {
+		t.Errorf("first thread after reset has ID %d, want 1", id)
+	}
+}
+
+func TestMixedInstructionLengths(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	// A Thumb-style stream of 2- and 4-byte instructions ending in a
+	// legacy boolean result, which advances by the default 4 bytes
+	lengths := map[float64]int{0x1000: 2, 0x1002: 4, 0x1006: 2, 0x1008: 2, 0x100a: 4}
+	call(vo.Initialize, newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func(args []js.Value) interface{} {
+			if length, ok := lengths[args[0].Float()]; ok {
+				return map[string]interface{}{"ok": true, "length": length}
+			}
+			return true
+		},
+	}))
+
+	id := createThread(t, vo, 0x1000, 1, "paused")
+	for _, want := range []uint64{0x1002, 0x1006, 0x1008, 0x100a, 0x100e, 0x1012} {
+		requireOK(t, call(vo.StepThread, id))
+		if got := threadPC(t, vo, id); got != want {
+			t.Fatalf("pc = %#x, want %#x", got, want)
+		}
+	}
+}
+
+func TestThreadNameSurvivesSuspendAndResume(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	names := make(chan string, 1)
+	call(vo.OnThreadTerminated, newCallback(t, func(args []js.Value) {
+		names <- args[0].Get("name").String()
+	}))
+	requireOK(t, call(vo.Start))
+
+	id := createThread(t, vo, 0x2000, 1, "running", "gc")
+	requireOK(t, call(vo.SuspendThread, id))
+	requireOK(t, call(vo.ResumeThread, id))
+
+	if got := statsThread(vo, id).Get("name").String(); got != "gc" {
+		t.Fatalf("stats name = %q after suspend/resume, want gc", got)
+	}
+	requireOK(t, call(vo.SetThreadName, id, "renderer"))
+	if got := statsThread(vo, id).Get("name").String(); got != "renderer" {
+		t.Fatalf("stats name = %q after rename, want renderer", got)
+	}
+
+	requireOK(t, call(vo.KillThread, id))
+	var name string
+	eventually(t, "the termination callback", func() bool {
+		select {
+		case name = <-names:
+			return true
+		default:
+			return false
+		}
+	})
+	if name != "renderer" {
+		t.Errorf("termination callback name = %q, want renderer", name)
+	}
+}
+
+func TestThreadAge(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+	id := createThread(t, vo, 0x40000000)
+	age := func() float64 { return call(vo.GetThread, id).Get("ageMs").Float() }
+
+	first := age()
+	time.Sleep(20 * time.Millisecond)
+	second := age()
+	if second-first < 15 {
+		t.Errorf("running thread aged %.1fms in 20ms", second-first)
+	}
+	if got := statsThread(vo, id).Get("ageMs").Float(); got < second {
+		t.Errorf("GetStats age %.1fms is behind GetThread's %.1fms", got, second)
+	}
+
+	// A snapshot keeps the creation time, so a restored thread is not younger
+	snapshot := call(vo.Snapshot)
+	time.Sleep(10 * time.Millisecond)
+	call(vo.Restore, snapshot)
+	// The host clock is coarse, so allow for the sleep measuring short
+	if restored := age(); restored < second+5 {
+		t.Errorf("restored thread is %.1fms old, want at least %.1fms", restored, second+5)
+	}
+
+	requireOK(t, call(vo.KillThread, id))
+	frozen := age()
+	time.Sleep(20 * time.Millisecond)
+	if got := age(); got != frozen {
+		t.Errorf("terminated thread aged from %.1fms to %.1fms", frozen, got)
+	}
+}
+
+func TestListThreadIDs(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	if ids := jsToInts(call(vo.ListThreadIDs)); len(ids) != 0 {
+		t.Fatalf("new VM lists threads %v", ids)
+	}
+	for i := 0; i < 4; i++ {
+		createThread(t, vo, uint64(0x40000000+0x1000*i), 1, "paused")
+	}
+	requireOK(t, call(vo.KillThread, 2))
+
+	if ids := jsToInts(call(vo.ListThreadIDs)); !reflect.DeepEqual(ids, []int{1, 3, 4}) {
+		t.Errorf("active threads = %v, want [1 3 4]", ids)
+	}
+	if ids := jsToInts(call(vo.ListThreadIDs, true)); !reflect.DeepEqual(ids, []int{1, 2, 3, 4}) {
+		t.Errorf("threads with zombies = %v, want [1 2 3 4]", ids)
+	}
+	requireOK(t, call(vo.ReapThread, 2))
+	if ids := jsToInts(call(vo.ListThreadIDs, true)); !reflect.DeepEqual(ids, []int{1, 3, 4}) {
+		t.Errorf("threads with zombies after reaping = %v, want [1 3 4]", ids)
+	}
+}
+
+func TestThreadsCreatedWhileStoppedWaitForStart(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	id := createThread(t, vo, 0x40000000)
+
+	time.Sleep(20 * time.Millisecond)
+	if got := threadPC(t, vo, id); got != 0x40000000 {
+		t.Fatalf("thread ran to %#x before Start", got)
+	}
+	requireOK(t, call(vo.Start))
+	eventually(t, "the queued thread to run", func() bool { return threadPC(t, vo, id) > 0x40000000 })
+}
+
+func TestRejectThreadsWhileStopped(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	if !call(vo.SetAllowThreadsWhileStopped, false).Bool() {
+		t.Fatal("setAllowThreadsWhileStopped failed")
+	}
+
+	requireError(t, call(vo.CreateThread, 0x40000000), errNotRunning)
+	requireError(t, call(vo.CreateThreads, []interface{}{map[string]interface{}{"startPC": 0x40000000}}), errNotRunning)
+	if got := stat(vo, "threadsCreated"); got != 0 {
+		t.Errorf("rejected creations counted %v threads", got)
+	}
+
+	requireOK(t, call(vo.Start))
+	id := createThread(t, vo, 0x40000000)
+	eventually(t, "the thread to run", func() bool { return threadPC(t, vo, id) > 0x40000000 })
+
+	call(vo.Stop)
+	requireError(t, call(vo.CreateThread, 0x40000000), errNotRunning)
+}
+
+func TestPeakActiveThreads(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	ids := pausedThreads(t, vo, 5)
+	for _, id := range ids[:3] {
+		requireOK(t, call(vo.KillThread, id))
+	}
+	pausedThreads(t, vo, 2)
+
+	// Seven threads were created, but at most five were ever active at once
+	if got := stat(vo, "peakActiveThreads"); got != 5 {
+		t.Fatalf("peakActiveThreads = %v, want 5", got)
+	}
+
+	call(vo.ResetPeakThreadCount)
+	if got := stat(vo, "peakActiveThreads"); got != 4 {
+		t.Fatalf("peakActiveThreads = %v after reset, want the 4 active now", got)
+	}
+
+	// Concurrent creations must not lose an update to the peak
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				call(vo.CreateThread, 0x1000, 1, "paused")
+			}
+		}()
+	}
+	wg.Wait()
+	if got := stat(vo, "peakActiveThreads"); got != 44 {
+		t.Errorf("peakActiveThreads = %v after concurrent creation, want 44", got)
+	}
+}
@@ -0,0 +1,118 @@
+// Timed Waits
+// Ends WaitThread waits explicitly or after a timeout
+//
+// A thread put into "waiting" by WaitThread consumes no quanta: it is off
+// the run queue until something makes it runnable again. WakeThread does
+// that immediately; a wait entered with a timeout also ends by itself once
+// the timeout elapses, and the thread then reports timedOut: true until its
+// next wait. Waits on guest mutexes and condition variables are not
+// wakeable this way, since waking them would skip the lock hand-off.
+//
+// A WakeThread that arrives while the thread is still running or paused,
+// before it enters the wait the wake targets, is not lost: it leaves a
+// wake pending, checked under the same thread mutex WaitThread parks with,
+// and the next WaitThread consumes it and returns without parking. Pending
+// wakes do not accumulate.
+//
+// Snapshots and exported state record whether a wait is wakeable, so a
+// restored WaitThread wait can still be ended by WakeThread, but not its
+// timer: a restored wait that had a timeout waits until woken.
+
+package main
+
+import (
+	"syscall/js"
+	"time"
+)
+
+// WakeThread ends a WaitThread wait, making the thread runnable. If the
+// thread is running or paused, the wake is kept for its next WaitThread.
+// Any other thread, including one blocked on a guest mutex or condition
+// variable, cannot be woken.
+func (vo *VMOrchestrator) WakeThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(false, errInvalidArgument, "wakeThread requires a thread ID")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	if !vo.wakeThread(thread, nil) {
+		return vo.fail(false, errInvalidState, "thread %d is not in a wakeable wait and cannot enter one", threadID)
+	}
+	return vo.succeed(true, nil)
+}
+
+// armWaitTimeout makes a thread's wait end after timeout, replacing any
+// earlier timeout. Caller must hold thread.mutex.
+func (vo *VMOrchestrator) armWaitTimeout(thread *VMThread, timeout time.Duration) {
+	if thread.waitTimer != nil {
+		thread.waitTimer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(timeout, func() { vo.wakeThread(thread, timer) })
+	thread.waitTimer = timer
+}
+
+// wakeThread ends a thread's wakeable wait and queues it. timer is the timer that expired, or nil for an explicit wake; an
+// expired timer that no longer belongs to the current wait is ignored, and
+// an explicit wake for a thread not in a wakeable wait is left pending.
+// Returns false if the wake was neither delivered nor left pending.
+func (vo *VMOrchestrator) wakeThread(thread *VMThread, timer *time.Timer) bool {
+	thread.mutex.Lock()
+	if thread.status != "waiting" || !thread.wakeable || (timer != nil && thread.waitTimer != timer) {
+		// Only a thread WaitThread can still park will consume the wake
+		pending := timer == nil && (thread.status == "running" || thread.status == "paused")
+		if pending {
+			thread.wakePending = true
+		}
+		thread.mutex.Unlock()
+		return pending
+	}
+	thread.timedOut = timer != nil
+	thread.waitingOn = nil
+	vo.resumeFromWait(thread)
+	thread.mutex.Unlock()
+
+	vo.enqueueThread(thread)
+	return true
+}
+
+// beginWait moves a running, paused or yielding thread to "waiting" and
+// remembers which status to return it to. A thread already waiting (a
+// notified condition variable waiter queueing on its mutex) keeps the
+// status it had before its first wait. Caller must hold thread.mutex.
+func (vo *VMOrchestrator) beginWait(thread *VMThread) {
+	switch {
+	case thread.status == "paused":
+		thread.resumeStatus = "paused"
+	case thread.status == "running" || thread.yielding:
+		thread.resumeStatus = "running"
+	}
+	vo.setStatus(thread, "waiting")
+}
+
+// resumeFromWait returns a "waiting" thread to the status it had before
+// the wait. Caller must hold thread.mutex.
+func (vo *VMOrchestrator) resumeFromWait(thread *VMThread) {
+	status := thread.resumeStatus
+	if status == "" {
+		status = "running"
+	}
+	thread.resumeStatus = ""
+	vo.setStatus(thread, status)
+}
+
+// endWait cancels the wake-up of a thread leaving "waiting". Caller must
+// hold thread.mutex.
+func (thread *VMThread) endWait() {
+	if thread.waitTimer != nil {
+		thread.waitTimer.Stop()
+		thread.waitTimer = nil
+	}
+	thread.wakeable = false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitTimesOut(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+	id := createThread(t, vo, 0x2000)
+
+	requireOK(t, call(vo.WaitThread, id, 1, 50))
+	if got := threadStatus(vo, id); got != "waiting" {
+		t.Fatalf("thread is %q after waitThread, want waiting", got)
+	}
+	pc := threadPC(t, vo, id)
+	time.Sleep(20 * time.Millisecond)
+	if got := threadPC(t, vo, id); got != pc {
+		t.Fatalf("waiting thread advanced from %#x to %#x", pc, got)
+	}
+
+	eventually(t, "the timeout to wake the thread", func() bool { return threadStatus(vo, id) == "running" })
+	if !call(vo.GetThread, id).Get("timedOut").Bool() {
+		t.Error("a wait ended by its timeout does not report timedOut")
+	}
+	eventually(t, "the woken thread to run", func() bool { return threadPC(t, vo, id) != pc })
+}
+
+func TestWakeThreadResumesImmediately(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	call(vo.Initialize, stepBridge(t))
+	requireOK(t, call(vo.Start))
+	id := createThread(t, vo, 0x2000)
+
+	// The timeout is far enough away that only the explicit wake can end it
+	requireOK(t, call(vo.WaitThread, id, 1, 60000))
+	pc := threadPC(t, vo, id)
+	requireOK(t, call(vo.WakeThread, id))
+	if got := threadStatus(vo, id); got != "running" {
+		t.Fatalf("thread is %q right after wakeThread, want running", got)
+	}
+	if call(vo.GetThread, id).Get("timedOut").Bool() {
+		t.Error("an explicitly woken wait reports timedOut")
+	}
+	eventually(t, "the woken thread to run", func() bool { return threadPC(t, vo, id) != pc })
+
+	// Nothing is left to wake, and an unknown thread cannot be woken
+	requireOK(t, call(vo.SuspendThread, id))
+	requireError(t, call(vo.WakeThread, id), errInvalidState)
+	requireError(t, call(vo.WakeThread, 99), errUnknownThread)
+}
+
+func TestWaitKeepsPausedThreadPaused(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	id := pausedThreads(t, vo, 2)[0]
+
+	requireOK(t, call(vo.WaitThread, id, 2))
+	requireOK(t, call(vo.WakeThread, id))
+	if got := threadStatus(vo, id); got != "paused" {
+		t.Fatalf("thread that waited while paused is %q after wakeThread, want paused", got)
+	}
+
+	requireOK(t, call(vo.WaitThread, id, 2, 10))
+	eventually(t, "the timeout to end the wait", func() bool { return threadStatus(vo, id) != "waiting" })
+	if got := threadStatus(vo, id); got != "paused" {
+		t.Fatalf("thread that waited while paused is %q after timing out, want paused", got)
+	}
+
+	// The mode survives a snapshot taken during the wait
+	requireOK(t, call(vo.WaitThread, id, 2))
+	snapshot := call(vo.Snapshot)
+	requireOK(t, call(vo.WakeThread, id))
+	if !call(vo.Restore, snapshot).Bool() {
+		t.Fatal("restore failed")
+	}
+	requireOK(t, call(vo.WakeThread, id))
+	if got := threadStatus(vo, id); got != "paused" {
+		t.Errorf("restored waiter is %q after wakeThread, want paused", got)
+	}
+}
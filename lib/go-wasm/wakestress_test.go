@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestParkWakeStress(t *testing.T) {
+	const threads, rounds = 8, 300
+	vo := newTestOrchestrator(t)
+	ids := pausedThreads(t, vo, threads)
+
+	// Each round races one WaitThread against one WakeThread on the same
+	// thread. Whichever lands first, the wake must end the wait: either it
+	// is delivered to the parked thread, or it is left pending and the wait
+	// consumes it without parking.
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for round := 0; round < rounds; round++ {
+				first := func() { call(vo.WaitThread, id, 1) }
+				second := func() { call(vo.WakeThread, id) }
+				if round%2 == 1 {
+					first, second = second, first
+				}
+				var pair sync.WaitGroup
+				pair.Add(2)
+				for _, side := range []func(){first, second} {
+					go func(side func()) {
+						defer pair.Done()
+						side()
+					}(side)
+				}
+				pair.Wait()
+
+				thread := call(vo.GetThread, id)
+				if status := thread.Get("status").String(); status == "waiting" {
+					t.Errorf("thread %d hung in round %d: the wake was lost", id, round)
+					return
+				}
+				if thread.Get("wakePending").Bool() {
+					t.Errorf("thread %d kept a consumed wake in round %d", id, round)
+					return
+				}
+			}
+		}(id)
+	}
+	wg.Wait()
+}
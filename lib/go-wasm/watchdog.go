@@ -0,0 +1,116 @@
+// Bridge Watchdog
+// Detects emulator bridge calls that run far longer than an instruction
+// should
+//
+// With SetBridgeTimeout, every executeInstruction/executeInstructions call
+// records its start time on the thread. A watchdog goroutine started with
+// each run compares those timestamps against the timeout and faults threads
+// whose call has overrun with the message "bridge_timeout", firing OnFault.
+// A call that returns after the timeout faults its thread the same way and
+// its result is discarded.
+//
+// A synchronous js.Value.Call cannot be interrupted. The worker stuck in it
+// stays blocked (and leaks if the bridge never returns); the watchdog only
+// makes the hang visible. Under the single-threaded js/wasm runtime the
+// watchdog itself cannot run until the call yields back to Go, so a truly
+// hung bridge freezes the whole module and a slow one is reported when it
+// returns.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"time"
+)
+
+// watchdogIdlePeriod is how often the watchdog rechecks the timeout while
+// it is disabled
+const watchdogIdlePeriod = 100 * time.Millisecond
+
+// bridgeTimeoutReason is the fault message of a thread whose bridge call
+// overran the timeout
+const bridgeTimeoutReason = "bridge_timeout"
+
+// SetBridgeTimeout sets how long a single bridge call may run before its
+// thread is faulted. 0 disables the watchdog.
+func (vo *VMOrchestrator) SetBridgeTimeout(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber || args[0].Float() < 0 {
+		return js.ValueOf(false)
+	}
+
+	timeout := time.Duration(args[0].Float() * float64(time.Millisecond))
+	atomic.StoreInt64(&vo.bridgeTimeout, int64(timeout))
+	return js.ValueOf(true)
+}
+
+// callBridge calls an emulator method on behalf of a thread, recording the
+// call for the watchdog. Returns false if the call overran the timeout, in
+// which case the thread has been faulted and the result must be ignored.
+func (vo *VMOrchestrator) callBridge(thread *VMThread, emulator js.Value, method string, args ...interface{}) (js.Value, bool) {
+	timeout := time.Duration(atomic.LoadInt64(&vo.bridgeTimeout))
+	if timeout <= 0 {
+		return emulator.Call(method, args...), true
+	}
+
+	start := time.Now()
+	started := start.UnixNano()
+	atomic.StoreInt64(&thread.bridgeCallStart, started)
+	// A panicking call must not be reported as a timeout later
+	defer atomic.CompareAndSwapInt64(&thread.bridgeCallStart, started, 0)
+
+	result := emulator.Call(method, args...)
+	if !atomic.CompareAndSwapInt64(&thread.bridgeCallStart, started, 0) {
+		return result, false // the watchdog got there first
+	}
+	if time.Since(start) > timeout {
+		vo.faultThread(thread, bridgeTimeoutReason)
+		return result, false
+	}
+	return result, true
+}
+
+// watchdog checks bridge calls in progress until the run ends
+func (vo *VMOrchestrator) watchdog(done <-chan struct{}) {
+	for {
+		period := watchdogIdlePeriod
+		if timeout := time.Duration(atomic.LoadInt64(&vo.bridgeTimeout)); timeout > 0 {
+			period = max(timeout/4, time.Millisecond)
+		}
+
+		timer := time.NewTimer(period)
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		vo.checkBridgeCalls()
+	}
+}
+
+// checkBridgeCalls faults every thread whose bridge call has overrun the
+// timeout. Claiming the call's timestamp first ensures a thread is faulted
+// once, by either the watchdog or callBridge.
+func (vo *VMOrchestrator) checkBridgeCalls() {
+	timeout := atomic.LoadInt64(&vo.bridgeTimeout)
+	if timeout <= 0 {
+		return
+	}
+
+	vo.threadMutex.RLock()
+	threads := make([]*VMThread, 0, len(vo.threads))
+	for _, thread := range vo.threads {
+		threads = append(threads, thread)
+	}
+	vo.threadMutex.RUnlock()
+
+	now := time.Now().UnixNano()
+	for _, thread := range threads {
+		started := atomic.LoadInt64(&thread.bridgeCallStart)
+		if started != 0 && now-started > timeout &&
+			atomic.CompareAndSwapInt64(&thread.bridgeCallStart, started, 0) {
+			vo.faultThread(thread, bridgeTimeoutReason)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// faultMessages registers a fault callback that records each thread's message
+func faultMessages(t *testing.T, vo *VMOrchestrator) chan [2]interface{} {
+	t.Helper()
+	faults := make(chan [2]interface{}, 16)
+	call(vo.OnFault, newCallback(t, func(args []js.Value) {
+		faults <- [2]interface{}{args[0].Int(), args[1].String()}
+	}))
+	return faults
+}
+
+// requireFault waits for the fault callback to report threadID with message
+func requireFault(t *testing.T, faults chan [2]interface{}, threadID int, message string) {
+	t.Helper()
+	select {
+	case fault := <-faults:
+		if fault != [2]interface{}{threadID, message} {
+			t.Fatalf("fault callback got thread %v %q, want thread %d %q", fault[0], fault[1], threadID, message)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the fault callback")
+	}
+}
+
+func TestWatchdogFaultsSlowJSBridge(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	bridge := js.Global().Get("Object").New()
+	// A busy loop never returns to Go, so the overrun is caught on return
+	bridge.Set("executeInstruction", jsFunction("pc", `
+		if (pc === 0x40000000) { const end = Date.now() + 30; while (Date.now() < end) {} }
+		return true;`))
+	call(vo.Initialize, bridge)
+	call(vo.SetYieldStrategy, "gosched")
+	faults := faultMessages(t, vo)
+	if !call(vo.SetBridgeTimeout, 10).Bool() {
+		t.Fatal("setBridgeTimeout(10) failed")
+	}
+
+	requireOK(t, call(vo.Start))
+	slow := createThread(t, vo, 0x40000000)
+	requireFault(t, faults, slow, bridgeTimeoutReason)
+	if got := threadPC(t, vo, slow); got != 0x40000000 {
+		t.Errorf("timed-out instruction advanced the PC to %#x", got)
+	}
+	if got := threadStatus(vo, 1); got != "running" {
+		t.Errorf("fast main thread is %q, want running", got)
+	}
+}
+
+func TestWatchdogFaultsHungCall(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	release := make(chan struct{})
+	call(vo.Initialize, newBridge(t, map[string]func([]js.Value) interface{}{
+		"executeInstruction": func(args []js.Value) interface{} {
+			if args[0].Float() == 0x40000000 {
+				<-release // blocks this worker, leaving the watchdog free to run
+			}
+			return true
+		},
+	}))
+	call(vo.SetYieldStrategy, "gosched")
+	faults := faultMessages(t, vo)
+	call(vo.SetBridgeTimeout, 10)
+
+	requireOK(t, call(vo.Start))
+	// Calls into Go bridges return in LIFO order, so any other thread's call
+	// would be stuck behind the hung one and time out too
+	requireOK(t, call(vo.KillThread, 1))
+	hung := createThread(t, vo, 0x40000000)
+	requireFault(t, faults, hung, bridgeTimeoutReason)
+	if got := threadStatus(vo, hung); got != "faulted" {
+		t.Errorf("hung thread is %q while its call is in progress, want faulted", got)
+	}
+
+	// The late result is discarded
+	close(release)
+	time.Sleep(20 * time.Millisecond)
+	if got := threadPC(t, vo, hung); got != 0x40000000 {
+		t.Errorf("late result advanced the PC to %#x", got)
+	}
+	select {
+	case fault := <-faults:
+		t.Errorf("thread faulted again: %v", fault)
+	default:
+	}
+}
+
+func TestWatchdogDisabled(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	bridge := js.Global().Get("Object").New()
+	bridge.Set("executeInstruction", jsFunction("pc", `
+		if (pc === 0x40000000) { const end = Date.now() + 5; while (Date.now() < end) {} }
+		return true;`))
+	call(vo.Initialize, bridge)
+	call(vo.SetYieldStrategy, "gosched")
+	call(vo.SetBridgeTimeout, 1)
+	if call(vo.SetBridgeTimeout, -1).Bool() {
+		t.Error("setBridgeTimeout accepted a negative timeout")
+	}
+	call(vo.SetBridgeTimeout, 0)
+
+	requireOK(t, call(vo.Start))
+	slow := createThread(t, vo, 0x40000000)
+	eventually(t, "the slow instruction to complete", func() bool { return threadPC(t, vo, slow) > 0x40000000 })
+	if got := threadStatus(vo, slow); got == "faulted" {
+		t.Error("slow thread faulted with the watchdog disabled")
+	}
+}
@@ -0,0 +1,126 @@
+// Register Watchpoints
+// Pauses a thread when an instruction changes a watched register
+//
+// Watched register values are snapshotted before each instruction and
+// compared afterwards. A change pauses the thread after the instruction
+// completes and fires the OnWatch callback once per changed register.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// registerChange describes a watched register whose value changed
+type registerChange struct {
+	index    int
+	oldValue uint32
+	newValue uint32
+}
+
+// SetRegisterWatch watches a register on a thread
+// Arguments: threadID, regIndex
+func (vo *VMOrchestrator) SetRegisterWatch(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(false, errInvalidArgument, "setRegisterWatch requires a thread ID and register index")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	index := args[1].Int()
+	if index < 0 || index >= len(thread.registers) {
+		return vo.fail(false, errOutOfRange, "register %d is outside 0-%d", index, len(thread.registers)-1)
+	}
+
+	thread.mutex.Lock()
+	if thread.watches == nil {
+		thread.watches = make(map[int]struct{})
+	}
+	thread.watches[index] = struct{}{}
+	thread.mutex.Unlock()
+
+	return vo.succeed(true, nil)
+}
+
+// ClearRegisterWatch stops watching a register on a thread
+// Returns false if the register was not watched
+func (vo *VMOrchestrator) ClearRegisterWatch(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return vo.fail(false, errInvalidArgument, "clearRegisterWatch requires a thread ID and register index")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	index := args[1].Int()
+
+	thread.mutex.Lock()
+	defer thread.mutex.Unlock()
+
+	if _, ok := thread.watches[index]; !ok {
+		return vo.fail(false, errInvalidArgument, "register %d is not watched on thread %d", index, threadID)
+	}
+	delete(thread.watches, index)
+	return vo.succeed(true, nil)
+}
+
+// OnWatch registers a callback invoked as
+// callback(threadID, regIndex, oldValue, newValue) when a watched register
+// changes. Passing null clears it.
+func (vo *VMOrchestrator) OnWatch(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !isCallbackArg(args[0]) {
+		return js.ValueOf(false)
+	}
+
+	vo.callbackMutex.Lock()
+	vo.watchCallback = args[0]
+	vo.callbackMutex.Unlock()
+
+	return js.ValueOf(true)
+}
+
+// watchedValues snapshots the current value of every watched register.
+// Returns nil when nothing is watched. Caller must hold thread.mutex.
+func (thread *VMThread) watchedValues() map[int]uint32 {
+	if len(thread.watches) == 0 {
+		return nil
+	}
+
+	values := make(map[int]uint32, len(thread.watches))
+	for index := range thread.watches {
+		values[index] = thread.registers[index]
+	}
+	return values
+}
+
+// changedRegisters compares a watchedValues snapshot against the current
+// register file. Caller must hold thread.mutex.
+func (thread *VMThread) changedRegisters(before map[int]uint32) []registerChange {
+	var changes []registerChange
+	for index, oldValue := range before {
+		if newValue := thread.registers[index]; newValue != oldValue {
+			changes = append(changes, registerChange{index, oldValue, newValue})
+		}
+	}
+	return changes
+}
+
+// fireWatch queues a call to the registered watch callback
+func (vo *VMOrchestrator) fireWatch(threadID int, change registerChange) {
+	vo.postEvent(eventNormal, "", func() {
+		vo.callbackMutex.RLock()
+		callback := vo.watchCallback
+		vo.callbackMutex.RUnlock()
+
+		if callback.Type() == js.TypeFunction {
+			callback.Invoke(threadID, change.index, change.oldValue, change.newValue)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+// Cooperative Yield
+// Lets a thread give up the rest of its quantum
+//
+// A yield is signalled by the emulator returning { ok: true, yield: true }
+// from executeInstruction (e.g. for a YIELD or PAUSE spin hint), or by
+// calling YieldThread from JS. The thread is marked "waiting" until its
+// quantum ends; the scheduler then returns it to "running" at the tail of
+// the run queue, so every other queued thread runs before it does again.
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// YieldThread makes a running thread give up the rest of its quantum
+func (vo *VMOrchestrator) YieldThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(false, errInvalidArgument, "yieldThread requires a thread ID")
+	}
+
+	threadID := args[0].Int()
+	thread := vo.getThread(threadID)
+	if thread == nil {
+		return vo.unknownThread(false, threadID)
+	}
+
+	if !vo.yieldThread(thread) {
+		return vo.fail(false, errInvalidState, "thread %d is not running", threadID)
+	}
+	return vo.succeed(true, nil)
+}
+
+// yieldThread marks a running thread as yielding. executeThread ends its
+// quantum at the next instruction boundary and requeueThread makes it
+// runnable again. Returns false if the thread was not running.
+func (vo *VMOrchestrator) yieldThread(thread *VMThread) bool {
+	thread.mutex.Lock()
+	if thread.status != "running" {
+		thread.mutex.Unlock()
+		return false
+	}
+	vo.setStatus(thread, "waiting")
+	thread.yielding = true
+	thread.mutex.Unlock()
+
+	vo.statsMutex.Lock()
+	vo.stats.Yields++
+	vo.statsMutex.Unlock()
+
+	return true
+}
+
+// endYield returns a yielding thread to "running". Caller must hold
+// thread.mutex.
+func (vo *VMOrchestrator) endYield(thread *VMThread) {
+	if !thread.yielding {
+		return
+	}
+	thread.yielding = false
+	if thread.status == "waiting" {
+		vo.setStatus(thread, "running")
+	}
+}
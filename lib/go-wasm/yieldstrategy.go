@@ -0,0 +1,107 @@
+// Yield Strategy
+// How a worker gives way to other goroutines between instructions
+//
+// Guest yields (yield.go) end a thread's quantum. This is the host side:
+// after each step executeThread lets other goroutines (workers, the event
+// dispatcher, timers) run according to the strategy set by
+// SetYieldStrategy:
+//
+//	"sleep0"      time.Sleep(0), the default. Go returns from a zero sleep
+//	              immediately, so this costs a call and yields nothing.
+//	"gosched"     runtime.Gosched, which reschedules the goroutine so other
+//	              runnable goroutines get a turn.
+//	"none"        no yield; highest throughput, other goroutines only run
+//	              when a worker blocks.
+//	"interval(n)" sleep n microseconds after every n instructions. Sleeping
+//	              goes through a JS timer, which browsers clamp (usually to
+//	              1ms or more), but it also lets the JS event loop run.
+//
+// Cheaper strategies trade responsiveness of callbacks and other workers
+// for instruction throughput.
+
+package main
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall/js"
+	"time"
+)
+
+// Yield strategy kinds
+const (
+	yieldSleep0 = iota
+	yieldGosched
+	yieldNone
+	yieldInterval
+)
+
+// yieldStrategy is the parsed SetYieldStrategy argument
+type yieldStrategy struct {
+	kind  int
+	every int // instructions between sleeps for yieldInterval, also the sleep in µs
+}
+
+// SetYieldStrategy sets how workers yield between instructions: "sleep0",
+// "gosched", "none" or "interval(n)" with n >= 1
+func (vo *VMOrchestrator) SetYieldStrategy(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return js.ValueOf(false)
+	}
+
+	strategy, ok := parseYieldStrategy(args[0].String())
+	if !ok {
+		return js.ValueOf(false)
+	}
+	vo.yield.Store(strategy)
+	return js.ValueOf(true)
+}
+
+// parseYieldStrategy parses a SetYieldStrategy argument
+func parseYieldStrategy(name string) (*yieldStrategy, bool) {
+	switch name {
+	case "sleep0":
+		return &yieldStrategy{kind: yieldSleep0}, true
+	case "gosched":
+		return &yieldStrategy{kind: yieldGosched}, true
+	case "none":
+		return &yieldStrategy{kind: yieldNone}, true
+	}
+
+	arg, ok := strings.CutPrefix(name, "interval(")
+	if !ok {
+		return nil, false
+	}
+	arg, ok = strings.CutSuffix(arg, ")")
+	if !ok {
+		return nil, false
+	}
+	every, err := strconv.Atoi(arg)
+	if err != nil || every < 1 {
+		return nil, false
+	}
+	return &yieldStrategy{kind: yieldInterval, every: every}, true
+}
+
+// yieldWorker yields according to the strategy after a step. sinceSleep
+// counts instructions since the last interval sleep and is updated in place.
+func (vo *VMOrchestrator) yieldWorker(used int, sinceSleep *int) {
+	strategy := vo.yield.Load()
+	if strategy == nil {
+		time.Sleep(0)
+		return
+	}
+
+	switch strategy.kind {
+	case yieldSleep0:
+		time.Sleep(0)
+	case yieldGosched:
+		runtime.Gosched()
+	case yieldInterval:
+		if *sinceSleep += used; *sinceSleep >= strategy.every {
+			*sinceSleep = 0
+			time.Sleep(time.Duration(strategy.every) * time.Microsecond)
+		}
+	}
+}
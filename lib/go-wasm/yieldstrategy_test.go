@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+func TestParseYieldStrategy(t *testing.T) {
+	tests := []struct {
+		name string
+		want *yieldStrategy
+	}{
+		{"sleep0", &yieldStrategy{kind: yieldSleep0}},
+		{"gosched", &yieldStrategy{kind: yieldGosched}},
+		{"none", &yieldStrategy{kind: yieldNone}},
+		{"interval(50)", &yieldStrategy{kind: yieldInterval, every: 50}},
+		{"interval(0)", nil},
+		{"interval(-1)", nil},
+		{"interval(5", nil},
+		{"interval()", nil},
+		{"sleep", nil},
+	}
+	for _, test := range tests {
+		got, ok := parseYieldStrategy(test.name)
+		if ok != (test.want != nil) || (ok && *got != *test.want) {
+			t.Errorf("parseYieldStrategy(%q) = %+v, %v; want %+v", test.name, got, ok, test.want)
+		}
+	}
+}
+
+// BenchmarkYieldStrategy measures instruction throughput under each yield
+// strategy, and fairness as the smaller thread's share of the instructions
+// run by two equal-priority threads
+func BenchmarkYieldStrategy(b *testing.B) {
+	const perOp = 1024
+	for _, strategy := range []string{"sleep0", "gosched", "none", "interval(64)"} {
+		b.Run(strategy, func(b *testing.B) {
+			vo := newOrchestrator(defaultRegisterCount)
+			var counts [2]int64 // main thread, second thread
+			bridge := js.Global().Get("Object").New()
+			fn := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				if args[0].Float() >= 0x40000000 {
+					atomic.AddInt64(&counts[1], 1)
+				} else {
+					atomic.AddInt64(&counts[0], 1)
+				}
+				return true
+			})
+			b.Cleanup(fn.Release)
+			bridge.Set("executeInstruction", fn)
+			call(vo.Initialize, bridge)
+			call(vo.SetYieldStrategy, strategy)
+			call(vo.Start)
+			defer call(vo.Stop)
+			call(vo.CreateThread, 0x40000000)
+
+			b.ResetTimer()
+			for i := 1; i <= b.N; i++ {
+				for atomic.LoadInt64(&counts[0])+atomic.LoadInt64(&counts[1]) < int64(i*perOp) {
+					time.Sleep(time.Microsecond) // lets timers fire under js/wasm
+				}
+			}
+			b.StopTimer()
+			first, second := atomic.LoadInt64(&counts[0]), atomic.LoadInt64(&counts[1])
+			b.ReportMetric(float64(min(first, second))/float64(first+second), "min-share")
+		})
+	}
+}
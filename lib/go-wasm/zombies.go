@@ -0,0 +1,87 @@
+// Zombie Threads
+// Keeps terminated threads queryable until the host reaps them
+//
+// A thread that terminates on its own (halt, instruction limit, kill,
+// migration) leaves the active thread map but is kept as a zombie with its
+// final state, so GetThread and DumpThreads still show it, marked as such,
+// after the scheduler has moved on. ReapThread discards a zombie and its
+// exit record; SetReapTimeout reaps zombies automatically after a delay.
+// Zombies never run, count as active threads or block idle detection.
+//
+// Stop, Reset and Restore clear the thread map wholesale and do not leave
+// zombies; JoinThread still resolves from the exit record.
+
+package main
+
+import (
+	"sync/atomic"
+	"syscall/js"
+	"time"
+)
+
+// ReapThread discards a terminated thread's retained state
+func (vo *VMOrchestrator) ReapThread(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return vo.fail(false, errInvalidArgument, "reapThread requires a thread ID")
+	}
+
+	threadID := args[0].Int()
+
+	vo.threadMutex.Lock()
+	defer vo.threadMutex.Unlock()
+
+	if _, ok := vo.threads[threadID]; ok {
+		return vo.fail(false, errInvalidState, "thread %d has not terminated", threadID)
+	}
+	if _, ok := vo.zombies[threadID]; !ok {
+		return vo.unknownThread(false, threadID)
+	}
+	delete(vo.zombies, threadID)
+	delete(vo.exitStates, threadID)
+	return vo.succeed(true, nil)
+}
+
+// SetReapTimeout reaps zombies automatically ms milliseconds after they
+// terminate. 0 (the default) keeps them until ReapThread.
+func (vo *VMOrchestrator) SetReapTimeout(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber || args[0].Float() < 0 {
+		return js.ValueOf(false)
+	}
+
+	timeout := time.Duration(args[0].Float() * float64(time.Millisecond))
+	atomic.StoreInt64(&vo.reapTimeout, int64(timeout))
+	return js.ValueOf(true)
+}
+
+// addZombie retains a terminated thread and schedules its automatic reaping.
+// Caller must hold threadMutex.
+func (vo *VMOrchestrator) addZombie(thread *VMThread) {
+	vo.zombies[thread.id] = thread
+
+	if timeout := time.Duration(atomic.LoadInt64(&vo.reapTimeout)); timeout > 0 {
+		time.AfterFunc(timeout, func() { vo.reapZombie(thread) })
+	}
+}
+
+// reapZombie discards a zombie unless it was already reaped or replaced
+func (vo *VMOrchestrator) reapZombie(thread *VMThread) {
+	vo.threadMutex.Lock()
+	defer vo.threadMutex.Unlock()
+
+	if vo.zombies[thread.id] == thread {
+		delete(vo.zombies, thread.id)
+		delete(vo.exitStates, thread.id)
+	}
+}
+
+// lookupThread returns an active thread or a zombie, reporting which
+func (vo *VMOrchestrator) lookupThread(threadID int) (thread *VMThread, zombie bool) {
+	vo.threadMutex.RLock()
+	defer vo.threadMutex.RUnlock()
+
+	if thread := vo.threads[threadID]; thread != nil {
+		return thread, false
+	}
+	thread = vo.zombies[threadID]
+	return thread, thread != nil
+}
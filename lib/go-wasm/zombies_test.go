@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestZombieQueryableUntilReaped(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	id := createThread(t, vo, 0x40000000, 1, "paused", "done")
+	requireOK(t, call(vo.SetRegister, id, 4, 44))
+	requireOK(t, call(vo.KillThread, id))
+
+	info := call(vo.GetThread, id)
+	if info.IsNull() {
+		t.Fatal("terminated thread is gone before being reaped")
+	}
+	if !info.Get("zombie").Bool() || info.Get("status").String() != "terminated" || info.Get("exitReason").String() != "killed" {
+		t.Errorf("zombie = %v, status %v, exit reason %v; want a killed zombie",
+			info.Get("zombie"), info.Get("status"), info.Get("exitReason"))
+	}
+	if got := info.Get("registers").Index(4).Int(); got != 44 {
+		t.Errorf("zombie register 4 = %d, want its final value 44", got)
+	}
+	if dump := call(vo.DumpThreads).String(); !strings.Contains(dump, `Thread 1 "done": terminated (killed)`) {
+		t.Errorf("dump does not show the zombie:\n%s", dump)
+	}
+	if ids := jsToInts(call(vo.ListThreadIDs)); len(ids) != 0 {
+		t.Errorf("zombie listed as active: %v", ids)
+	}
+	if ids := jsToInts(call(vo.ListThreadIDs, true)); len(ids) != 1 || ids[0] != id {
+		t.Errorf("listThreadIDs(true) = %v, want [%d]", ids, id)
+	}
+
+	requireOK(t, call(vo.ReapThread, id))
+	if !call(vo.GetThread, id).IsNull() {
+		t.Error("reaped thread is still queryable")
+	}
+	requireError(t, call(vo.ReapThread, id), errUnknownThread)
+}
+
+func TestReapThreadRejectsActiveThreads(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	id := createThread(t, vo, 0x40000000, 1, "paused")
+	requireError(t, call(vo.ReapThread, id), errInvalidState)
+}
+
+func TestReapTimeout(t *testing.T) {
+	vo := newTestOrchestrator(t)
+	if call(vo.SetReapTimeout, -1).Bool() {
+		t.Error("setReapTimeout accepted a negative timeout")
+	}
+	call(vo.SetReapTimeout, 20)
+	id := createThread(t, vo, 0x40000000, 1, "paused")
+	requireOK(t, call(vo.KillThread, id))
+
+	if call(vo.GetThread, id).IsNull() {
+		t.Fatal("zombie reaped before the timeout")
+	}
+	eventually(t, "the zombie to be reaped", func() bool { return call(vo.GetThread, id).IsNull() })
+}
@@ -45,7 +45,7 @@ fi
 
 # Build for WebAssembly
 echo "Compiling Go to WebAssembly..."
-GOOS=js GOARCH=wasm go build -o "$OUTPUT_DIR/vm-orchestrator.wasm" vm-orchestrator.go
+GOOS=js GOARCH=wasm go build -o "$OUTPUT_DIR/vm-orchestrator.wasm" .
 
 # Copy Go WASM JS support file
 if [ -f "$(go env GOROOT)/misc/wasm/wasm_exec.js" ]; then